@@ -2,15 +2,13 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"net/http"
 	"os"
-	"sync"
 	"time"
 
 	"github.com/xXRoxXeRXx/cloud-performance-monitor/internal/agent"
-	"github.com/xXRoxXeRXx/cloud-performance-monitor/internal/nextcloud"
-	"github.com/xXRoxXeRXx/cloud-performance-monitor/internal/magentacloud"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
@@ -20,6 +18,9 @@ const (
 )
 
 func main() {
+	configPath := flag.String("config", agent.ConfigFilePath(), "Path to a YAML or JSON instance configuration file (env: CONFIG_FILE)")
+	flag.Parse()
+	
 	// Initialize structured logging
 	logLevel := agent.GetLogLevel()
 	logFormat := agent.GetLogFormat()
@@ -32,7 +33,7 @@ func main() {
 	shutdownManager := agent.NewShutdownManager(DefaultShutdownTimeout)
 	
 	// Load all configurations
-	allConfigs, err := agent.LoadConfigs()
+	allConfigs, err := agent.LoadConfigsFrom(*configPath)
 	if err != nil {
 		agent.Logger.Error("Could not load configuration", err)
 		os.Exit(1)
@@ -44,13 +45,41 @@ func main() {
 	// Create health checker
 	healthChecker := agent.NewHealthChecker(Version)
 	
-	// Register all services with health checker
-	for _, cfg := range allConfigs {
-		healthChecker.RegisterService(cfg.InstanceName)
+	// Open the test result history used for the daily and monthly averages
+	history, err := agent.NewHistoryStore(agent.HistoryFilePath())
+	if err != nil {
+		agent.Logger.Error("Could not open test history", err)
+		os.Exit(1)
+	}
+	defer history.Close()
+	go agent.RunHistoryAggregation(shutdownManager.Context(), history, agent.DefaultHistoryAggregationInterval)
+	
+	// Keep the OAuth2 tokens across test runs and restarts
+	tokenStore, err := agent.NewTokenStore(agent.TokenStoreFilePath(), agent.TokenStoreKey())
+	if err != nil {
+		agent.Logger.Error("Could not open token store", err)
+		os.Exit(1)
 	}
+	agent.SetTokenStore(tokenStore)
 	
-	// Create test manager
+	// Create test manager with the configured execution mode
 	testManager := agent.NewTestManager(shutdownManager)
+	limits, err := agent.GetExecutionLimits()
+	if err != nil {
+		agent.Logger.Error("Invalid execution settings", err)
+		os.Exit(1)
+	}
+	testManager.SetLimits(limits)
+	
+	// Per-instance schedules; the instance manager adds the instances once the HTTP server runs
+	scheduler := startScheduledMonitoring(shutdownManager.Context(), healthChecker, testManager, history)
+	instances := agent.NewInstanceManager(shutdownManager.Context(), scheduler, healthChecker)
+	
+	// On-demand tests go through the same test manager as the scheduled ones
+	onDemand := agent.NewOnDemandRunner(shutdownManager.Context(), testManager, instances.Configs,
+		func(ctx context.Context, cfg *agent.Config) *agent.RunResult {
+			return runTestForInstance(ctx, cfg, healthChecker, history, true)
+		}, agent.APITokenFromEnv())
 	
 	// Setup HTTP server with health endpoints
 	mux := http.NewServeMux()
@@ -58,6 +87,10 @@ func main() {
 	mux.HandleFunc("/health", healthChecker.HealthHandler())
 	mux.HandleFunc("/health/live", healthChecker.LivenessHandler())
 	mux.HandleFunc("/health/ready", healthChecker.ReadinessHandler())
+	mux.HandleFunc("/api/v1/results", agent.ResultsHandler(history))
+	runsAPI := onDemand.Handler()
+	mux.Handle("/api/v1/runs", runsAPI)
+	mux.Handle("/api/v1/runs/", runsAPI)
 	
 	// Create HTTP server manager
 	httpManager := agent.NewHTTPServerManager(":8080", mux)
@@ -74,24 +107,24 @@ func main() {
 	}()
 	
 	agent.Logger.InfoWithFields("http-server", ":8080", 
-		"HTTP server started with endpoints: /metrics, /health, /health/live, /health/ready", "", "")
+		"HTTP server started with endpoints: /metrics, /health, /health/live, /health/ready, /api/v1/results, /api/v1/runs", "", "")
 	
-	// Start sequential monitoring
-	var wg sync.WaitGroup
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		startSequentialMonitoring(shutdownManager.Context(), allConfigs, healthChecker, testManager)
-	}()
+	// Start per-instance scheduled monitoring and network latency monitoring;
+	// the instance manager registers the instances with the health checker
+	instances.Apply(allConfigs)
+	agent.ConfigLastReloadSuccess.SetToCurrentTime()
 	
-	// Start network latency monitoring for all instances
-	for _, cfg := range allConfigs {
-		wg.Add(1)
-		go func(config *agent.Config) {
-			defer wg.Done()
-			agent.UpdateNetworkLatencyMetrics(shutdownManager.Context(), config, config.ServiceType)
-		}(cfg)
-	}
+	// Reload the configuration on SIGHUP or when the config file changes
+	go agent.WatchConfig(shutdownManager.Context(), *configPath, agent.GetConfigReloadInterval(), func(reason string) {
+		diff, err := instances.Reload(*configPath)
+		if err != nil {
+			agent.Logger.ErrorWithFields("monitor-agent", "", 
+				fmt.Sprintf("Configuration reload (%s) failed, keeping current instances", reason), err)
+			return
+		}
+		agent.Logger.InfoWithFields("monitor-agent", "", 
+			fmt.Sprintf("Configuration reloaded (%s): %s", reason, diff), "", "")
+	})
 	
 	// Wait for shutdown signal and perform graceful shutdown
 	if err := shutdownManager.WaitForShutdown(); err != nil {
@@ -100,137 +133,58 @@ func main() {
 	}
 	
 	// Wait for all goroutines to finish
-	wg.Wait()
+	scheduler.Wait()
+	instances.Wait()
 	agent.Logger.Info("Application shutdown completed successfully")
 }
 
-// startSequentialMonitoring runs tests for all instances sequentially - one after another
-func startSequentialMonitoring(ctx context.Context, configs []*agent.Config, healthChecker *agent.HealthChecker, testManager *agent.TestManager) {
-	agent.Logger.InfoWithFields("monitor-agent", "", 
-		fmt.Sprintf("Starting sequential monitoring for %d instances", len(configs)), "", "")
-	
-	if len(configs) == 0 {
-		agent.Logger.Warn("No instances configured, exiting")
-		return
-	}
-	
-	// Use the first config's interval as base interval
-	baseInterval := time.Duration(configs[0].TestIntervalSec) * time.Second
+// startScheduledMonitoring creates the scheduler that tests every instance at its own
+// interval; instances are added by the instance manager. The test manager decides how many tests run at the same time (EXECUTION_MODE).
+func startScheduledMonitoring(ctx context.Context, healthChecker *agent.HealthChecker, testManager *agent.TestManager, history *agent.HistoryStore) *agent.Scheduler {
+	jitterPercent := agent.GetJitterPercent()
+	limits := testManager.Limits()
 	agent.Logger.InfoWithFields("monitor-agent", "", 
-		fmt.Sprintf("Test cycle interval: %v", baseInterval), "", "")
-	
-	// Create clients for all instances
-	clients := make(map[*agent.Config]interface{})
-	for _, cfg := range configs {
-		switch cfg.ServiceType {
-		case "nextcloud":
-			clients[cfg] = nextcloud.NewClient(cfg.URL, cfg.Username, cfg.Password)
-		case "hidrive":
-			clients[cfg] = cfg // HiDrive client is created in the test function
-		case "hidrive_legacy":
-			clients[cfg] = cfg // HiDrive Legacy client is created in the test function
-		case "dropbox":
-			clients[cfg] = cfg // Dropbox client is created in the test function
-		case "magentacloud":
-			clients[cfg] = magentacloud.NewClient(cfg.URL, cfg.Username, cfg.Password, cfg.ANID)
-		}
-	}
-	
-	// Run initial test cycle immediately
-	agent.Logger.Info("Starting initial test cycle")
-	if !runTestCycle(ctx, configs, clients, healthChecker, testManager) {
-		return // Shutdown signal received
-	}
-	
-	// Start the periodic testing loop
-	ticker := time.NewTicker(baseInterval)
-	defer ticker.Stop()
-	
-	for {
-		select {
-		case <-ctx.Done():
-			agent.Logger.Info("Shutdown signal received, stopping monitoring...")
-			return
-		case <-ticker.C:
-			agent.Logger.Info("Starting new test cycle")
-			if !runTestCycle(ctx, configs, clients, healthChecker, testManager) {
-				return // Shutdown signal received during test
+		fmt.Sprintf("Starting scheduled monitoring (jitter ±%d%%, %s execution, max %d concurrent, max %d per service)",
+			jitterPercent, limits.Mode, limits.MaxConcurrent, limits.MaxPerService), "", "")
+	
+	scheduler := agent.NewScheduler(ctx, jitterPercent, func(ctx context.Context, cfg *agent.Config) {
+		// Failed tests are logged by runTestForInstance; Execute itself only fails when the
+		// test is cancelled before it starts
+		_ = testManager.Execute(ctx, agent.InstanceKey(cfg), cfg.ServiceType, func(ctx context.Context) error {
+			testStart := time.Now()
+			agent.Logger.InfoWithFields(cfg.ServiceType, cfg.InstanceName, 
+				"Starting scheduled test", "", "")
+			
+			result := runTestForInstance(ctx, cfg, healthChecker, history, false)
+			
+			if result.Skipped {
+				return nil
 			}
-		}
-	}
-}
-
-// runTestCycle runs tests for all instances sequentially - one after another
-// Returns false if shutdown signal received, true if completed normally
-func runTestCycle(ctx context.Context, configs []*agent.Config, clients map[*agent.Config]interface{}, healthChecker *agent.HealthChecker, testManager *agent.TestManager) bool {
-	cycleStart := time.Now()
-	agent.Logger.InfoWithFields("monitor-agent", "", 
-		fmt.Sprintf("Starting test cycle with %d instances", len(configs)), "", "")
-	
-	for i, cfg := range configs {
-		// Check for shutdown signal before each test
-		select {
-		case <-ctx.Done():
-			agent.Logger.InfoWithFields("monitor-agent", "", 
-				fmt.Sprintf("Shutdown signal received during test cycle, stopping after %d/%d tests", i, len(configs)), "", "")
-			return false
-		default:
-		}
-		
-		agent.Logger.InfoWithFields(cfg.ServiceType, cfg.InstanceName, 
-			fmt.Sprintf("Starting test [%d/%d]", i+1, len(configs)), "", "")
-		
-		testStart := time.Now()
-		
-		// Run test directly (synchronously) for sequential execution
-		err := runTestForInstance(ctx, cfg, clients[cfg], healthChecker)
-		if err != nil {
-			agent.Logger.ErrorWithFields(cfg.ServiceType, cfg.InstanceName, 
-				"Test failed", err)
-		}
-		
-		testDuration := time.Since(testStart)
-		
-		agent.Logger.InfoWithFields(cfg.ServiceType, cfg.InstanceName, 
-			fmt.Sprintf("Completed test [%d/%d]", i+1, len(configs)), 
-			testDuration.String(), "")
-	}
+			agent.Logger.InfoWithFields(cfg.ServiceType, cfg.InstanceName, 
+				"Completed scheduled test", time.Since(testStart).String(), "")
+			return result.Err
+		})
+	})
 	
-	cycleDuration := time.Since(cycleStart)
-	agent.Logger.InfoWithFields("monitor-agent", "", 
-		"Test cycle completed", cycleDuration.String(), "")
-	return true
+	return scheduler
 }
 
-// runTestForInstance runs a single test for the given instance
-func runTestForInstance(ctx context.Context, cfg *agent.Config, client interface{}, healthChecker *agent.HealthChecker) error {
+// runTestForInstance runs a single test for the given instance through its circuit breaker
+// and records it in the history. A forced test runs even while the circuit is open.
+func runTestForInstance(ctx context.Context, cfg *agent.Config, healthChecker *agent.HealthChecker, history *agent.HistoryStore, forced bool) *agent.RunResult {
 	startTime := time.Now()
-	var err error
-	
-	switch cfg.ServiceType {
-	case "nextcloud":
-		if ncClient, ok := client.(*nextcloud.Client); ok {
-			agent.RunTest(cfg, ncClient)
-		} else {
-			err = fmt.Errorf("invalid client type for Nextcloud instance %s", cfg.InstanceName)
-		}
-	case "hidrive":
-		err = agent.RunHiDriveTest(ctx, cfg)
-	case "hidrive_legacy":
-		err = agent.RunHiDriveLegacyTest(ctx, cfg)
-	case "dropbox":
-		err = agent.RunDropboxTest(ctx, cfg)
-	case "magentacloud":
-		err = agent.RunMagentaCloudTest(ctx, cfg)
-	default:
-		err = fmt.Errorf("unknown service type: %s", cfg.ServiceType)
-	}
+	// Create the provider client and run the generic test pipeline
+	result := agent.RunGuardedInstanceTest(ctx, cfg, forced)
+	err := result.Err
 	
 	duration := time.Since(startTime)
 	
 	// Update health status
 	status := "healthy"
-	if err != nil {
+	if result.Skipped {
+		// The circuit breaker has logged the skip; the instance stays unhealthy
+		status = "unhealthy"
+	} else if err != nil {
 		status = "unhealthy"
 		agent.Logger.ErrorWithFields(cfg.ServiceType, cfg.InstanceName, 
 			"Test failed", err)
@@ -241,5 +195,13 @@ func runTestForInstance(ctx context.Context, cfg *agent.Config, client interface
 	
 	healthChecker.UpdateServiceHealth(cfg.InstanceName, status, duration, err)
 	
-	return err
+	// Runs aborted by a shutdown or skipped by the circuit breaker say nothing about the provider
+	if ctx.Err() == nil && !result.Skipped {
+		if histErr := history.Add(result); histErr != nil {
+			agent.Logger.ErrorWithFields(cfg.ServiceType, cfg.InstanceName, 
+				"Could not record test result", histErr)
+		}
+	}
+	
+	return result
 }
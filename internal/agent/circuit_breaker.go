@@ -0,0 +1,137 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/xXRoxXeRXx/cloud-performance-monitor/internal/utils"
+)
+
+// Circuit breaker defaults. A test run is expensive, so the breaker opens after a few
+// failed runs and a single successful probe closes it again.
+const (
+	DefaultCircuitBreakerMaxFailures = 3
+	DefaultCircuitBreakerReset       = 15 * time.Minute
+)
+
+// GetCircuitBreakerConfig reads the circuit breaker settings from CIRCUIT_BREAKER_MAX_FAILURES
+// (failed runs before the circuit opens, 0 disables the breaker) and CIRCUIT_BREAKER_RESET_SECONDS
+// (time until the first probe after opening)
+func GetCircuitBreakerConfig() *utils.CircuitBreakerConfig {
+	cfg := &utils.CircuitBreakerConfig{
+		MaxFailures:      DefaultCircuitBreakerMaxFailures,
+		ResetTimeout:     DefaultCircuitBreakerReset,
+		SuccessThreshold: 1,
+	}
+	if value := os.Getenv("CIRCUIT_BREAKER_MAX_FAILURES"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			cfg.MaxFailures = n
+		}
+	}
+	if value := os.Getenv("CIRCUIT_BREAKER_RESET_SECONDS"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+			cfg.ResetTimeout = time.Duration(seconds) * time.Second
+		}
+	}
+	return cfg
+}
+
+// circuitBreakers holds the circuit breaker of every running instance by InstanceKey
+var circuitBreakers = struct {
+	sync.Mutex
+	m map[string]*utils.CircuitBreaker
+}{m: make(map[string]*utils.CircuitBreaker)}
+
+// circuitBreakerFor returns the circuit breaker of an instance, creating it on first use.
+// It returns nil if the breaker is disabled.
+func circuitBreakerFor(cfg *Config) *utils.CircuitBreaker {
+	circuitBreakers.Lock()
+	defer circuitBreakers.Unlock()
+
+	key := InstanceKey(cfg)
+	if cb, ok := circuitBreakers.m[key]; ok {
+		return cb
+	}
+	settings := GetCircuitBreakerConfig()
+	if settings.MaxFailures == 0 {
+		return nil
+	}
+
+	cb := utils.NewCircuitBreaker(key, settings)
+	service, instance := cfg.ServiceType, cfg.InstanceName
+	cb.SetStateChangeHandler(func(from, to utils.CircuitState) {
+		CircuitBreakerState.WithLabelValues(service, instance).Set(float64(to))
+		level := INFO
+		if to == utils.CircuitOpen {
+			level = WARN
+		}
+		LogServiceOperation(level, service, instance, "circuit_breaker", "state",
+			fmt.Sprintf("Circuit breaker state changed: %s -> %s", from, to))
+	})
+	CircuitBreakerState.WithLabelValues(service, instance).Set(float64(utils.CircuitClosed))
+	circuitBreakers.m[key] = cb
+	return cb
+}
+
+// RemoveCircuitBreaker drops the circuit breaker of an instance, so that a changed
+// configuration starts with a closed circuit
+func RemoveCircuitBreaker(cfg *Config) {
+	circuitBreakers.Lock()
+	defer circuitBreakers.Unlock()
+
+	delete(circuitBreakers.m, InstanceKey(cfg))
+	CircuitBreakerState.DeleteLabelValues(cfg.ServiceType, cfg.InstanceName)
+}
+
+// RunGuardedInstanceTest runs the test of an instance through its circuit breaker.
+// While the circuit is open the test is skipped; once the reset timeout has passed the
+// next run probes the instance in half-open state. A forced run (e.g. triggered on demand)
+// always executes and acts as a probe.
+func RunGuardedInstanceTest(ctx context.Context, cfg *Config, forced bool) *RunResult {
+	return runGuarded(ctx, cfg, forced, RunInstanceTest)
+}
+
+// runGuarded runs run through the circuit breaker of cfg's instance
+func runGuarded(ctx context.Context, cfg *Config, forced bool, run func(ctx context.Context, cfg *Config) *RunResult) *RunResult {
+	cb := circuitBreakerFor(cfg)
+	if cb == nil {
+		return run(ctx, cfg)
+	}
+
+	var result *RunResult
+	test := func(ctx context.Context) error {
+		result = run(ctx, cfg)
+		if ctx.Err() != nil {
+			// A run aborted by a shutdown says nothing about the instance
+			return utils.ErrAborted
+		}
+		return result.Err
+	}
+
+	var err error
+	if forced {
+		err = cb.Probe(ctx, test)
+	} else {
+		err = cb.Execute(ctx, test)
+	}
+	if result != nil {
+		return result
+	}
+
+	// The test did not run, so err is ErrCircuitOpen: skip without touching the provider
+	TestsSkipped.WithLabelValues(cfg.ServiceType, cfg.InstanceName, "circuit_breaker_open").Inc()
+	LogServiceOperation(WARN, cfg.ServiceType, cfg.InstanceName, "test", "skipped",
+		fmt.Sprintf("Circuit breaker open, skipping test until %s", cb.OpenUntil().Format(time.RFC3339)))
+	return &RunResult{
+		ServiceType:  cfg.ServiceType,
+		InstanceName: cfg.InstanceName,
+		StartTime:    time.Now(),
+		ErrorCode:    "circuit_breaker_open",
+		Skipped:      true,
+		Err:          fmt.Errorf("test skipped: %w", err),
+	}
+}
@@ -0,0 +1,120 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/xXRoxXeRXx/cloud-performance-monitor/internal/utils"
+)
+
+// countingRun returns a test function that fails while *failing is set and counts its calls
+func countingRun(failing *bool, calls *int) func(ctx context.Context, cfg *Config) *RunResult {
+	return func(ctx context.Context, cfg *Config) *RunResult {
+		*calls++
+		result := &RunResult{ServiceType: cfg.ServiceType, InstanceName: cfg.InstanceName, ErrorCode: "none"}
+		if *failing {
+			result.Err = errors.New("upload failed with status 503")
+			result.ErrorCode = "http_503_service_unavailable"
+		}
+		return result
+	}
+}
+
+func TestRunGuardedOpensAndSkips(t *testing.T) {
+	t.Setenv("CIRCUIT_BREAKER_MAX_FAILURES", "2")
+	cfg := runnerTestConfig("cb-open")
+	defer RemoveCircuitBreaker(cfg)
+
+	failing, calls := true, 0
+	run := countingRun(&failing, &calls)
+
+	for i := 0; i < 2; i++ {
+		if result := runGuarded(context.Background(), cfg, false, run); result.Skipped {
+			t.Fatalf("run %d: expected the test to run while the circuit is closed", i+1)
+		}
+	}
+	if state := testutil.ToFloat64(CircuitBreakerState.WithLabelValues(cfg.ServiceType, cfg.InstanceName)); state != float64(utils.CircuitOpen) {
+		t.Fatalf("expected circuit breaker state %d after 2 failures, got %v", utils.CircuitOpen, state)
+	}
+
+	result := runGuarded(context.Background(), cfg, false, run)
+	if !result.Skipped || !errors.Is(result.Err, utils.ErrCircuitOpen) || result.ErrorCode != "circuit_breaker_open" {
+		t.Errorf("expected skipped run with circuit_breaker_open, got %+v", result)
+	}
+	if calls != 2 {
+		t.Errorf("expected the open circuit to skip the test, got %d calls", calls)
+	}
+	if skipped := testutil.ToFloat64(TestsSkipped.WithLabelValues(cfg.ServiceType, cfg.InstanceName, "circuit_breaker_open")); skipped != 1 {
+		t.Errorf("expected 1 skipped test, got %v", skipped)
+	}
+}
+
+func TestRunGuardedForcedRunProbesAndCloses(t *testing.T) {
+	t.Setenv("CIRCUIT_BREAKER_MAX_FAILURES", "1")
+	cfg := runnerTestConfig("cb-probe")
+	defer RemoveCircuitBreaker(cfg)
+
+	failing, calls := true, 0
+	run := countingRun(&failing, &calls)
+
+	runGuarded(context.Background(), cfg, false, run)
+	if state := testutil.ToFloat64(CircuitBreakerState.WithLabelValues(cfg.ServiceType, cfg.InstanceName)); state != float64(utils.CircuitOpen) {
+		t.Fatalf("expected open circuit, got state %v", state)
+	}
+
+	// The provider has recovered; an on-demand run probes and closes the circuit
+	failing = false
+	if result := runGuarded(context.Background(), cfg, true, run); result.Skipped || result.Err != nil {
+		t.Fatalf("expected forced run to execute and succeed, got %+v", result)
+	}
+	if state := testutil.ToFloat64(CircuitBreakerState.WithLabelValues(cfg.ServiceType, cfg.InstanceName)); state != float64(utils.CircuitClosed) {
+		t.Errorf("expected closed circuit after successful probe, got state %v", state)
+	}
+	if result := runGuarded(context.Background(), cfg, false, run); result.Skipped {
+		t.Error("expected scheduled runs to execute again after the circuit closed")
+	}
+}
+
+func TestRunGuardedAbortedRunIsNotRecorded(t *testing.T) {
+	t.Setenv("CIRCUIT_BREAKER_MAX_FAILURES", "2")
+	cfg := runnerTestConfig("cb-aborted")
+	defer RemoveCircuitBreaker(cfg)
+
+	failing, calls := true, 0
+	run := countingRun(&failing, &calls)
+	runGuarded(context.Background(), cfg, false, run)
+
+	// A run aborted by shutdown must not reset the failure count like a success
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	failing = false
+	if result := runGuarded(ctx, cfg, false, run); result.Skipped {
+		t.Fatal("expected the aborted run to execute")
+	}
+
+	failing = true
+	runGuarded(context.Background(), cfg, false, run)
+	if state := testutil.ToFloat64(CircuitBreakerState.WithLabelValues(cfg.ServiceType, cfg.InstanceName)); state != float64(utils.CircuitOpen) {
+		t.Errorf("expected the circuit to open after 2 failures around an aborted run, got state %v", state)
+	}
+}
+
+func TestRunGuardedDisabled(t *testing.T) {
+	t.Setenv("CIRCUIT_BREAKER_MAX_FAILURES", "0")
+	cfg := runnerTestConfig("cb-disabled")
+	defer RemoveCircuitBreaker(cfg)
+
+	failing, calls := true, 0
+	run := countingRun(&failing, &calls)
+	for i := 0; i < 5; i++ {
+		if result := runGuarded(context.Background(), cfg, false, run); result.Skipped {
+			t.Fatal("expected no run to be skipped with the circuit breaker disabled")
+		}
+	}
+	if calls != 5 {
+		t.Errorf("expected 5 calls, got %d", calls)
+	}
+}
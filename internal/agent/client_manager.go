@@ -0,0 +1,112 @@
+package agent
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+// Connection kinds of the cold/warm measurements
+const (
+	// ConnectionCold marks the run that starts without kept-alive connections
+	ConnectionCold = "cold"
+	// ConnectionWarm marks the run over the connections kept alive by the cold run
+	ConnectionWarm = "warm"
+)
+
+// managedProvider is the long-lived client of an instance and the configuration it was
+// created for
+type managedProvider struct {
+	cfg      Config
+	provider StorageProvider
+}
+
+// providerClients holds the client of every running instance by InstanceKey, so that
+// connections and OAuth tokens are reused across test cycles
+var providerClients = struct {
+	sync.Mutex
+	m map[string]*managedProvider
+}{m: make(map[string]*managedProvider)}
+
+// providerFor returns the client of an instance, creating it on first use. A client
+// created for a different configuration of the instance is replaced. Failed creations
+// are not cached, the next run tries again.
+func providerFor(ctx context.Context, cfg *Config) (StorageProvider, error) {
+	key := InstanceKey(cfg)
+	providerClients.Lock()
+	existing, ok := providerClients.m[key]
+	providerClients.Unlock()
+	if ok && reflect.DeepEqual(existing.cfg, *cfg) {
+		return existing.provider, nil
+	}
+
+	// Creating the client may authenticate, so it runs without holding the lock
+	provider, err := NewProvider(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	providerClients.Lock()
+	defer providerClients.Unlock()
+	if current, ok := providerClients.m[key]; ok {
+		if current != existing && reflect.DeepEqual(current.cfg, *cfg) {
+			// Another run created a client for the same configuration meanwhile
+			closeIdleConnections(provider)
+			return current.provider, nil
+		}
+		closeIdleConnections(current.provider)
+	}
+	providerClients.m[key] = &managedProvider{cfg: *cfg, provider: provider}
+	return provider, nil
+}
+
+// RemoveProviderClient drops the client of an instance and closes its idle connections
+func RemoveProviderClient(cfg *Config) {
+	providerClients.Lock()
+	defer providerClients.Unlock()
+
+	key := InstanceKey(cfg)
+	if current, ok := providerClients.m[key]; ok {
+		closeIdleConnections(current.provider)
+		delete(providerClients.m, key)
+	}
+}
+
+// closeIdleConnections closes the kept-alive connections of a provider client if it has any
+func closeIdleConnections(provider StorageProvider) {
+	if closer, ok := provider.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+// runColdWarm runs the test twice: first after closing all kept-alive connections, so
+// that the first requests pay DNS, TCP and TLS setup, then over the connections the cold
+// run left open. The phase durations of both runs are exported with their connection
+// kind. The warm run is returned, or the cold run if it failed.
+func runColdWarm(ctx context.Context, cfg *Config, provider StorageProvider) *RunResult {
+	closeIdleConnections(provider)
+	cold := RunProviderTest(ctx, cfg, provider)
+	cold.Connection = ConnectionCold
+	recordConnectionRun(cold)
+	if cold.Err != nil || ctx.Err() != nil {
+		return cold
+	}
+
+	warm := RunProviderTest(ctx, cfg, provider)
+	warm.Connection = ConnectionWarm
+	recordConnectionRun(warm)
+	return warm
+}
+
+// recordConnectionRun exports the durations of the successful timed phases of a run
+func recordConnectionRun(result *RunResult) {
+	for _, phase := range result.Phases {
+		switch phase.Phase {
+		case PhaseUpload, PhaseDownload, PhaseList, PhaseStat:
+			if phase.Success() {
+				ConnectionTestDuration.WithLabelValues(result.ServiceType, result.InstanceName, phase.Phase, result.Connection).
+					Set(phase.Duration.Seconds())
+			}
+		}
+	}
+}
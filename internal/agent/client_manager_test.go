@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestProviderForReusesClient(t *testing.T) {
+	cfg := &Config{
+		ServiceType:  "nextcloud",
+		InstanceName: "client-manager",
+		URL:          "https://cloud.example.com",
+		Username:     "user",
+		Password:     "pass",
+	}
+	defer RemoveProviderClient(cfg)
+
+	first, err := providerFor(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("providerFor failed: %v", err)
+	}
+	second, _ := providerFor(context.Background(), cfg)
+	if first != second {
+		t.Error("expected the client to be reused for the same configuration")
+	}
+
+	changed := *cfg
+	changed.Password = "new-pass"
+	third, _ := providerFor(context.Background(), &changed)
+	if third == first {
+		t.Error("expected a new client after a configuration change")
+	}
+
+	RemoveProviderClient(&changed)
+	fourth, _ := providerFor(context.Background(), &changed)
+	if fourth == third {
+		t.Error("expected a new client after the client was removed")
+	}
+}
+
+func TestRunColdWarm(t *testing.T) {
+	cfg := runnerTestConfig("cold-warm")
+	provider := newMemoryProvider()
+
+	result := runColdWarm(context.Background(), cfg, provider)
+	if !result.Success() {
+		t.Fatalf("expected success, got %v", result.Err)
+	}
+	if result.Connection != ConnectionWarm {
+		t.Errorf("expected the warm run to be returned, got %q", result.Connection)
+	}
+	for _, connection := range []string{ConnectionCold, ConnectionWarm} {
+		if v := testutil.ToFloat64(ConnectionTestDuration.WithLabelValues(cfg.ServiceType, cfg.InstanceName, PhaseUpload, connection)); v <= 0 {
+			t.Errorf("expected the %s upload duration to be exported, got %v", connection, v)
+		}
+	}
+}
+
+func TestRunColdWarmStopsAfterFailedColdRun(t *testing.T) {
+	cfg := runnerTestConfig("cold-warm-failure")
+	provider := newMemoryProvider()
+	provider.uploadErr = errors.New("upload rejected")
+
+	result := runColdWarm(context.Background(), cfg, provider)
+	if result.Success() {
+		t.Fatal("expected the run to fail")
+	}
+	if result.Connection != ConnectionCold {
+		t.Errorf("expected the cold run to be returned, got %q", result.Connection)
+	}
+}
@@ -4,31 +4,79 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xXRoxXeRXx/cloud-performance-monitor/internal/s3"
+	"github.com/xXRoxXeRXx/cloud-performance-monitor/internal/webdav"
 )
 
-// Config holds the configuration for a single storage instance (Nextcloud, HiDrive, HiDrive Legacy, Dropbox, or MagentaCLOUD)
+// Config holds the configuration for a single storage instance (Nextcloud, HiDrive, HiDrive Legacy, Dropbox, MagentaCLOUD, generic WebDAV, S3, or Google Drive)
 type Config struct {
 	InstanceName    string
-	ServiceType     string // "nextcloud", "hidrive", "hidrive_legacy", "dropbox", or "magentacloud"
+	ServiceType     string // "nextcloud", "hidrive", "hidrive_legacy", "dropbox", "magentacloud", "webdav", "s3", or "gdrive"
 	URL             string
 	Username        string
 	Password        string
 	ANID            string // For MagentaCLOUD Account Number ID
 	AccessToken     string // For HiDrive Legacy API only
-	RefreshToken    string // For Dropbox OAuth2 and HiDrive Legacy OAuth2
+	RefreshToken    string // For Dropbox, HiDrive Legacy and Google Drive OAuth2
 	AppKey          string // For Dropbox OAuth2 (App Key)
 	AppSecret       string // For Dropbox OAuth2 (App Secret)  
-	ClientID        string // For HiDrive Legacy OAuth2
-	ClientSecret    string // For HiDrive Legacy OAuth2
+	ClientID        string // For HiDrive Legacy and Google Drive OAuth2
+	ClientSecret    string // For HiDrive Legacy and Google Drive OAuth2
+	WebDAVRoot      string // For generic WebDAV: collection of the user's files below URL
+	WebDAVChunking  string // For generic WebDAV: upload chunking mode (none, nextcloud, owncloud)
+	Bucket          string // For S3; URL is the endpoint
+	Region          string // For S3 request signing, empty means us-east-1
+	AccessKey       string // For S3
+	SecretKey       string // For S3
 	TestFileSizeMB  int
 	TestIntervalSec int
 	TestChunkSizeMB int
+	SmallFileCount  int               // Number of files in the small files test
+	SmallFileSizeKB int               // Size of each file in the small files test
+	TestTypes       []string          // Enabled test types, empty means TestTypeTransfer only
+	TestTimeoutSec  int               // Maximum duration of a whole test run, 0 means DefaultTestTimeoutSec
+	PhaseTimeoutSec int               // Maximum duration of a single test phase, 0 means DefaultPhaseTimeoutSec
+	ColdWarmRuns    bool              // Run every test on new connections first, then on kept-alive ones
+	Labels          map[string]string // Free-form labels exported via cloud_instance_label
+}
+
+// TestEnabled reports whether the given test type is enabled for the instance.
+// Without explicit test types only the transfer test runs.
+func (c *Config) TestEnabled(testType string) bool {
+	if len(c.TestTypes) == 0 {
+		return testType == TestTypeTransfer
+	}
+	for _, t := range c.TestTypes {
+		if t == testType {
+			return true
+		}
+	}
+	return false
+}
+
+// TestTimeout returns the maximum duration of a whole test run
+func (c *Config) TestTimeout() time.Duration {
+	return time.Duration(firstPositive(c.TestTimeoutSec, DefaultTestTimeoutSec)) * time.Second
+}
+
+// PhaseTimeout returns the maximum duration of a single test phase
+func (c *Config) PhaseTimeout() time.Duration {
+	return time.Duration(firstPositive(c.PhaseTimeoutSec, DefaultPhaseTimeoutSec)) * time.Second
 }
 
 const (
 	DefaultFileSizeMB  = 10
 	DefaultIntervalSec = 300
 	DefaultChunkSizeMB = 5 // Kleinere Chunks für HiDrive (5MB statt 10MB)
+	
+	DefaultSmallFileCount  = 100
+	DefaultSmallFileSizeKB = 64
+
+	DefaultTestTimeoutSec  = 900
+	DefaultPhaseTimeoutSec = 300
 )
 
 // ServiceConfig defines the configuration pattern for a service type
@@ -39,6 +87,12 @@ type ServiceConfig struct {
 	UserKey         string
 	PassKey         string
 	ANIDKey         string // For MagentaCLOUD
+	RootKey         string // For generic WebDAV
+	ChunkingKey     string // For generic WebDAV
+	BucketKey       string // For S3
+	RegionKey       string // For S3
+	AccessKeyKey    string // For S3
+	SecretKeyKey    string // For S3
 	RefreshTokenKey string // For OAuth2 services
 	ClientIDKey     string // For OAuth2 services
 	ClientSecretKey string // For OAuth2 services
@@ -48,57 +102,28 @@ type ServiceConfig struct {
 	DefaultURL      string // For services with fixed URLs
 }
 
-// LoadConfigs loads configurations for all specified Nextcloud, HiDrive, HiDrive Legacy, Dropbox, and MagentaCLOUD instances
+// LoadConfigs loads the instance configurations from the file named by CONFIG_FILE,
+// or from numbered environment variables if no config file is set
 func LoadConfigs() ([]*Config, error) {
-	var configs []*Config
+	return LoadConfigsFrom(ConfigFilePath())
+}
 
-	// Define service configurations
-	serviceConfigs := []ServiceConfig{
-		{
-			ServiceType: "nextcloud",
-			Prefix:      "NC_INSTANCE",
-			URLKey:      "URL",
-			UserKey:     "USER",
-			PassKey:     "PASS",
-		},
-		{
-			ServiceType: "hidrive",
-			Prefix:      "HIDRIVE_INSTANCE",
-			URLKey:      "URL",
-			UserKey:     "USER",
-			PassKey:     "PASS",
-		},
-		{
-			ServiceType:     "hidrive_legacy",
-			Prefix:          "HIDRIVE_LEGACY_INSTANCE",
-			RefreshTokenKey: "REFRESH_TOKEN",
-			ClientIDKey:     "CLIENT_ID",
-			ClientSecretKey: "CLIENT_SECRET",
-			NameKey:         "NAME",
-			DefaultURL:      "https://api.hidrive.strato.com",
-		},
-		{
-			ServiceType:     "dropbox",
-			Prefix:          "DROPBOX_INSTANCE",
-			RefreshTokenKey: "REFRESH_TOKEN",
-			AppKeyKey:       "APP_KEY",
-			AppSecretKey:    "APP_SECRET",
-			NameKey:         "NAME",
-			DefaultURL:      "https://api.dropboxapi.com",
-		},
-		{
-			ServiceType: "magentacloud",
-			Prefix:      "MAGENTACLOUD_INSTANCE",
-			URLKey:      "URL",
-			UserKey:     "USER",
-			PassKey:     "PASS",
-			ANIDKey:     "ANID",
-		},
+// LoadConfigsFrom loads the instance configurations from the given config file.
+// An empty path falls back to the numbered environment variables.
+func LoadConfigsFrom(path string) ([]*Config, error) {
+	if path != "" {
+		return LoadConfigFile(path)
 	}
+	return loadEnvConfigs()
+}
+
+// loadEnvConfigs loads configurations for all specified Nextcloud, HiDrive, HiDrive Legacy, Dropbox, MagentaCLOUD, generic WebDAV, S3, and Google Drive instances
+func loadEnvConfigs() ([]*Config, error) {
+	var configs []*Config
 
 	// Load configurations for each service type
-	for _, svc := range serviceConfigs {
-		serviceConfigs, err := loadServiceConfigs(svc)
+	for _, def := range RegisteredProviders() {
+		serviceConfigs, err := loadServiceConfigs(def.Env)
 		if err != nil {
 			return nil, err
 		}
@@ -106,7 +131,7 @@ func LoadConfigs() ([]*Config, error) {
 	}
 
 	if len(configs) == 0 {
-		return nil, fmt.Errorf("error: no instances configured. Please set NC_INSTANCE_1_..., HIDRIVE_INSTANCE_1_..., HIDRIVE_LEGACY_INSTANCE_1_..., DROPBOX_INSTANCE_1_REFRESH_TOKEN (with OAuth2 credentials), or MAGENTACLOUD_INSTANCE_1_... (with ANID)")
+		return nil, fmt.Errorf("error: no instances configured. Please set NC_INSTANCE_1_..., HIDRIVE_INSTANCE_1_..., HIDRIVE_LEGACY_INSTANCE_1_..., DROPBOX_INSTANCE_1_REFRESH_TOKEN (with OAuth2 credentials), MAGENTACLOUD_INSTANCE_1_... (with ANID), WEBDAV_INSTANCE_1_..., S3_INSTANCE_1_ENDPOINT (with bucket and keys), or GDRIVE_INSTANCE_1_REFRESH_TOKEN (with OAuth2 credentials)")
 	}
 
 	// Validate all configurations
@@ -166,18 +191,72 @@ func loadSingleServiceConfig(svc ServiceConfig, index int) (*Config, bool, error
 	}
 
 	// Load service-specific parameters
-	switch svc.ServiceType {
-	case "nextcloud", "hidrive":
-		return loadWebDAVConfig(svc, index, fileSize, interval, chunkSize)
-	case "hidrive_legacy":
-		return loadHiDriveLegacyConfig(svc, index, fileSize, interval, chunkSize)
-	case "dropbox":
-		return loadDropboxConfig(svc, index, fileSize, interval, chunkSize)
-	case "magentacloud":
-		return loadMagentaCloudConfig(svc, index, fileSize, interval, chunkSize)
-	default:
+	def, ok := GetProvider(svc.ServiceType)
+	if !ok {
 		return nil, false, fmt.Errorf("unknown service type: %s", svc.ServiceType)
 	}
+	config, found, err := def.LoadEnv(svc, index, fileSize, interval, chunkSize)
+	if err != nil || config == nil {
+		return config, found, err
+	}
+	config.TestTypes = defaultTestTypes()
+	if config.SmallFileCount, err = envPositiveInt("SMALL_FILES_COUNT", DefaultSmallFileCount); err != nil {
+		return nil, false, err
+	}
+	if config.SmallFileSizeKB, err = envPositiveInt("SMALL_FILES_SIZE_KB", DefaultSmallFileSizeKB); err != nil {
+		return nil, false, err
+	}
+	if config.TestTimeoutSec, err = envPositiveInt("TEST_TIMEOUT_SECONDS", DefaultTestTimeoutSec); err != nil {
+		return nil, false, err
+	}
+	if config.PhaseTimeoutSec, err = envPositiveInt("PHASE_TIMEOUT_SECONDS", DefaultPhaseTimeoutSec); err != nil {
+		return nil, false, err
+	}
+	if config.ColdWarmRuns, err = envBool("COLD_WARM_RUNS"); err != nil {
+		return nil, false, err
+	}
+	return config, found, nil
+}
+
+// envPositiveInt reads a positive integer from the environment, returning def if unset
+func envPositiveInt(key string, def int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("error: %s must be a positive number, got %q", key, value)
+	}
+	return n, nil
+}
+
+// envBool reads a boolean from the environment, returning false if unset
+func envBool(key string) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("error: %s must be true or false, got %q", key, value)
+	}
+	return b, nil
+}
+
+// defaultTestTypes returns the test types from TEST_TYPES (comma separated),
+// or only the transfer test if not set
+func defaultTestTypes() []string {
+	var types []string
+	for _, t := range strings.Split(os.Getenv("TEST_TYPES"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types = append(types, t)
+		}
+	}
+	if len(types) == 0 {
+		types = []string{TestTypeTransfer}
+	}
+	return types
 }
 
 // loadWebDAVConfig loads configuration for WebDAV-based services (Nextcloud, HiDrive)
@@ -288,6 +367,39 @@ func loadDropboxConfig(svc ServiceConfig, index, fileSize, interval, chunkSize i
 	return config, true, nil
 }
 
+// loadGoogleDriveConfig loads configuration for Google Drive (OAuth2). The optional URL
+// replaces the API base URL, e.g. to test against a local fake.
+func loadGoogleDriveConfig(svc ServiceConfig, index, fileSize, interval, chunkSize int) (*Config, bool, error) {
+	refreshToken := os.Getenv(fmt.Sprintf("%s_%d_%s", svc.Prefix, index, svc.RefreshTokenKey))
+	clientID := os.Getenv(fmt.Sprintf("%s_%d_%s", svc.Prefix, index, svc.ClientIDKey))
+	clientSecret := os.Getenv(fmt.Sprintf("%s_%d_%s", svc.Prefix, index, svc.ClientSecretKey))
+	if refreshToken == "" || clientID == "" || clientSecret == "" {
+		return nil, false, nil
+	}
+
+	instanceName := os.Getenv(fmt.Sprintf("%s_%d_%s", svc.Prefix, index, svc.NameKey))
+	if instanceName == "" {
+		instanceName = fmt.Sprintf("gdrive-instance-%d", index)
+	}
+	baseURL := os.Getenv(fmt.Sprintf("%s_%d_%s", svc.Prefix, index, svc.URLKey))
+	if baseURL == "" {
+		baseURL = svc.DefaultURL
+	}
+
+	config := &Config{
+		InstanceName:    instanceName,
+		ServiceType:     svc.ServiceType,
+		URL:             baseURL,
+		RefreshToken:    refreshToken,
+		ClientID:        clientID,
+		ClientSecret:    clientSecret,
+		TestFileSizeMB:  fileSize,
+		TestIntervalSec: interval,
+		TestChunkSizeMB: chunkSize,
+	}
+	return config, true, nil
+}
+
 // loadMagentaCloudConfig loads configuration for MagentaCLOUD (WebDAV with ANID)
 func loadMagentaCloudConfig(svc ServiceConfig, index, fileSize, interval, chunkSize int) (*Config, bool, error) {
 	urlKey := fmt.Sprintf("%s_%d_%s", svc.Prefix, index, svc.URLKey)
@@ -321,59 +433,72 @@ func loadMagentaCloudConfig(svc ServiceConfig, index, fileSize, interval, chunkS
 	return config, true, nil
 }
 
+// loadGenericWebDAVConfig loads configuration for generic WebDAV servers, which in addition
+// to the WebDAV credentials have a root path and a chunking mode
+func loadGenericWebDAVConfig(svc ServiceConfig, index, fileSize, interval, chunkSize int) (*Config, bool, error) {
+	config, found, err := loadWebDAVConfig(svc, index, fileSize, interval, chunkSize)
+	if err != nil || !found {
+		return config, found, err
+	}
+	config.WebDAVRoot = os.Getenv(fmt.Sprintf("%s_%d_%s", svc.Prefix, index, svc.RootKey))
+	config.WebDAVChunking = os.Getenv(fmt.Sprintf("%s_%d_%s", svc.Prefix, index, svc.ChunkingKey))
+	return config, true, nil
+}
+
+// loadS3Config loads configuration for S3-compatible object storage
+func loadS3Config(svc ServiceConfig, index, fileSize, interval, chunkSize int) (*Config, bool, error) {
+	endpointKey := fmt.Sprintf("%s_%d_%s", svc.Prefix, index, svc.URLKey)
+	bucketKey := fmt.Sprintf("%s_%d_%s", svc.Prefix, index, svc.BucketKey)
+	accessKeyKey := fmt.Sprintf("%s_%d_%s", svc.Prefix, index, svc.AccessKeyKey)
+	secretKeyKey := fmt.Sprintf("%s_%d_%s", svc.Prefix, index, svc.SecretKeyKey)
+
+	endpoint := os.Getenv(endpointKey)
+	if endpoint == "" {
+		return nil, false, nil
+	}
+
+	bucket := os.Getenv(bucketKey)
+	accessKey := os.Getenv(accessKeyKey)
+	secretKey := os.Getenv(secretKeyKey)
+	if bucket == "" || accessKey == "" || secretKey == "" {
+		return nil, false, fmt.Errorf("error: %s, %s and %s must be set for instance %d", bucketKey, accessKeyKey, secretKeyKey, index)
+	}
+
+	instanceName := os.Getenv(fmt.Sprintf("%s_%d_%s", svc.Prefix, index, svc.NameKey))
+	if instanceName == "" {
+		instanceName = endpoint
+	}
+
+	config := &Config{
+		InstanceName:    instanceName,
+		ServiceType:     svc.ServiceType,
+		URL:             endpoint,
+		Bucket:          bucket,
+		Region:          os.Getenv(fmt.Sprintf("%s_%d_%s", svc.Prefix, index, svc.RegionKey)),
+		AccessKey:       accessKey,
+		SecretKey:       secretKey,
+		TestFileSizeMB:  fileSize,
+		TestIntervalSec: interval,
+		TestChunkSizeMB: chunkSize,
+	}
+	return config, true, nil
+}
+
 // validateConfig validates a single configuration instance
 func validateConfig(cfg *Config) error {
 	if cfg.InstanceName == "" {
 		return fmt.Errorf("instance name cannot be empty")
 	}
 
-	switch cfg.ServiceType {
-	case "nextcloud", "hidrive":
-		if cfg.URL == "" {
-			return fmt.Errorf("URL cannot be empty for %s", cfg.ServiceType)
-		}
-		if cfg.Username == "" {
-			return fmt.Errorf("username cannot be empty for %s", cfg.ServiceType)
-		}
-		if cfg.Password == "" {
-			return fmt.Errorf("password cannot be empty for %s", cfg.ServiceType)
-		}
-	case "magentacloud":
-		if cfg.URL == "" {
-			return fmt.Errorf("URL cannot be empty for MagentaCLOUD")
-		}
-		if cfg.Username == "" {
-			return fmt.Errorf("username cannot be empty for MagentaCLOUD")
-		}
-		if cfg.Password == "" {
-			return fmt.Errorf("password cannot be empty for MagentaCLOUD")
-		}
-		if cfg.ANID == "" {
-			return fmt.Errorf("ANID cannot be empty for MagentaCLOUD")
-		}
-	case "hidrive_legacy":
-		if cfg.RefreshToken == "" {
-			return fmt.Errorf("refresh token cannot be empty for HiDrive Legacy")
-		}
-		if cfg.ClientID == "" {
-			return fmt.Errorf("client ID cannot be empty for HiDrive Legacy")
-		}
-		if cfg.ClientSecret == "" {
-			return fmt.Errorf("client secret cannot be empty for HiDrive Legacy")
-		}
-	case "dropbox":
-		if cfg.RefreshToken == "" {
-			return fmt.Errorf("refresh token cannot be empty for Dropbox")
-		}
-		if cfg.AppKey == "" {
-			return fmt.Errorf("app key cannot be empty for Dropbox")
-		}
-		if cfg.AppSecret == "" {
-			return fmt.Errorf("app secret cannot be empty for Dropbox")
-		}
-	default:
+	def, ok := GetProvider(cfg.ServiceType)
+	if !ok {
 		return fmt.Errorf("unsupported service type: %s", cfg.ServiceType)
 	}
+	if def.Validate != nil {
+		if err := def.Validate(cfg); err != nil {
+			return err
+		}
+	}
 
 	if cfg.TestFileSizeMB <= 0 {
 		return fmt.Errorf("test file size must be positive, got %d", cfg.TestFileSizeMB)
@@ -385,5 +510,113 @@ func validateConfig(cfg *Config) error {
 		return fmt.Errorf("chunk size must be positive, got %d", cfg.TestChunkSizeMB)
 	}
 
+	for _, testType := range cfg.TestTypes {
+		if !isKnownTestType(testType) {
+			return fmt.Errorf("unknown test type %q, must be one of: %s", testType, strings.Join(TestTypes(), ", "))
+		}
+	}
+
+	return nil
+}
+
+// validateWebDAVConfig validates the fields required by WebDAV-based services (Nextcloud, HiDrive)
+func validateWebDAVConfig(cfg *Config) error {
+	if cfg.URL == "" {
+		return fmt.Errorf("URL cannot be empty for %s", cfg.ServiceType)
+	}
+	if cfg.Username == "" {
+		return fmt.Errorf("username cannot be empty for %s", cfg.ServiceType)
+	}
+	if cfg.Password == "" {
+		return fmt.Errorf("password cannot be empty for %s", cfg.ServiceType)
+	}
+	return nil
+}
+
+// validateMagentaCloudConfig validates the fields required by MagentaCLOUD
+func validateMagentaCloudConfig(cfg *Config) error {
+	if cfg.URL == "" {
+		return fmt.Errorf("URL cannot be empty for MagentaCLOUD")
+	}
+	if cfg.Username == "" {
+		return fmt.Errorf("username cannot be empty for MagentaCLOUD")
+	}
+	if cfg.Password == "" {
+		return fmt.Errorf("password cannot be empty for MagentaCLOUD")
+	}
+	if cfg.ANID == "" {
+		return fmt.Errorf("ANID cannot be empty for MagentaCLOUD")
+	}
+	return nil
+}
+
+// validateGenericWebDAVConfig validates the fields required by generic WebDAV servers
+func validateGenericWebDAVConfig(cfg *Config) error {
+	if err := validateWebDAVConfig(cfg); err != nil {
+		return err
+	}
+	if !webdav.IsChunkingMode(cfg.WebDAVChunking) {
+		return fmt.Errorf("unknown chunking mode %q for webdav, must be one of: %s", cfg.WebDAVChunking, strings.Join(webdav.ChunkingModes(), ", "))
+	}
+	return nil
+}
+
+// validateS3Config validates the fields required by S3-compatible object storage
+func validateS3Config(cfg *Config) error {
+	if cfg.URL == "" {
+		return fmt.Errorf("endpoint cannot be empty for S3")
+	}
+	if cfg.Bucket == "" {
+		return fmt.Errorf("bucket cannot be empty for S3")
+	}
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return fmt.Errorf("access key and secret key cannot be empty for S3")
+	}
+	// The chunk size is the part size of multipart uploads
+	if int64(cfg.TestChunkSizeMB)*1024*1024 < s3.MinPartSize {
+		return fmt.Errorf("chunk size must be at least %d MB for S3 multipart uploads, got %d", s3.MinPartSize/(1024*1024), cfg.TestChunkSizeMB)
+	}
+	return nil
+}
+
+// validateHiDriveLegacyConfig validates the OAuth2 fields required by HiDrive Legacy
+func validateHiDriveLegacyConfig(cfg *Config) error {
+	if cfg.RefreshToken == "" {
+		return fmt.Errorf("refresh token cannot be empty for HiDrive Legacy")
+	}
+	if cfg.ClientID == "" {
+		return fmt.Errorf("client ID cannot be empty for HiDrive Legacy")
+	}
+	if cfg.ClientSecret == "" {
+		return fmt.Errorf("client secret cannot be empty for HiDrive Legacy")
+	}
+	return nil
+}
+
+// validateGoogleDriveConfig validates the OAuth2 fields required by Google Drive
+func validateGoogleDriveConfig(cfg *Config) error {
+	if cfg.RefreshToken == "" {
+		return fmt.Errorf("refresh token cannot be empty for Google Drive")
+	}
+	if cfg.ClientID == "" {
+		return fmt.Errorf("client ID cannot be empty for Google Drive")
+	}
+	if cfg.ClientSecret == "" {
+		return fmt.Errorf("client secret cannot be empty for Google Drive")
+	}
+	return nil
+}
+
+// validateDropboxConfig validates the OAuth2 fields required by Dropbox
+func validateDropboxConfig(cfg *Config) error {
+	if cfg.RefreshToken == "" {
+		return fmt.Errorf("refresh token cannot be empty for Dropbox")
+	}
+	if cfg.AppKey == "" {
+		return fmt.Errorf("app key cannot be empty for Dropbox")
+	}
+	if cfg.AppSecret == "" {
+		return fmt.Errorf("app secret cannot be empty for Dropbox")
+	}
 	return nil
 }
@@ -0,0 +1,276 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileEnv is the environment variable holding the path of the optional config file
+const ConfigFileEnv = "CONFIG_FILE"
+
+// FileConfig is the structure of the YAML/JSON configuration file
+type FileConfig struct {
+	Defaults  InstanceDefaults `yaml:"defaults" json:"defaults"`
+	Instances []FileInstance   `yaml:"instances" json:"instances"`
+}
+
+// InstanceDefaults holds test parameters applied to every instance that does not set its own
+type InstanceDefaults struct {
+	FileSizeMB      int               `yaml:"file_size_mb" json:"file_size_mb"`
+	ChunkSizeMB     int               `yaml:"chunk_size_mb" json:"chunk_size_mb"`
+	IntervalSeconds int               `yaml:"interval_seconds" json:"interval_seconds"`
+	SmallFileCount  int               `yaml:"small_file_count" json:"small_file_count"`
+	SmallFileSizeKB int               `yaml:"small_file_size_kb" json:"small_file_size_kb"`
+	TestTimeoutSec  int               `yaml:"test_timeout_seconds" json:"test_timeout_seconds"`
+	PhaseTimeoutSec int               `yaml:"phase_timeout_seconds" json:"phase_timeout_seconds"`
+	ColdWarmRuns    bool              `yaml:"cold_warm_runs" json:"cold_warm_runs"`
+	TestTypes       []string          `yaml:"test_types" json:"test_types"`
+	Labels          map[string]string `yaml:"labels" json:"labels"`
+}
+
+// FileInstance declares a single instance in the config file.
+// String values may reference environment variables as ${VAR}; fields left empty
+// are read from the numbered environment variables under EnvPrefix
+// (e.g. env_prefix "NC_INSTANCE_1" reads NC_INSTANCE_1_PASS).
+type FileInstance struct {
+	Name         string `yaml:"name" json:"name"`
+	Service      string `yaml:"service" json:"service"`
+	EnvPrefix    string `yaml:"env_prefix" json:"env_prefix"`
+	URL          string `yaml:"url" json:"url"`
+	Username     string `yaml:"username" json:"username"`
+	Password     string `yaml:"password" json:"password"`
+	ANID         string `yaml:"anid" json:"anid"`
+	RefreshToken string `yaml:"refresh_token" json:"refresh_token"`
+	AppKey       string `yaml:"app_key" json:"app_key"`
+	AppSecret    string `yaml:"app_secret" json:"app_secret"`
+	ClientID     string `yaml:"client_id" json:"client_id"`
+	ClientSecret string `yaml:"client_secret" json:"client_secret"`
+	Root         string `yaml:"root" json:"root"`
+	Chunking     string `yaml:"chunking" json:"chunking"`
+	Bucket       string `yaml:"bucket" json:"bucket"`
+	Region       string `yaml:"region" json:"region"`
+	AccessKey    string `yaml:"access_key" json:"access_key"`
+	SecretKey    string `yaml:"secret_key" json:"secret_key"`
+
+	FileSizeMB      int               `yaml:"file_size_mb" json:"file_size_mb"`
+	ChunkSizeMB     int               `yaml:"chunk_size_mb" json:"chunk_size_mb"`
+	IntervalSeconds int               `yaml:"interval_seconds" json:"interval_seconds"`
+	SmallFileCount  int               `yaml:"small_file_count" json:"small_file_count"`
+	SmallFileSizeKB int               `yaml:"small_file_size_kb" json:"small_file_size_kb"`
+	TestTimeoutSec  int               `yaml:"test_timeout_seconds" json:"test_timeout_seconds"`
+	PhaseTimeoutSec int               `yaml:"phase_timeout_seconds" json:"phase_timeout_seconds"`
+	ColdWarmRuns    *bool             `yaml:"cold_warm_runs" json:"cold_warm_runs"` // nil uses the default
+	TestTypes       []string          `yaml:"test_types" json:"test_types"`
+	Labels          map[string]string `yaml:"labels" json:"labels"`
+}
+
+// ConfigFilePath returns the config file path from CONFIG_FILE, empty if not set
+func ConfigFilePath() string {
+	return os.Getenv(ConfigFileEnv)
+}
+
+// LoadConfigFile reads and validates the instances declared in a YAML or JSON config file
+func LoadConfigFile(path string) ([]*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	fileCfg, err := ParseConfigFile(path, data)
+	if err != nil {
+		return nil, err
+	}
+
+	configs, err := fileCfg.Configs()
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	return configs, nil
+}
+
+// ParseConfigFile decodes config file data; the format is chosen by the file extension
+// (.json, otherwise YAML)
+func ParseConfigFile(path string, data []byte) (*FileConfig, error) {
+	var fileCfg FileConfig
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&fileCfg); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON config file %s: %w", path, err)
+		}
+	default:
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&fileCfg); err != nil {
+			return nil, fmt.Errorf("failed to parse YAML config file %s: %w", path, err)
+		}
+	}
+	return &fileCfg, nil
+}
+
+// Configs converts the file contents into validated instance configurations
+func (f *FileConfig) Configs() ([]*Config, error) {
+	if len(f.Instances) == 0 {
+		return nil, fmt.Errorf("no instances declared")
+	}
+
+	configs := make([]*Config, 0, len(f.Instances))
+	seen := make(map[string]bool)
+	for i, inst := range f.Instances {
+		cfg, err := f.instanceConfig(i+1, inst)
+		if err != nil {
+			return nil, fmt.Errorf("instance %d: %w", i+1, err)
+		}
+		if err := validateConfig(cfg); err != nil {
+			return nil, fmt.Errorf("configuration validation failed for instance %d (%s): %w", i+1, cfg.InstanceName, err)
+		}
+		key := cfg.ServiceType + "/" + cfg.InstanceName
+		if seen[key] {
+			return nil, fmt.Errorf("duplicate instance %q for service %s", cfg.InstanceName, cfg.ServiceType)
+		}
+		seen[key] = true
+		configs = append(configs, cfg)
+	}
+	return configs, nil
+}
+
+// instanceConfig builds the Config of a single file instance, merging defaults and
+// environment variables
+func (f *FileConfig) instanceConfig(index int, inst FileInstance) (*Config, error) {
+	def, ok := GetProvider(inst.Service)
+	if !ok {
+		return nil, fmt.Errorf("unknown service type %q, must be one of: %s", inst.Service, strings.Join(ProviderTypes(), ", "))
+	}
+	if inst.FileSizeMB < 0 || inst.ChunkSizeMB < 0 || inst.IntervalSeconds < 0 ||
+		inst.SmallFileCount < 0 || inst.SmallFileSizeKB < 0 || inst.TestTimeoutSec < 0 || inst.PhaseTimeoutSec < 0 {
+		return nil, fmt.Errorf("file_size_mb, chunk_size_mb, interval_seconds, small_file_count, small_file_size_kb, test_timeout_seconds and phase_timeout_seconds must not be negative")
+	}
+
+	cfg := &Config{
+		InstanceName:    os.ExpandEnv(inst.Name),
+		ServiceType:     inst.Service,
+		URL:             os.ExpandEnv(inst.URL),
+		Username:        os.ExpandEnv(inst.Username),
+		Password:        os.ExpandEnv(inst.Password),
+		ANID:            os.ExpandEnv(inst.ANID),
+		RefreshToken:    os.ExpandEnv(inst.RefreshToken),
+		AppKey:          os.ExpandEnv(inst.AppKey),
+		AppSecret:       os.ExpandEnv(inst.AppSecret),
+		ClientID:        os.ExpandEnv(inst.ClientID),
+		ClientSecret:    os.ExpandEnv(inst.ClientSecret),
+		WebDAVRoot:      os.ExpandEnv(inst.Root),
+		WebDAVChunking:  inst.Chunking,
+		Bucket:          os.ExpandEnv(inst.Bucket),
+		Region:          os.ExpandEnv(inst.Region),
+		AccessKey:       os.ExpandEnv(inst.AccessKey),
+		SecretKey:       os.ExpandEnv(inst.SecretKey),
+		TestFileSizeMB:  firstPositive(inst.FileSizeMB, f.Defaults.FileSizeMB, DefaultFileSizeMB),
+		TestChunkSizeMB: firstPositive(inst.ChunkSizeMB, f.Defaults.ChunkSizeMB, DefaultChunkSizeMB),
+		TestIntervalSec: firstPositive(inst.IntervalSeconds, f.Defaults.IntervalSeconds, DefaultIntervalSec),
+		SmallFileCount:  firstPositive(inst.SmallFileCount, f.Defaults.SmallFileCount, DefaultSmallFileCount),
+		SmallFileSizeKB: firstPositive(inst.SmallFileSizeKB, f.Defaults.SmallFileSizeKB, DefaultSmallFileSizeKB),
+		TestTimeoutSec:  firstPositive(inst.TestTimeoutSec, f.Defaults.TestTimeoutSec, DefaultTestTimeoutSec),
+		PhaseTimeoutSec: firstPositive(inst.PhaseTimeoutSec, f.Defaults.PhaseTimeoutSec, DefaultPhaseTimeoutSec),
+		ColdWarmRuns:    f.Defaults.ColdWarmRuns,
+		TestTypes:       inst.TestTypes,
+		Labels:          mergeLabels(f.Defaults.Labels, inst.Labels),
+	}
+	if inst.ColdWarmRuns != nil {
+		cfg.ColdWarmRuns = *inst.ColdWarmRuns
+	}
+	if len(cfg.TestTypes) == 0 {
+		cfg.TestTypes = f.Defaults.TestTypes
+	}
+	if len(cfg.TestTypes) == 0 {
+		cfg.TestTypes = defaultTestTypes()
+	}
+
+	if inst.EnvPrefix != "" {
+		applyEnvSecrets(cfg, def.Env, inst.EnvPrefix)
+	}
+	if cfg.URL == "" {
+		cfg.URL = def.Env.DefaultURL
+	}
+	if cfg.InstanceName == "" {
+		if def.Env.DefaultURL == "" && cfg.URL != "" {
+			// WebDAV instances are named after their URL, like in the env configuration
+			cfg.InstanceName = cfg.URL
+		} else {
+			cfg.InstanceName = fmt.Sprintf("%s-instance-%d", strings.ReplaceAll(cfg.ServiceType, "_", "-"), index)
+		}
+	}
+	return cfg, nil
+}
+
+// applyEnvSecrets fills empty credential fields from the environment variables
+// <prefix>_<KEY> using the key layout of the provider
+func applyEnvSecrets(cfg *Config, svc ServiceConfig, prefix string) {
+	fill := func(field *string, key string) {
+		if *field == "" && key != "" {
+			*field = os.Getenv(prefix + "_" + key)
+		}
+	}
+	fill(&cfg.URL, svc.URLKey)
+	fill(&cfg.Username, svc.UserKey)
+	fill(&cfg.Password, svc.PassKey)
+	fill(&cfg.ANID, svc.ANIDKey)
+	fill(&cfg.WebDAVRoot, svc.RootKey)
+	fill(&cfg.WebDAVChunking, svc.ChunkingKey)
+	fill(&cfg.Bucket, svc.BucketKey)
+	fill(&cfg.Region, svc.RegionKey)
+	fill(&cfg.AccessKey, svc.AccessKeyKey)
+	fill(&cfg.SecretKey, svc.SecretKeyKey)
+	fill(&cfg.RefreshToken, svc.RefreshTokenKey)
+	fill(&cfg.ClientID, svc.ClientIDKey)
+	fill(&cfg.ClientSecret, svc.ClientSecretKey)
+	fill(&cfg.AppKey, svc.AppKeyKey)
+	fill(&cfg.AppSecret, svc.AppSecretKey)
+	fill(&cfg.InstanceName, svc.NameKey)
+}
+
+// firstPositive returns the first value greater than zero
+func firstPositive(values ...int) int {
+	for _, v := range values {
+		if v > 0 {
+			return v
+		}
+	}
+	return 0
+}
+
+// mergeLabels returns the default labels overridden by the instance labels
+func mergeLabels(defaults, labels map[string]string) map[string]string {
+	if len(defaults) == 0 && len(labels) == 0 {
+		return nil
+	}
+	merged := make(map[string]string, len(defaults)+len(labels))
+	for k, v := range defaults {
+		merged[k] = v
+	}
+	for k, v := range labels {
+		merged[k] = v
+	}
+	return merged
+}
+
+// ExportInstanceLabels publishes the configured labels of an instance as
+// cloud_instance_label series so they can be joined onto the test metrics
+func ExportInstanceLabels(cfg *Config) {
+	for label, value := range cfg.Labels {
+		InstanceLabel.WithLabelValues(cfg.ServiceType, cfg.InstanceName, label, value).Set(1)
+	}
+}
+
+// DeleteInstanceLabels removes the cloud_instance_label series of an instance
+func DeleteInstanceLabels(cfg *Config) {
+	for label, value := range cfg.Labels {
+		InstanceLabel.DeleteLabelValues(cfg.ServiceType, cfg.InstanceName, label, value)
+	}
+}
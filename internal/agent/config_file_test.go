@@ -0,0 +1,189 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfigFileYAML(t *testing.T) {
+	t.Setenv("NC_INSTANCE_1_PASS", "secret-from-env")
+	t.Setenv("DROPBOX_TOKEN", "refresh-token")
+	t.Setenv("DROPBOX_INSTANCE_1_APP_KEY", "app-key")
+	t.Setenv("DROPBOX_INSTANCE_1_APP_SECRET", "app-secret")
+
+	path := writeConfigFile(t, "config.yaml", `
+defaults:
+  file_size_mb: 20
+  interval_seconds: 600
+  cold_warm_runs: true
+  labels:
+    env: prod
+instances:
+  - service: nextcloud
+    url: https://cloud.example.com
+    username: monitor
+    env_prefix: NC_INSTANCE_1
+    file_size_mb: 500
+    chunk_size_mb: 50
+    labels:
+      tier: large
+  - name: small-dropbox
+    service: dropbox
+    refresh_token: ${DROPBOX_TOKEN}
+    env_prefix: DROPBOX_INSTANCE_1
+    file_size_mb: 1
+    chunk_size_mb: 1
+    interval_seconds: 120
+    cold_warm_runs: false
+    test_types: [transfer]
+`)
+
+	configs, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatalf("LoadConfigFile failed: %v", err)
+	}
+	if len(configs) != 2 {
+		t.Fatalf("expected 2 configs, got %d", len(configs))
+	}
+
+	nc := configs[0]
+	if nc.InstanceName != "https://cloud.example.com" {
+		t.Errorf("expected instance name from URL, got %s", nc.InstanceName)
+	}
+	if nc.Password != "secret-from-env" {
+		t.Errorf("expected password from env prefix, got %q", nc.Password)
+	}
+	if nc.TestFileSizeMB != 500 || nc.TestChunkSizeMB != 50 || nc.TestIntervalSec != 600 {
+		t.Errorf("unexpected test parameters: size=%d chunk=%d interval=%d", nc.TestFileSizeMB, nc.TestChunkSizeMB, nc.TestIntervalSec)
+	}
+	if nc.Labels["env"] != "prod" || nc.Labels["tier"] != "large" {
+		t.Errorf("unexpected labels: %v", nc.Labels)
+	}
+	if !nc.ColdWarmRuns {
+		t.Error("expected cold/warm runs from the defaults")
+	}
+	if !nc.TestEnabled(TestTypeTransfer) {
+		t.Error("expected transfer test to be enabled by default")
+	}
+
+	dbx := configs[1]
+	if dbx.InstanceName != "small-dropbox" {
+		t.Errorf("unexpected instance name: %s", dbx.InstanceName)
+	}
+	if dbx.RefreshToken != "refresh-token" || dbx.AppKey != "app-key" || dbx.AppSecret != "app-secret" {
+		t.Errorf("secrets not merged from env: %+v", dbx)
+	}
+	if dbx.URL != "https://api.dropboxapi.com" {
+		t.Errorf("expected default Dropbox URL, got %s", dbx.URL)
+	}
+	if dbx.TestFileSizeMB != 1 || dbx.TestIntervalSec != 120 {
+		t.Errorf("unexpected test parameters: size=%d interval=%d", dbx.TestFileSizeMB, dbx.TestIntervalSec)
+	}
+	if dbx.ColdWarmRuns {
+		t.Error("expected the instance to opt out of the default cold/warm runs")
+	}
+}
+
+func TestLoadConfigFileJSON(t *testing.T) {
+	path := writeConfigFile(t, "config.json", `{
+  "instances": [
+    {"name": "hidrive-main", "service": "hidrive", "url": "https://webdav.hidrive.com", "username": "u", "password": "p"}
+  ]
+}`)
+
+	configs, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatalf("LoadConfigFile failed: %v", err)
+	}
+	if len(configs) != 1 || configs[0].InstanceName != "hidrive-main" {
+		t.Fatalf("unexpected configs: %+v", configs)
+	}
+	if configs[0].TestFileSizeMB != DefaultFileSizeMB || configs[0].TestIntervalSec != DefaultIntervalSec {
+		t.Errorf("expected default test parameters, got %+v", configs[0])
+	}
+}
+
+func TestLoadConfigFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    string
+	}{
+		{
+			name:    "no instances",
+			file:    "empty.yaml",
+			content: "instances: []\n",
+			want:    "no instances",
+		},
+		{
+			name:    "unknown service",
+			file:    "unknown.yaml",
+			content: "instances:\n  - service: ftp\n    url: ftp://example.com\n",
+			want:    "unknown service type",
+		},
+		{
+			name:    "missing credentials",
+			file:    "creds.yaml",
+			content: "instances:\n  - service: nextcloud\n    url: https://cloud.example.com\n",
+			want:    "username cannot be empty",
+		},
+		{
+			name:    "unknown test type",
+			file:    "types.yaml",
+			content: "instances:\n  - service: nextcloud\n    url: https://c.example.com\n    username: u\n    password: p\n    test_types: [bogus]\n",
+			want:    "unknown test type",
+		},
+		{
+			name:    "unknown chunking mode",
+			file:    "chunking.yaml",
+			content: "instances:\n  - {service: webdav, url: https://dav.example.com, username: u, password: p, chunking: tus}\n",
+			want:    "unknown chunking mode",
+		},
+		{
+			name:    "duplicate instance",
+			file:    "dup.yaml",
+			content: "instances:\n  - {name: a, service: hidrive, url: https://h, username: u, password: p}\n  - {name: a, service: hidrive, url: https://h, username: u, password: p}\n",
+			want:    "duplicate instance",
+		},
+		{
+			name:    "unknown field",
+			file:    "field.json",
+			content: `{"instances": [{"service": "nextcloud", "hostname": "x"}]}`,
+			want:    "unknown field",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfigFile(writeConfigFile(t, tt.file, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLoadConfigsFromUsesConfigFileEnv(t *testing.T) {
+	path := writeConfigFile(t, "config.yml", "instances:\n  - {name: nc, service: nextcloud, url: https://c, username: u, password: p}\n")
+	t.Setenv(ConfigFileEnv, path)
+
+	configs, err := LoadConfigs()
+	if err != nil {
+		t.Fatalf("LoadConfigs failed: %v", err)
+	}
+	if len(configs) != 1 || configs[0].InstanceName != "nc" {
+		t.Fatalf("expected instance from config file, got %+v", configs)
+	}
+}
@@ -44,6 +44,116 @@ func TestLoadConfigs(t *testing.T) {
 	}
 }
 
+func TestLoadConfigsGenericWebDAV(t *testing.T) {
+	os.Setenv("WEBDAV_INSTANCE_1_URL", "https://owncloud.example.com")
+	os.Setenv("WEBDAV_INSTANCE_1_USER", "user")
+	os.Setenv("WEBDAV_INSTANCE_1_PASS", "validpassword123")
+	os.Setenv("WEBDAV_INSTANCE_1_ROOT", "/remote.php/webdav")
+	os.Setenv("WEBDAV_INSTANCE_1_CHUNKING", "owncloud")
+
+	defer func() {
+		os.Unsetenv("WEBDAV_INSTANCE_1_URL")
+		os.Unsetenv("WEBDAV_INSTANCE_1_USER")
+		os.Unsetenv("WEBDAV_INSTANCE_1_PASS")
+		os.Unsetenv("WEBDAV_INSTANCE_1_ROOT")
+		os.Unsetenv("WEBDAV_INSTANCE_1_CHUNKING")
+	}()
+
+	configs, err := LoadConfigs()
+	if err != nil {
+		t.Fatalf("LoadConfigs failed: %v", err)
+	}
+	if len(configs) != 1 {
+		t.Fatalf("Expected 1 config, got %d", len(configs))
+	}
+
+	cfg := configs[0]
+	if cfg.ServiceType != "webdav" || cfg.WebDAVRoot != "/remote.php/webdav" || cfg.WebDAVChunking != "owncloud" {
+		t.Errorf("Unexpected config: %+v", cfg)
+	}
+	def, _ := GetProvider("webdav")
+	if url := def.CredentialURL(cfg); url != "https://owncloud.example.com/remote.php/webdav/" {
+		t.Errorf("Unexpected credential URL: %s", url)
+	}
+}
+
+func TestLoadConfigsS3(t *testing.T) {
+	os.Setenv("S3_INSTANCE_1_ENDPOINT", "http://localhost:9000")
+	os.Setenv("S3_INSTANCE_1_BUCKET", "monitor")
+	os.Setenv("S3_INSTANCE_1_ACCESS_KEY", "minioadmin")
+	os.Setenv("S3_INSTANCE_1_SECRET_KEY", "minioadmin")
+	os.Setenv("S3_INSTANCE_1_NAME", "minio-local")
+
+	defer func() {
+		os.Unsetenv("S3_INSTANCE_1_ENDPOINT")
+		os.Unsetenv("S3_INSTANCE_1_BUCKET")
+		os.Unsetenv("S3_INSTANCE_1_ACCESS_KEY")
+		os.Unsetenv("S3_INSTANCE_1_SECRET_KEY")
+		os.Unsetenv("S3_INSTANCE_1_NAME")
+		os.Unsetenv("TEST_CHUNK_SIZE_MB")
+	}()
+
+	configs, err := LoadConfigs()
+	if err != nil {
+		t.Fatalf("LoadConfigs failed: %v", err)
+	}
+	if len(configs) != 1 {
+		t.Fatalf("Expected 1 config, got %d", len(configs))
+	}
+	cfg := configs[0]
+	if cfg.ServiceType != "s3" || cfg.InstanceName != "minio-local" || cfg.URL != "http://localhost:9000" || cfg.Bucket != "monitor" {
+		t.Errorf("Unexpected config: %+v", cfg)
+	}
+
+	// Parts smaller than 5 MB are rejected by S3
+	os.Setenv("TEST_CHUNK_SIZE_MB", "4")
+	if _, err := LoadConfigs(); err == nil {
+		t.Error("Expected error for a part size below the S3 minimum")
+	}
+
+	os.Setenv("TEST_CHUNK_SIZE_MB", "5")
+	os.Unsetenv("S3_INSTANCE_1_SECRET_KEY")
+	if _, err := LoadConfigs(); err == nil {
+		t.Error("Expected error for a missing secret key")
+	}
+}
+
+func TestLoadConfigsGoogleDrive(t *testing.T) {
+	os.Setenv("GDRIVE_INSTANCE_1_REFRESH_TOKEN", "refresh")
+	os.Setenv("GDRIVE_INSTANCE_1_CLIENT_ID", "client")
+	os.Setenv("GDRIVE_INSTANCE_1_CLIENT_SECRET", "secret")
+	os.Setenv("GDRIVE_INSTANCE_2_REFRESH_TOKEN", "refresh")
+	os.Setenv("GDRIVE_INSTANCE_2_CLIENT_ID", "client")
+	os.Setenv("GDRIVE_INSTANCE_2_CLIENT_SECRET", "secret")
+	os.Setenv("GDRIVE_INSTANCE_2_URL", "http://localhost:8080")
+	os.Setenv("GDRIVE_INSTANCE_2_NAME", "gdrive-fake")
+
+	defer func() {
+		for _, i := range []string{"1", "2"} {
+			os.Unsetenv("GDRIVE_INSTANCE_" + i + "_REFRESH_TOKEN")
+			os.Unsetenv("GDRIVE_INSTANCE_" + i + "_CLIENT_ID")
+			os.Unsetenv("GDRIVE_INSTANCE_" + i + "_CLIENT_SECRET")
+		}
+		os.Unsetenv("GDRIVE_INSTANCE_2_URL")
+		os.Unsetenv("GDRIVE_INSTANCE_2_NAME")
+	}()
+
+	configs, err := LoadConfigs()
+	if err != nil {
+		t.Fatalf("LoadConfigs failed: %v", err)
+	}
+	if len(configs) != 2 {
+		t.Fatalf("Expected 2 configs, got %d", len(configs))
+	}
+	if cfg := configs[0]; cfg.ServiceType != "gdrive" || cfg.InstanceName != "gdrive-instance-1" || cfg.URL != "https://www.googleapis.com" {
+		t.Errorf("Unexpected config: %+v", cfg)
+	}
+	// The API base URL can point to a local fake
+	if cfg := configs[1]; cfg.InstanceName != "gdrive-fake" || cfg.URL != "http://localhost:8080" || cfg.ClientID != "client" {
+		t.Errorf("Unexpected config: %+v", cfg)
+	}
+}
+
 func TestLoadConfigsInvalid(t *testing.T) {
 	// No instances
 	_, err := LoadConfigs()
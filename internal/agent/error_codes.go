@@ -1,19 +1,118 @@
 package agent
 
 import (
-	"fmt"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
 	"net/http"
 	"strings"
+	"syscall"
+
+	"github.com/xXRoxXeRXx/cloud-performance-monitor/internal/utils"
 )
 
-// ExtractErrorCode extracts a specific error code from various error types
+// ExtractErrorCode extracts a specific error code from various error types. Typed errors
+// (provider errors, HTTP status errors, network errors) are classified by their type;
+// only errors without type information are classified by their message.
 func ExtractErrorCode(err error, operation string) string {
 	if err == nil {
 		return "none"
 	}
+	if code, ok := typedErrorCode(err); ok {
+		return code
+	}
+	return heuristicErrorCode(err, operation)
+}
+
+// typedErrorCode classifies err by its type. ok is false if err carries no type information.
+func typedErrorCode(err error) (code string, ok bool) {
+	var providerErr *utils.ProviderError
+	if errors.As(err, &providerErr) {
+		if code := providerTagErrorCode(providerErr.Tag); code != "" {
+			return code, true
+		}
+	}
+
+	var statusErr *utils.HTTPStatusError
+	if errors.As(err, &statusErr) {
+		if statusErr.RateLimited {
+			return "http_429_rate_limited", true
+		}
+		return StatusErrorCode(statusErr.StatusCode), true
+	}
+	if providerErr != nil && providerErr.StatusCode >= 400 {
+		return StatusErrorCode(providerErr.StatusCode), true
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "network_timeout", true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		if dnsErr.IsTimeout {
+			return "network_timeout", true
+		}
+		return "network_dns_error", true
+	}
+	var (
+		unknownAuthority x509.UnknownAuthorityError
+		invalidCert      x509.CertificateInvalidError
+		hostnameErr      x509.HostnameError
+		verifyErr        *tls.CertificateVerificationError
+		recordHeaderErr  tls.RecordHeaderError
+	)
+	if errors.As(err, &unknownAuthority) || errors.As(err, &invalidCert) || errors.As(err, &hostnameErr) ||
+		errors.As(err, &verifyErr) || errors.As(err, &recordHeaderErr) {
+		return "network_tls_error", true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return "network_timeout", true
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return "network_connection_error", true
+	}
+	return "", false
+}
 
+// providerTagErrorCode maps provider error tags that say more than the HTTP status, e.g.
+// Dropbox reports a missing path as 409 "path/not_found", S3 throttles with 503
+// "SlowDown" and Google Drive with 403 "rateLimitExceeded". It returns "" for other tags.
+func providerTagErrorCode(tag string) string {
+	tag = strings.ToLower(tag)
+	switch {
+	case tag == "":
+		return ""
+	case strings.Contains(tag, "insufficient_space") || strings.Contains(tag, "insufficientstorage") || strings.Contains(tag, "quota"):
+		return "quota_exceeded"
+	case strings.Contains(tag, "too_many_requests") || strings.Contains(tag, "too_many_write_operations") || tag == "slowdown" ||
+		strings.HasSuffix(tag, "ratelimitexceeded"):
+		return "http_429_rate_limited"
+	case strings.Contains(tag, "access_token") || tag == "invalid_grant":
+		return "token_error"
+	case tag == "signaturedoesnotmatch" || tag == "invalidaccesskeyid":
+		return "auth_failed"
+	case strings.Contains(tag, "no_write_permission"):
+		return "permission_denied"
+	case strings.Contains(tag, "not_found") || strings.HasSuffix(tag, `\notfound`) || strings.HasPrefix(tag, "nosuch"):
+		return "http_404_not_found"
+	case strings.Contains(tag, "conflict"):
+		return "http_409_conflict"
+	}
+	return ""
+}
+
+// heuristicErrorCode classifies an untyped error by its message
+func heuristicErrorCode(err error, operation string) string {
 	errStr := strings.ToLower(err.Error())
 
+	// Data integrity patterns - checked first, the messages contain hex checksums
+	if strings.Contains(errStr, "checksum mismatch") || strings.Contains(errStr, "data corruption") {
+		return "data_corruption"
+	}
+
 	// HTTP Status Code patterns (from error strings)
 	if strings.Contains(errStr, "status 401") || strings.Contains(errStr, "401") || strings.Contains(errStr, "unauthorized") {
 		return "http_401_unauthorized"
@@ -103,6 +202,8 @@ func ExtractErrorCode(err error, operation string) string {
 		return "directory_error"
 	case "delete", "cleanup":
 		return "cleanup_failed"
+	case "list", "stat":
+		return "metadata_failed"
 	case "auth", "token":
 		return "auth_failed"
 	default:
@@ -117,47 +218,52 @@ func ExtractHTTPErrorCode(resp *http.Response, err error, operation string) stri
 	}
 
 	if resp != nil && resp.StatusCode >= 400 {
-		switch resp.StatusCode {
-		case 400:
-			return "http_400_bad_request"
-		case 401:
-			return "http_401_unauthorized"
-		case 403:
-			return "http_403_forbidden"
-		case 404:
-			return "http_404_not_found"
-		case 409:
-			return "http_409_conflict"
-		case 412:
-			return "http_412_precondition_failed"
-		case 413:
-			return "http_413_payload_too_large"
-		case 429:
-			return "http_429_rate_limited"
-		case 500:
-			return "http_500_server_error"
-		case 501:
-			return "http_501_not_implemented"
-		case 502:
-			return "http_502_bad_gateway"
-		case 503:
-			return "http_503_unavailable"
-		case 504:
-			return "http_504_timeout"
-		case 507:
-			return "http_507_insufficient_storage"
-		default:
-			if resp.StatusCode >= 400 && resp.StatusCode < 500 {
-				return fmt.Sprintf("http_%d_client_error", resp.StatusCode)
-			} else if resp.StatusCode >= 500 {
-				return fmt.Sprintf("http_%d_server_error", resp.StatusCode)
-			}
-		}
+		return StatusErrorCode(resp.StatusCode)
 	}
 
 	return "none"
 }
 
+// StatusErrorCode returns the error code of an HTTP error status (400 and above).
+// Statuses without their own code share http_4xx_client_error and http_5xx_server_error,
+// so that every code can be reset by GetAllErrorCodes after a successful test.
+func StatusErrorCode(status int) string {
+	switch status {
+	case 400:
+		return "http_400_bad_request"
+	case 401:
+		return "http_401_unauthorized"
+	case 403:
+		return "http_403_forbidden"
+	case 404:
+		return "http_404_not_found"
+	case 409:
+		return "http_409_conflict"
+	case 412:
+		return "http_412_precondition_failed"
+	case 413:
+		return "http_413_payload_too_large"
+	case 429:
+		return "http_429_rate_limited"
+	case 500:
+		return "http_500_server_error"
+	case 501:
+		return "http_501_not_implemented"
+	case 502:
+		return "http_502_bad_gateway"
+	case 503:
+		return "http_503_unavailable"
+	case 504:
+		return "http_504_timeout"
+	case 507:
+		return "http_507_insufficient_storage"
+	}
+	if status >= 500 {
+		return "http_5xx_server_error"
+	}
+	return "http_4xx_client_error"
+}
+
 // GetAllErrorCodes returns a list of all possible error codes
 // This is used to reset metrics after successful tests to prevent false alerts
 func GetAllErrorCodes() []string {
@@ -177,6 +283,8 @@ func GetAllErrorCodes() []string {
 		"http_503_unavailable",
 		"http_504_timeout",
 		"http_507_insufficient_storage",
+		"http_4xx_client_error",
+		"http_5xx_server_error",
 		
 		// Authentication Errors
 		"auth_failed",
@@ -188,6 +296,7 @@ func GetAllErrorCodes() []string {
 		"network_connection_error",
 		"network_dns_error",
 		"network_tls_error",
+		"certificate_expiring",
 		
 		// File Operation Errors
 		"permission_denied",
@@ -195,6 +304,9 @@ func GetAllErrorCodes() []string {
 		"file_too_large",
 		"size_mismatch",
 		"incomplete_download",
+		"data_corruption",
+		"listing_incomplete",
+		"metadata_failed",
 		
 		// WebDAV Specific Errors
 		"webdav_error",
@@ -2,8 +2,12 @@ package agent
 
 import (
 	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"testing"
+
+	"github.com/xXRoxXeRXx/cloud-performance-monitor/internal/utils"
 )
 
 func TestExtractErrorCode(t *testing.T) {
@@ -49,6 +53,12 @@ func TestExtractErrorCode(t *testing.T) {
 			operation: "download",
 			expected:  "network_connection_error",
 		},
+		{
+			name:      "Checksum mismatch",
+			err:       errors.New("checksum mismatch: uploaded sha256 5004a1, downloaded sha256 404b2c"),
+			operation: "download",
+			expected:  "data_corruption",
+		},
 		{
 			name:      "Generic upload error",
 			err:       errors.New("some generic error"),
@@ -109,6 +119,79 @@ func TestExtractErrorCode(t *testing.T) {
 	}
 }
 
+func TestExtractErrorCodeTyped(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{
+			name: "status takes precedence over numbers in the message",
+			err: fmt.Errorf("failed to upload chunk 404 of smallfile_404.tmp: %w",
+				&utils.ProviderError{Operation: "upload", Phase: "chunk", StatusCode: 500, Err: &utils.HTTPStatusError{StatusCode: 500}}),
+			expected: "http_500_server_error",
+		},
+		{
+			name:     "provider tag refines the status",
+			err:      &utils.ProviderError{Operation: "download", StatusCode: 409, Tag: "path/not_found", Err: &utils.HTTPStatusError{StatusCode: 409}},
+			expected: "http_404_not_found",
+		},
+		{
+			name:     "WebDAV quota exception",
+			err:      &utils.ProviderError{Operation: "upload", StatusCode: 507, Tag: `Sabre\DAV\Exception\InsufficientStorage`, Err: &utils.HTTPStatusError{StatusCode: 507}},
+			expected: "quota_exceeded",
+		},
+		{
+			name:     "WebDAV not found exception",
+			err:      &utils.ProviderError{Operation: "download", StatusCode: 400, Tag: `Sabre\DAV\Exception\NotFound`, Err: &utils.HTTPStatusError{StatusCode: 400}},
+			expected: "http_404_not_found",
+		},
+		{
+			name:     "S3 throttling",
+			err:      &utils.ProviderError{Operation: "upload", Phase: "part", StatusCode: 503, Tag: "SlowDown", Err: &utils.HTTPStatusError{StatusCode: 503}},
+			expected: "http_429_rate_limited",
+		},
+		{
+			name:     "S3 wrong secret key",
+			err:      &utils.ProviderError{Operation: "upload", StatusCode: 403, Tag: "SignatureDoesNotMatch", Err: &utils.HTTPStatusError{StatusCode: 403}},
+			expected: "auth_failed",
+		},
+		{
+			name:     "S3 missing bucket",
+			err:      &utils.ProviderError{Operation: "mkdir", StatusCode: 404, Tag: "NoSuchBucket", Err: &utils.HTTPStatusError{StatusCode: 404}},
+			expected: "http_404_not_found",
+		},
+		{
+			name:     "Google Drive rate limit",
+			err:      &utils.ProviderError{Operation: "upload", Phase: "chunk", StatusCode: 403, Tag: "userRateLimitExceeded", Err: &utils.HTTPStatusError{StatusCode: 403}},
+			expected: "http_429_rate_limited",
+		},
+		{
+			name:     "Google Drive revoked refresh token",
+			err:      &utils.ProviderError{Operation: "token_refresh", StatusCode: 400, Tag: "invalid_grant", Err: &utils.HTTPStatusError{StatusCode: 400}},
+			expected: "token_error",
+		},
+		{
+			name:     "rate limited with status 403",
+			err:      &utils.HTTPStatusError{StatusCode: 403, RateLimited: true},
+			expected: "http_429_rate_limited",
+		},
+		{
+			name:     "DNS failure",
+			err:      utils.WrapProviderError("nextcloud", "upload", "", &net.DNSError{Err: "no such host", Name: "cloud.example.com"}),
+			expected: "network_dns_error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := ExtractErrorCode(tt.err, "upload"); result != tt.expected {
+				t.Errorf("ExtractErrorCode(%v) = %s, want %s", tt.err, result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestExtractHTTPErrorCode(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -157,14 +240,14 @@ func TestExtractHTTPErrorCode(t *testing.T) {
 			resp:      &http.Response{StatusCode: 418},
 			err:       nil,
 			operation: "upload",
-			expected:  "http_418_client_error",
+			expected:  "http_4xx_client_error",
 		},
 		{
 			name:      "Unknown 5xx error",
 			resp:      &http.Response{StatusCode: 599},
 			err:       nil,
 			operation: "upload",
-			expected:  "http_599_server_error",
+			expected:  "http_5xx_server_error",
 		},
 	}
 
@@ -177,3 +260,16 @@ func TestExtractHTTPErrorCode(t *testing.T) {
 		})
 	}
 }
+
+func TestStatusErrorCodesAreResettable(t *testing.T) {
+	// Success resets only the codes of GetAllErrorCodes, so every status must map to one
+	known := make(map[string]bool)
+	for _, code := range GetAllErrorCodes() {
+		known[code] = true
+	}
+	for status := 400; status < 600; status++ {
+		if code := StatusErrorCode(status); !known[code] {
+			t.Errorf("StatusErrorCode(%d) = %s, which is not in GetAllErrorCodes", status, code)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Execution modes for scheduled tests
+const (
+	// ExecutionSequential runs one test at a time across all instances
+	ExecutionSequential = "sequential"
+	// ExecutionConcurrent runs tests in parallel within the configured limits
+	ExecutionConcurrent = "concurrent"
+)
+
+const (
+	DefaultMaxConcurrentTests           = 4
+	DefaultMaxConcurrentTestsPerService = 1
+)
+
+// ExecutionLimits bounds how many tests the TestManager runs at the same time.
+// An instance is never tested twice at the same time, regardless of the limits.
+type ExecutionLimits struct {
+	Mode          string
+	MaxConcurrent int // over all services
+	MaxPerService int // per service type (e.g. all Dropbox instances)
+}
+
+// SequentialLimits returns the limits of the sequential execution mode
+func SequentialLimits() ExecutionLimits {
+	return ExecutionLimits{Mode: ExecutionSequential, MaxConcurrent: 1, MaxPerService: 1}
+}
+
+// GetExecutionLimits reads the execution mode and limits from the environment:
+// EXECUTION_MODE (sequential|concurrent), MAX_CONCURRENT_TESTS and
+// MAX_CONCURRENT_TESTS_PER_SERVICE
+func GetExecutionLimits() (ExecutionLimits, error) {
+	mode := strings.ToLower(strings.TrimSpace(os.Getenv("EXECUTION_MODE")))
+	switch mode {
+	case "", ExecutionSequential:
+		return SequentialLimits(), nil
+	case ExecutionConcurrent:
+	default:
+		return ExecutionLimits{}, fmt.Errorf("invalid EXECUTION_MODE %q, must be %s or %s", mode, ExecutionSequential, ExecutionConcurrent)
+	}
+
+	limits := ExecutionLimits{
+		Mode:          ExecutionConcurrent,
+		MaxConcurrent: DefaultMaxConcurrentTests,
+		MaxPerService: DefaultMaxConcurrentTestsPerService,
+	}
+	if value := os.Getenv("MAX_CONCURRENT_TESTS"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return ExecutionLimits{}, fmt.Errorf("MAX_CONCURRENT_TESTS must be a positive number, got %q", value)
+		}
+		limits.MaxConcurrent = n
+	}
+	if value := os.Getenv("MAX_CONCURRENT_TESTS_PER_SERVICE"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return ExecutionLimits{}, fmt.Errorf("MAX_CONCURRENT_TESTS_PER_SERVICE must be a positive number, got %q", value)
+		}
+		limits.MaxPerService = n
+	}
+	if limits.MaxPerService > limits.MaxConcurrent {
+		limits.MaxPerService = limits.MaxConcurrent
+	}
+	return limits, nil
+}
+
+// SetLimits configures the concurrency limits used by Execute.
+// It must be called before tests are executed.
+func (tm *TestManager) SetLimits(limits ExecutionLimits) {
+	if limits.MaxConcurrent <= 0 {
+		limits.MaxConcurrent = 1
+	}
+	if limits.MaxPerService <= 0 {
+		limits.MaxPerService = 1
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.limits = limits
+	tm.globalSlots = make(chan struct{}, limits.MaxConcurrent)
+	tm.serviceSlots = make(map[string]chan struct{})
+}
+
+// Limits returns the configured concurrency limits
+func (tm *TestManager) Limits() ExecutionLimits {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	return tm.limits
+}
+
+// Execute runs testFunc as test testID of the given service once a slot is free and
+// blocks until it has finished. testID identifies the instance; the same testID
+// never runs twice at the same time. The test is cancelled when ctx is cancelled,
+// when StopTest is called or on shutdown.
+func (tm *TestManager) Execute(ctx context.Context, testID, service string, testFunc func(ctx context.Context) error) error {
+	queued := time.Now()
+
+	// Queued tests count as running so that shutdown waits for them to give up their slot
+	tm.wg.Add(1)
+	defer tm.wg.Done()
+
+	tm.mu.Lock()
+	instanceLock := slotFor(tm.instanceLocks, testID, 1)
+	serviceSlots := slotFor(tm.serviceSlots, service, tm.limits.MaxPerService)
+	globalSlots := tm.globalSlots
+	tm.mu.Unlock()
+
+	// Always acquire in the same order (instance → service → global) to avoid deadlocks
+	for _, slots := range []chan struct{}{instanceLock, serviceSlots, globalSlots} {
+		select {
+		case slots <- struct{}{}:
+			defer func(slots chan struct{}) { <-slots }(slots)
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tm.shutdown.Context().Done():
+			return tm.shutdown.Context().Err()
+		}
+	}
+	if tm.shutdown.IsShuttingDown() {
+		return context.Canceled
+	}
+	TestQueueWaitSeconds.WithLabelValues(service, testID).Set(time.Since(queued).Seconds())
+
+	testCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(tm.shutdown.Context(), cancel)
+	defer stop()
+
+	tm.mu.Lock()
+	tm.runningTests[testID] = cancel
+	tm.mu.Unlock()
+	defer func() {
+		tm.mu.Lock()
+		delete(tm.runningTests, testID)
+		tm.mu.Unlock()
+	}()
+
+	RunningTests.WithLabelValues(service).Inc()
+	defer RunningTests.WithLabelValues(service).Dec()
+
+	return testFunc(testCtx)
+}
+
+// RunningTestIDs returns the IDs of the tests currently running
+func (tm *TestManager) RunningTestIDs() []string {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	ids := make([]string, 0, len(tm.runningTests))
+	for id := range tm.runningTests {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// slotFor returns the semaphore for key, creating it with the given capacity
+func slotFor(slots map[string]chan struct{}, key string, capacity int) chan struct{} {
+	ch, ok := slots[key]
+	if !ok {
+		ch = make(chan struct{}, capacity)
+		slots[key] = ch
+	}
+	return ch
+}
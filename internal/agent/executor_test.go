@@ -0,0 +1,185 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// concurrencyProbe records the maximum number of concurrently running tests
+type concurrencyProbe struct {
+	mu      sync.Mutex
+	running map[string]int
+	max     map[string]int
+}
+
+func newConcurrencyProbe() *concurrencyProbe {
+	return &concurrencyProbe{running: make(map[string]int), max: make(map[string]int)}
+}
+
+func (p *concurrencyProbe) run(keys ...string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		p.mu.Lock()
+		for _, k := range keys {
+			p.running[k]++
+			if p.running[k] > p.max[k] {
+				p.max[k] = p.running[k]
+			}
+		}
+		p.mu.Unlock()
+
+		time.Sleep(30 * time.Millisecond)
+
+		p.mu.Lock()
+		for _, k := range keys {
+			p.running[k]--
+		}
+		p.mu.Unlock()
+		return nil
+	}
+}
+
+func (p *concurrencyProbe) maxOf(key string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.max[key]
+}
+
+func TestExecuteRespectsLimits(t *testing.T) {
+	tests := []struct {
+		name          string
+		limits        ExecutionLimits
+		wantGlobal    int
+		wantDropbox   int
+		wantNextcloud int
+	}{
+		{"sequential", SequentialLimits(), 1, 1, 1},
+		{"concurrent", ExecutionLimits{Mode: ExecutionConcurrent, MaxConcurrent: 3, MaxPerService: 1}, 2, 1, 1},
+		{"per service", ExecutionLimits{Mode: ExecutionConcurrent, MaxConcurrent: 3, MaxPerService: 2}, 3, 2, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm := NewTestManager(NewShutdownManager(time.Second))
+			tm.SetLimits(tt.limits)
+			probe := newConcurrencyProbe()
+
+			var wg sync.WaitGroup
+			for i := 0; i < 4; i++ {
+				for _, service := range []string{"dropbox", "nextcloud"} {
+					wg.Add(1)
+					go func(service string, i int) {
+						defer wg.Done()
+						testID := service + "/" + string(rune('a'+i))
+						if err := tm.Execute(context.Background(), testID, service, probe.run("all", service)); err != nil {
+							t.Errorf("Execute failed: %v", err)
+						}
+					}(service, i)
+				}
+			}
+			wg.Wait()
+
+			if got := probe.maxOf("all"); got != tt.wantGlobal {
+				t.Errorf("expected at most %d concurrent tests, got %d", tt.wantGlobal, got)
+			}
+			if got := probe.maxOf("dropbox"); got != tt.wantDropbox {
+				t.Errorf("expected at most %d concurrent dropbox tests, got %d", tt.wantDropbox, got)
+			}
+			if got := probe.maxOf("nextcloud"); got != tt.wantNextcloud {
+				t.Errorf("expected at most %d concurrent nextcloud tests, got %d", tt.wantNextcloud, got)
+			}
+		})
+	}
+}
+
+func TestExecuteSerializesSameInstance(t *testing.T) {
+	tm := NewTestManager(NewShutdownManager(time.Second))
+	tm.SetLimits(ExecutionLimits{Mode: ExecutionConcurrent, MaxConcurrent: 4, MaxPerService: 4})
+	probe := newConcurrencyProbe()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = tm.Execute(context.Background(), "nextcloud/one", "nextcloud", probe.run("one"))
+		}()
+	}
+	wg.Wait()
+
+	if got := probe.maxOf("one"); got != 1 {
+		t.Errorf("expected the same instance to run at most once at a time, got %d", got)
+	}
+}
+
+func TestExecuteCancellation(t *testing.T) {
+	tm := NewTestManager(NewShutdownManager(time.Second))
+
+	started := make(chan struct{})
+	var result atomic.Value
+	go func() {
+		err := tm.Execute(context.Background(), "hidrive/a", "hidrive", func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		result.Store(err)
+	}()
+	<-started
+
+	// A queued test gives up when its context is cancelled
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	called := false
+	err := tm.Execute(ctx, "hidrive/b", "hidrive", func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) || called {
+		t.Errorf("expected queued test to time out without running, got err=%v called=%v", err, called)
+	}
+
+	if ids := tm.RunningTestIDs(); len(ids) != 1 || ids[0] != "hidrive/a" {
+		t.Fatalf("unexpected running tests: %v", ids)
+	}
+	tm.StopTest("hidrive/a")
+
+	deadline := time.Now().Add(time.Second)
+	for result.Load() == nil && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err, _ := result.Load().(error); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected running test to be cancelled, got %v", result.Load())
+	}
+}
+
+func TestGetExecutionLimits(t *testing.T) {
+	t.Setenv("EXECUTION_MODE", "")
+	if limits, err := GetExecutionLimits(); err != nil || limits != SequentialLimits() {
+		t.Errorf("expected sequential limits by default, got %+v, %v", limits, err)
+	}
+
+	t.Setenv("EXECUTION_MODE", "concurrent")
+	t.Setenv("MAX_CONCURRENT_TESTS", "6")
+	t.Setenv("MAX_CONCURRENT_TESTS_PER_SERVICE", "2")
+	limits, err := GetExecutionLimits()
+	if err != nil {
+		t.Fatalf("GetExecutionLimits failed: %v", err)
+	}
+	if limits.Mode != ExecutionConcurrent || limits.MaxConcurrent != 6 || limits.MaxPerService != 2 {
+		t.Errorf("unexpected limits: %+v", limits)
+	}
+
+	t.Setenv("MAX_CONCURRENT_TESTS", "zero")
+	if _, err := GetExecutionLimits(); err == nil {
+		t.Error("expected error for invalid MAX_CONCURRENT_TESTS")
+	}
+
+	t.Setenv("EXECUTION_MODE", "parallel")
+	if _, err := GetExecutionLimits(); err == nil {
+		t.Error("expected error for unknown EXECUTION_MODE")
+	}
+}
@@ -55,6 +55,14 @@ func (hc *HealthChecker) RegisterService(name string) {
 	}
 }
 
+// UnregisterService removes a service from health checking
+func (hc *HealthChecker) UnregisterService(name string) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	
+	delete(hc.services, name)
+}
+
 // UpdateServiceHealth updates the health status of a service
 func (hc *HealthChecker) UpdateServiceHealth(name, status string, responseTime time.Duration, err error) {
 	hc.mu.Lock()
@@ -0,0 +1,332 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// History defaults
+const (
+	// HistoryRetention is how long test results are kept; it covers the monthly averages
+	HistoryRetention = 30 * 24 * time.Hour
+	// DefaultHistoryAggregationInterval is how often the daily and monthly gauges are recomputed
+	DefaultHistoryAggregationInterval = 5 * time.Minute
+)
+
+// HistoryRecord is a persisted test result. Err is not serialisable, so its message
+// is stored separately.
+type HistoryRecord struct {
+	RunResult
+	Error string `json:"error,omitempty"`
+}
+
+// newHistoryRecord converts a run result into its persisted form
+func newHistoryRecord(result *RunResult) HistoryRecord {
+	rec := HistoryRecord{RunResult: *result}
+	rec.Phases = append([]PhaseResult(nil), result.Phases...)
+	if result.Err != nil {
+		rec.Error = result.Err.Error()
+	}
+	return rec
+}
+
+// HistoryStore keeps the test results of the last HistoryRetention in memory and,
+// if a path is set, in an append-only JSON Lines file so that they survive restarts
+type HistoryStore struct {
+	path      string
+	retention time.Duration
+
+	mu      sync.RWMutex
+	records []HistoryRecord // ordered by StartTime
+	file    *os.File
+}
+
+// HistoryFilePath returns the history file from HISTORY_FILE, empty for memory only
+func HistoryFilePath() string {
+	return os.Getenv("HISTORY_FILE")
+}
+
+// NewHistoryStore opens the history at path, loading and compacting existing records.
+// An empty path keeps the history in memory only.
+func NewHistoryStore(path string) (*HistoryStore, error) {
+	s := &HistoryStore{path: path, retention: HistoryRetention}
+	if path == "" {
+		return s, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("could not create history directory: %w", err)
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	if err := s.Compact(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load reads all records within the retention from the history file
+func (s *HistoryStore) load() error {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not open history file: %w", err)
+	}
+	defer f.Close()
+
+	cutoff := time.Now().Add(-s.retention)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var rec HistoryRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			// A crash may leave a truncated last line; skip it instead of losing the history
+			LogServiceOperation(WARN, "history", s.path, "load", "skip",
+				fmt.Sprintf("Skipping unreadable history line %d", line),
+				WithError(err))
+			continue
+		}
+		if rec.StartTime.After(cutoff) {
+			s.records = append(s.records, rec)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("could not read history file: %w", err)
+	}
+	// Concurrent runs are appended in completion order
+	sort.SliceStable(s.records, func(i, j int) bool { return s.records[i].StartTime.Before(s.records[j].StartTime) })
+	return nil
+}
+
+// Add stores a test result
+func (s *HistoryStore) Add(result *RunResult) error {
+	rec := newHistoryRecord(result)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Runs finish out of order when tests run concurrently; keep the slice sorted
+	i := len(s.records)
+	for i > 0 && s.records[i-1].StartTime.After(rec.StartTime) {
+		i--
+	}
+	s.records = append(s.records, HistoryRecord{})
+	copy(s.records[i+1:], s.records[i:])
+	s.records[i] = rec
+
+	if s.path == "" {
+		return nil
+	}
+	if s.file == nil {
+		f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return fmt.Errorf("could not open history file: %w", err)
+		}
+		s.file = f
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("could not append to history file: %w", err)
+	}
+	return nil
+}
+
+// Records returns the stored records started at or after since, oldest first
+func (s *HistoryStore) Records(since time.Time) []HistoryRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []HistoryRecord
+	for _, rec := range s.records {
+		if !rec.StartTime.Before(since) {
+			out = append(out, rec)
+		}
+	}
+	return out
+}
+
+// Compact drops records older than the retention and rewrites the history file
+func (s *HistoryStore) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := time.Now().Add(-s.retention)
+	keep := 0
+	for keep < len(s.records) && !s.records[keep].StartTime.After(cutoff) {
+		keep++
+	}
+	s.records = append([]HistoryRecord(nil), s.records[keep:]...)
+	if s.path == "" {
+		return nil
+	}
+
+	// Write to a temporary file and rename so that a crash never leaves a partial history
+	tmp := s.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("could not compact history file: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, rec := range s.records {
+		if err := enc.Encode(rec); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// Close closes the history file
+func (s *HistoryStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// historyAggregate accumulates the results of one instance within a time window
+type historyAggregate struct {
+	runs, successful       int
+	uploadSum, uploads     float64
+	downloadSum, downloads float64
+	typeCounts             map[string]int
+}
+
+func (a *historyAggregate) add(rec *HistoryRecord) {
+	a.runs++
+	if rec.Error == "" {
+		a.successful++
+	}
+	types := make(map[string]bool)
+	for _, p := range rec.Phases {
+		if p.Phase == PhaseCleanup || p.Phase == "connection" {
+			continue
+		}
+		types[metricTypeFor(p.Phase)] = true
+		if p.ErrorCode != "none" || p.SpeedMBps <= 0 {
+			continue
+		}
+		switch p.Phase {
+		case PhaseUpload:
+			a.uploadSum += p.SpeedMBps
+			a.uploads++
+		case PhaseDownload:
+			a.downloadSum += p.SpeedMBps
+			a.downloads++
+		}
+	}
+	for t := range types {
+		a.typeCounts[t]++
+	}
+}
+
+// aggregateHistory groups the records started after since by service and instance
+func aggregateHistory(records []HistoryRecord, since time.Time) map[[2]string]*historyAggregate {
+	aggs := make(map[[2]string]*historyAggregate)
+	for i := range records {
+		rec := &records[i]
+		if rec.StartTime.Before(since) {
+			continue
+		}
+		key := [2]string{rec.ServiceType, rec.InstanceName}
+		agg, ok := aggs[key]
+		if !ok {
+			agg = &historyAggregate{typeCounts: make(map[string]int)}
+			aggs[key] = agg
+		}
+		agg.add(rec)
+	}
+	return aggs
+}
+
+// UpdateAggregates recomputes the daily (24h) and monthly (30d) average gauges
+func (s *HistoryStore) UpdateAggregates(now time.Time) {
+	records := s.Records(now.Add(-s.retention))
+	daily := aggregateHistory(records, now.Add(-24*time.Hour))
+	monthly := aggregateHistory(records, now.Add(-30*24*time.Hour))
+
+	// Instances without results in a window must not keep their old values
+	for _, g := range []interface{ Reset() }{
+		DailyAverageUploadSpeed, DailyAverageDownloadSpeed, DailyTestCount, DailySuccessRate,
+		MonthlyAverageUploadSpeed, MonthlyAverageDownloadSpeed, MonthlyTestCount, MonthlySuccessRate,
+	} {
+		g.Reset()
+	}
+
+	export := func(aggs map[[2]string]*historyAggregate, upload, download, rate, count *prometheus.GaugeVec) {
+		for key, agg := range aggs {
+			service, instance := key[0], key[1]
+			if agg.uploads > 0 {
+				upload.WithLabelValues(service, instance).Set(agg.uploadSum / agg.uploads)
+			}
+			if agg.downloads > 0 {
+				download.WithLabelValues(service, instance).Set(agg.downloadSum / agg.downloads)
+			}
+			rate.WithLabelValues(service, instance).Set(100 * float64(agg.successful) / float64(agg.runs))
+			for t, n := range agg.typeCounts {
+				count.WithLabelValues(service, instance, t).Set(float64(n))
+			}
+		}
+	}
+	export(daily, DailyAverageUploadSpeed, DailyAverageDownloadSpeed, DailySuccessRate, DailyTestCount)
+	export(monthly, MonthlyAverageUploadSpeed, MonthlyAverageDownloadSpeed, MonthlySuccessRate, MonthlyTestCount)
+}
+
+// RunHistoryAggregation updates the aggregate gauges immediately and then every
+// interval, compacting the history file once a day, until ctx is cancelled
+func RunHistoryAggregation(ctx context.Context, store *HistoryStore, interval time.Duration) {
+	store.UpdateAggregates(time.Now())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	lastCompaction := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if now.Sub(lastCompaction) >= 24*time.Hour {
+				if err := store.Compact(); err != nil {
+					LogServiceOperation(ERROR, "history", store.path, "compact", "error",
+						"Could not compact history file",
+						WithError(err))
+				}
+				lastCompaction = now
+			}
+			store.UpdateAggregates(now)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package agent
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func historyTestResult(instance string, start time.Time, uploadMBps float64, err error) *RunResult {
+	result := &RunResult{
+		ServiceType:  "nextcloud",
+		InstanceName: instance,
+		StartTime:    start,
+		Duration:     10 * time.Second,
+		ErrorCode:    "none",
+		Phases: []PhaseResult{
+			{Phase: PhaseUpload, SpeedMBps: uploadMBps, ErrorCode: "none"},
+			{Phase: PhaseDownload, SpeedMBps: 2 * uploadMBps, ErrorCode: "none"},
+		},
+	}
+	if err != nil {
+		result.Err = err
+		result.ErrorCode = "http_500_server_error"
+		result.Phases[1] = PhaseResult{Phase: PhaseDownload, ErrorCode: "http_500_server_error", Err: err}
+	}
+	return result
+}
+
+func TestHistoryStorePersistsAcrossRestarts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	store, err := NewHistoryStore(path)
+	if err != nil {
+		t.Fatalf("NewHistoryStore failed: %v", err)
+	}
+	now := time.Now()
+	if err := store.Add(historyTestResult("persist", now.Add(-time.Hour), 10, nil)); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := store.Add(historyTestResult("persist", now.Add(-2*time.Hour), 20, errors.New("download failed with status 500"))); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	store.Close()
+
+	reopened, err := NewHistoryStore(path)
+	if err != nil {
+		t.Fatalf("reopening history failed: %v", err)
+	}
+	records := reopened.Records(time.Time{})
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records after restart, got %d", len(records))
+	}
+	if !records[0].StartTime.Before(records[1].StartTime) {
+		t.Error("expected records ordered by start time")
+	}
+	if records[0].Error == "" || records[0].ErrorCode != "http_500_server_error" {
+		t.Errorf("expected failed run to keep its error, got %+v", records[0])
+	}
+}
+
+func TestHistoryStoreDropsExpiredAndCorruptLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	store, _ := NewHistoryStore(path)
+	_ = store.Add(historyTestResult("expired", time.Now().Add(-31*24*time.Hour), 10, nil))
+	_ = store.Add(historyTestResult("expired", time.Now(), 10, nil))
+	store.Close()
+
+	// Simulate a crash while appending
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	_, _ = f.WriteString(`{"service":"nextcl`)
+	f.Close()
+
+	reopened, err := NewHistoryStore(path)
+	if err != nil {
+		t.Fatalf("reopening history failed: %v", err)
+	}
+	if n := len(reopened.Records(time.Time{})); n != 1 {
+		t.Errorf("expected only the current record, got %d", n)
+	}
+}
+
+func TestHistoryUpdateAggregates(t *testing.T) {
+	store, _ := NewHistoryStore("")
+	now := time.Now()
+	_ = store.Add(historyTestResult("aggregates", now.Add(-1*time.Hour), 10, nil))
+	_ = store.Add(historyTestResult("aggregates", now.Add(-2*time.Hour), 20, nil))
+	_ = store.Add(historyTestResult("aggregates", now.Add(-3*time.Hour), 30, errors.New("status 500")))
+	_ = store.Add(historyTestResult("aggregates", now.Add(-10*24*time.Hour), 60, nil))
+
+	store.UpdateAggregates(now)
+
+	if v := testutil.ToFloat64(DailyAverageUploadSpeed.WithLabelValues("nextcloud", "aggregates")); v != 20 {
+		t.Errorf("expected daily upload average 20, got %v", v)
+	}
+	if v := testutil.ToFloat64(DailyAverageDownloadSpeed.WithLabelValues("nextcloud", "aggregates")); v != 30 {
+		t.Errorf("expected daily download average 30 (failed download excluded), got %v", v)
+	}
+	if v := testutil.ToFloat64(MonthlyAverageUploadSpeed.WithLabelValues("nextcloud", "aggregates")); v != 30 {
+		t.Errorf("expected monthly upload average 30, got %v", v)
+	}
+	if v := testutil.ToFloat64(DailySuccessRate.WithLabelValues("nextcloud", "aggregates")); v < 66 || v > 67 {
+		t.Errorf("expected daily success rate 66.7%%, got %v", v)
+	}
+	if v := testutil.ToFloat64(MonthlySuccessRate.WithLabelValues("nextcloud", "aggregates")); v != 75 {
+		t.Errorf("expected monthly success rate 75%%, got %v", v)
+	}
+	if v := testutil.ToFloat64(DailyTestCount.WithLabelValues("nextcloud", "aggregates", PhaseUpload)); v != 3 {
+		t.Errorf("expected 3 upload tests today, got %v", v)
+	}
+	if v := testutil.ToFloat64(MonthlyTestCount.WithLabelValues("nextcloud", "aggregates", PhaseUpload)); v != 4 {
+		t.Errorf("expected 4 upload tests this month, got %v", v)
+	}
+}
@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/xXRoxXeRXx/cloud-performance-monitor/internal/utils"
+)
+
+// httpPhaseRecorder exports the phase timings of traced provider requests of one instance
+type httpPhaseRecorder struct {
+	serviceType  string
+	instanceName string
+}
+
+// ObserveHTTPPhases implements utils.HTTPPhaseObserver
+func (r *httpPhaseRecorder) ObserveHTTPPhases(operation string, t utils.HTTPTimings) {
+	phases := []struct {
+		name     string
+		duration time.Duration
+		// optional phases are skipped when they did not happen (reused connection, plain HTTP)
+		optional bool
+	}{
+		{"dns", t.DNS, true},
+		{"connect", t.Connect, true},
+		{"tls", t.TLS, true},
+		{"ttfb", t.TTFB, false},
+		{"transfer", t.Transfer, false},
+	}
+	for _, p := range phases {
+		if p.optional && (t.ReusedConn || p.duration == 0) {
+			continue
+		}
+		HTTPPhaseDuration.WithLabelValues(r.serviceType, r.instanceName, operation, p.name).Observe(p.duration.Seconds())
+		HTTPPhaseLastSeconds.WithLabelValues(r.serviceType, r.instanceName, operation, p.name).Set(p.duration.Seconds())
+	}
+}
+
+// ObserveConnection implements utils.ConnectionObserver
+func (r *httpPhaseRecorder) ObserveConnection(operation string, reused bool) {
+	HTTPConnections.WithLabelValues(r.serviceType, r.instanceName, operation, strconv.FormatBool(reused)).Inc()
+}
+
+// httpRetryRecorder exports and logs the retries of provider requests of one instance
+type httpRetryRecorder struct {
+	serviceType  string
+	instanceName string
+}
+
+// ObserveRetry implements utils.RetryObserver
+func (r *httpRetryRecorder) ObserveRetry(operation string, attempt int, reason string, delay time.Duration) {
+	HTTPRetries.WithLabelValues(r.serviceType, r.instanceName, operation, reason).Inc()
+	if operation == utils.HTTPOperationChunk {
+		ChunkRetries.WithLabelValues(r.serviceType, r.instanceName).Inc()
+	}
+	LogServiceOperation(WARN, r.serviceType, r.instanceName, operation, "retry",
+		fmt.Sprintf("Request failed (%s), retry %d in %v", reason, attempt, delay.Round(time.Millisecond)))
+}
+
+// ObserveRateLimit implements utils.RetryObserver
+func (r *httpRetryRecorder) ObserveRateLimit(operation string, wait time.Duration) {
+	RateLimitedSeconds.WithLabelValues(r.serviceType, r.instanceName).Add(wait.Seconds())
+	LogServiceOperation(WARN, r.serviceType, r.instanceName, operation, "rate_limited",
+		fmt.Sprintf("Rate limited by provider, waiting %v", wait.Round(time.Millisecond)))
+}
+
+// GetHTTPRetryConfig returns the retry policy of provider requests. HTTP_MAX_RETRIES
+// sets the number of retries per request (default 3, 0 disables retries) and
+// HTTP_MAX_RETRY_AFTER_SECONDS the longest wait a provider may ask for (default 60).
+func GetHTTPRetryConfig() *utils.RetryConfig {
+	config := utils.DefaultRetryConfig()
+	if value := os.Getenv("HTTP_MAX_RETRIES"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			config.MaxRetries = n
+		}
+	}
+	if value := os.Getenv("HTTP_MAX_RETRY_AFTER_SECONDS"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+			config.MaxRetryAfter = time.Duration(seconds) * time.Second
+		}
+	}
+	return config
+}
+
+// instrumentHTTPClient wraps the transport of a provider's HTTP client so that transient
+// failures are retried with the shared policy and its upload, download and chunk requests
+// report DNS, connect, TLS, TTFB and transfer times. Every attempt is traced separately.
+func instrumentHTTPClient(client *http.Client, cfg *Config) {
+	if client == nil {
+		return
+	}
+	traced := utils.NewTracingTransport(client.Transport, &httpPhaseRecorder{
+		serviceType:  cfg.ServiceType,
+		instanceName: cfg.InstanceName,
+	})
+	client.Transport = utils.NewRetryTransport(traced, GetHTTPRetryConfig(), &httpRetryRecorder{
+		serviceType:  cfg.ServiceType,
+		instanceName: cfg.InstanceName,
+	})
+}
@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/xXRoxXeRXx/cloud-performance-monitor/internal/utils"
+)
+
+func TestInstrumentedClientRetriesAndReports(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	cfg := runnerTestConfig("retry-metrics")
+	client := &http.Client{}
+	instrumentHTTPClient(client, cfg)
+
+	req, _ := http.NewRequest(http.MethodPut, server.URL, bytes.NewReader([]byte("chunk")))
+	resp, err := client.Do(utils.WithHTTPOperation(req, utils.HTTPOperationChunk))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated || calls != 2 {
+		t.Fatalf("expected success after one retry, got status %d after %d attempts", resp.StatusCode, calls)
+	}
+	if retries := testutil.ToFloat64(HTTPRetries.WithLabelValues(cfg.ServiceType, cfg.InstanceName, utils.HTTPOperationChunk, "http_429")); retries != 1 {
+		t.Errorf("expected 1 HTTP retry, got %v", retries)
+	}
+	if retries := testutil.ToFloat64(ChunkRetries.WithLabelValues(cfg.ServiceType, cfg.InstanceName)); retries != 1 {
+		t.Errorf("expected 1 chunk retry, got %v", retries)
+	}
+	if waited := testutil.ToFloat64(RateLimitedSeconds.WithLabelValues(cfg.ServiceType, cfg.InstanceName)); waited <= 0 {
+		t.Errorf("expected the wait for the rate limit to be counted, got %v", waited)
+	}
+}
+
+func TestGetHTTPRetryConfig(t *testing.T) {
+	t.Setenv("HTTP_MAX_RETRIES", "0")
+	if config := GetHTTPRetryConfig(); config.MaxRetries != 0 {
+		t.Errorf("expected retries to be disabled, got %d", config.MaxRetries)
+	}
+	t.Setenv("HTTP_MAX_RETRIES", "invalid")
+	if config := GetHTTPRetryConfig(); config.MaxRetries != utils.DefaultRetryConfig().MaxRetries {
+		t.Errorf("expected default retries for an invalid value, got %d", config.MaxRetries)
+	}
+	t.Setenv("HTTP_MAX_RETRY_AFTER_SECONDS", "300")
+	if config := GetHTTPRetryConfig(); config.MaxRetryAfter != 5*time.Minute {
+		t.Errorf("expected a maximum Retry-After of 5m, got %v", config.MaxRetryAfter)
+	}
+}
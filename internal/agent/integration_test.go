@@ -3,6 +3,7 @@
 package agent
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -168,7 +169,7 @@ func TestNextcloudIntegration(t *testing.T) {
 	client := nextcloud.NewClient(cfg.URL, cfg.Username, cfg.Password)
 
 	// Run the test
-	RunTest(cfg, client)
+	_ = RunTest(context.Background(), cfg, client)
 
 	// Verify metrics were recorded
 	// Note: In a real test, we'd check Prometheus metrics, but for now we just ensure no panics
@@ -9,6 +9,8 @@ import (
 	"runtime"
 	"strings"
 	"time"
+
+	"github.com/xXRoxXeRXx/cloud-performance-monitor/internal/utils"
 )
 
 // LogLevel represents the logging level
@@ -439,3 +441,73 @@ func GetLogFormat() bool {
 	format := os.Getenv("LOG_FORMAT")
 	return strings.ToLower(format) == "json"
 }
+
+// clientLoggerAdapter adapts StructuredLogger to ClientLogger interface
+type clientLoggerAdapter struct {
+	logger *StructuredLogger
+}
+
+func (a *clientLoggerAdapter) LogOperation(level utils.LogLevel, service, instance, operation, phase, message string, fields map[string]interface{}) {
+	// Convert utils.LogLevel to agent.LogLevel
+	var agentLevel LogLevel
+	switch level {
+	case utils.DEBUG:
+		agentLevel = DEBUG
+	case utils.INFO:
+		agentLevel = INFO
+	case utils.WARN:
+		agentLevel = WARN
+	case utils.ERROR:
+		agentLevel = ERROR
+	default:
+		agentLevel = INFO
+	}
+	
+	// Convert common fields to LogOptions
+	var opts []LogOption
+	for key, value := range fields {
+		switch key {
+		case "error":
+			if err, ok := value.(error); ok {
+				opts = append(opts, WithError(err))
+			}
+		case "duration":
+			if d, ok := value.(time.Duration); ok {
+				opts = append(opts, WithDuration(d))
+			}
+		case "status":
+			if s, ok := value.(string); ok {
+				opts = append(opts, WithStatus(s))
+			}
+		case "status_code":
+			if code, ok := value.(int); ok {
+				opts = append(opts, WithStatusCode(code))
+			}
+		case "size", "file_size", "chunk_size":
+			if size, ok := value.(int64); ok {
+				opts = append(opts, WithSize(size))
+			} else if size, ok := value.(int); ok {
+				opts = append(opts, WithSize(int64(size)))
+			}
+		case "speed_mbps":
+			if speed, ok := value.(float64); ok {
+				opts = append(opts, WithSpeed(speed))
+			}
+		case "chunk_num":
+			if chunkNum, ok := value.(int); ok {
+				if totalChunks, exists := fields["total_chunks"]; exists {
+					if total, ok := totalChunks.(int); ok {
+						opts = append(opts, WithChunk(chunkNum, total))
+					}
+				}
+			}
+		case "transfer_id", "session_id":
+			if id, ok := value.(string); ok {
+				opts = append(opts, WithTransferID(id))
+			}
+		// Other fields are ignored for now since LogEntry has specific structure
+		}
+	}
+	
+	a.logger.LogOperation(agentLevel, service, instance, operation, phase, message, opts...)
+}
@@ -0,0 +1,82 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"time"
+)
+
+// metadataProbeSize is the size of the file whose metadata is queried by the stat phase
+const metadataProbeSize = 1024
+
+// metadataRun carries the state of one metadata latency test
+type metadataRun struct {
+	*testRun
+
+	probePath string
+}
+
+// metadata measures how long browsing the test directory takes: a directory listing
+// (PROPFIND Depth: 1, /dir, list_folder) and a stat of a single file (PROPFIND Depth: 0,
+// /meta, get_metadata). A small probe file is uploaded first so that both operations
+// have something to find; it is removed again afterwards.
+func (r *testRun) metadata() {
+	m := &metadataRun{
+		testRun:   r,
+		probePath: fmt.Sprintf("%s/metadata_%d.tmp", TestDirectory, time.Now().UnixNano()),
+	}
+
+	if r.runPhase(PhaseMetadataSetup, m.uploadProbe) {
+		r.runPhase(PhaseList, m.list)
+		r.runPhase(PhaseStat, m.stat)
+	}
+
+	// The probe is not part of the measurement, a failed delete is only logged
+	ctx, cancel := r.cleanupContext()
+	defer cancel()
+	if err := r.provider.DeleteFile(ctx, m.probePath); err != nil {
+		LogServiceOperation(WARN, r.cfg.ServiceType, r.cfg.InstanceName, TestTypeMetadata, "warning",
+			"Could not delete metadata probe file",
+			WithError(err))
+	}
+}
+
+// uploadProbe uploads the file that is listed and queried by the following phases
+func (m *metadataRun) uploadProbe(ctx context.Context) (int64, error) {
+	reader := io.LimitReader(&randomReader{}, metadataProbeSize)
+	if err := m.provider.UploadFile(ctx, m.probePath, reader, metadataProbeSize, m.chunkSize); err != nil {
+		return 0, withErrorCode(ExtractErrorCode(err, PhaseUpload), fmt.Errorf("probe upload failed: %w", err))
+	}
+	return metadataProbeSize, nil
+}
+
+// list lists the test directory and checks that the probe file is part of the listing
+func (m *metadataRun) list(ctx context.Context) (int64, error) {
+	names, err := m.provider.ListDirectory(ctx, TestDirectory)
+	if err != nil {
+		return 0, err
+	}
+	m.phaseFiles = len(names)
+	for _, name := range names {
+		if name == path.Base(m.probePath) {
+			return 0, nil
+		}
+	}
+	return 0, withErrorCode("listing_incomplete",
+		fmt.Errorf("directory listing incomplete: %s missing from %d entries", path.Base(m.probePath), len(names)))
+}
+
+// stat queries the metadata of the probe file and checks the reported size
+func (m *metadataRun) stat(ctx context.Context) (int64, error) {
+	size, err := m.provider.StatFile(ctx, m.probePath)
+	if err != nil {
+		return 0, err
+	}
+	if size != metadataProbeSize {
+		return 0, withErrorCode("size_mismatch",
+			fmt.Errorf("stat reported size %d for %s, expected %d", size, path.Base(m.probePath), metadataProbeSize))
+	}
+	return 0, nil
+}
@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func metadataTestConfig(instance string) *Config {
+	cfg := runnerTestConfig(instance)
+	cfg.TestTypes = []string{TestTypeMetadata}
+	return cfg
+}
+
+func TestMetadataSuccess(t *testing.T) {
+	cfg := metadataTestConfig("metadata-success")
+	provider := newMemoryProvider()
+
+	result := RunProviderTest(context.Background(), cfg, provider)
+	if !result.Success() {
+		t.Fatalf("expected success, got %v", result.Err)
+	}
+	if result.Phase(PhaseUpload) != nil {
+		t.Error("transfer test must not run when only metadata is enabled")
+	}
+	for _, phase := range []string{PhaseMetadataSetup, PhaseList, PhaseStat} {
+		if result.Phase(phase) == nil {
+			t.Fatalf("expected phase %s in result", phase)
+		}
+	}
+	if len(provider.files) != 0 {
+		t.Errorf("expected probe file to be deleted, %d files left", len(provider.files))
+	}
+	for _, metricType := range []string{PhaseList, PhaseStat} {
+		if v := testutil.ToFloat64(TestSuccess.WithLabelValues(cfg.ServiceType, cfg.InstanceName, metricType, "none")); v != 1 {
+			t.Errorf("expected %s success metric 1, got %v", metricType, v)
+		}
+		if v := testutil.ToFloat64(TestDuration.WithLabelValues(cfg.ServiceType, cfg.InstanceName, metricType)); v <= 0 {
+			t.Errorf("expected positive %s duration, got %v", metricType, v)
+		}
+	}
+}
+
+func TestMetadataStatFailure(t *testing.T) {
+	cfg := metadataTestConfig("metadata-stat")
+	provider := newMemoryProvider()
+	provider.statErr = errors.New("get_metadata failed with status 409: path/not_found")
+
+	result := RunProviderTest(context.Background(), cfg, provider)
+	if result.Success() {
+		t.Fatal("expected failure")
+	}
+	if result.ErrorCode != "http_409_conflict" {
+		t.Errorf("expected http_409_conflict, got %s", result.ErrorCode)
+	}
+	if list := result.Phase(PhaseList); list == nil || !list.Success() {
+		t.Error("expected list phase to succeed")
+	}
+	if len(provider.files) != 0 {
+		t.Errorf("expected probe file to be deleted, %d files left", len(provider.files))
+	}
+	if v := testutil.ToFloat64(TestErrors.WithLabelValues(cfg.ServiceType, cfg.InstanceName, PhaseStat, "http_409_conflict")); v != 1 {
+		t.Errorf("expected one stat error, got %v", v)
+	}
+}
+
+func TestMetadataProbeUploadFailure(t *testing.T) {
+	cfg := metadataTestConfig("metadata-probe")
+	provider := newMemoryProvider()
+	provider.uploadErr = errors.New("connection refused")
+
+	result := RunProviderTest(context.Background(), cfg, provider)
+	if result.ErrorCode != "network_connection_error" {
+		t.Errorf("expected network_connection_error, got %s", result.ErrorCode)
+	}
+	if result.Phase(PhaseList) != nil || result.Phase(PhaseStat) != nil {
+		t.Error("list and stat must not run without probe file")
+	}
+	if v := testutil.ToFloat64(TestErrors.WithLabelValues(cfg.ServiceType, cfg.InstanceName, PhaseStat, "network_connection_error")); v != 1 {
+		t.Errorf("expected probe failure to be reported as stat error, got %v", v)
+	}
+}
@@ -59,7 +59,70 @@ var (
 			Name: "cloud_chunk_retries_total",
 			Help: "Total number of chunk upload retries.",
 		},
-		[]string{"service", "instance", "chunk_number"},
+		[]string{"service", "instance"},
+	)
+
+	// HTTPRetries counts retried provider requests by operation and reason (http_<status>, timeout, network).
+	HTTPRetries = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cloud_http_retries_total",
+			Help: "Total number of retried provider HTTP requests.",
+		},
+		[]string{"service", "instance", "operation", "reason"},
+	)
+
+	// HTTPConnections counts provider requests by whether they reused a kept-alive connection.
+	HTTPConnections = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cloud_http_connections_total",
+			Help: "Total number of provider requests by connection reuse (reused=\"true\" for kept-alive connections).",
+		},
+		[]string{"service", "instance", "operation", "reused"},
+	)
+
+	// ConnectionTestDuration records the phase durations of the cold- and warm-connection runs.
+	ConnectionTestDuration = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloud_test_connection_duration_seconds",
+			Help: "Duration of the last test phase on new (cold) or kept-alive (warm) connections.",
+		},
+		[]string{"service", "instance", "type", "connection"},
+	)
+
+	// RateLimitedSeconds sums the time spent waiting because a provider rate-limited requests.
+	RateLimitedSeconds = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cloud_rate_limited_seconds_total",
+			Help: "Total time in seconds spent waiting for provider rate limits.",
+		},
+		[]string{"service", "instance"},
+	)
+
+	// OAuthTokenExpiry records when the current OAuth access token of an instance expires.
+	OAuthTokenExpiry = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloud_oauth_token_expiry_timestamp_seconds",
+			Help: "Unix time at which the current OAuth access token expires.",
+		},
+		[]string{"service", "instance"},
+	)
+
+	// OAuthTokenRefreshes counts successful OAuth access token refreshes.
+	OAuthTokenRefreshes = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cloud_oauth_token_refreshes_total",
+			Help: "Total number of successful OAuth access token refreshes.",
+		},
+		[]string{"service", "instance"},
+	)
+
+	// OAuthTokenRefreshFailures counts OAuth access token refreshes that failed after all retries.
+	OAuthTokenRefreshFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cloud_oauth_token_refresh_failures_total",
+			Help: "Total number of failed OAuth access token refreshes.",
+		},
+		[]string{"service", "instance"},
 	)
 
 	// ChunkUploadDuration measures the duration of individual chunk uploads.
@@ -80,6 +143,60 @@ var (
 		[]string{"service", "instance"},
 	)
 
+	// NetworkStepDuration holds the duration of each network diagnostics step.
+	NetworkStepDuration = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloud_network_step_duration_seconds",
+			Help: "Duration of the last network diagnostics step (dns, connect, tls, http) per target in seconds.",
+		},
+		[]string{"service", "instance", "target", "step"},
+	)
+
+	// NetworkResolvedIP exposes the addresses a target resolved to in the last diagnostics run.
+	NetworkResolvedIP = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloud_network_resolved_ip",
+			Help: "Addresses returned by DNS for a target in the last diagnostics run (always 1).",
+		},
+		[]string{"service", "instance", "target", "ip", "family"},
+	)
+
+	// NetworkIPFamily indicates whether the diagnostics connected via IPv4 or IPv6.
+	NetworkIPFamily = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloud_network_ip_family",
+			Help: "IP version of the address the last diagnostics run connected to (4 or 6).",
+		},
+		[]string{"service", "instance", "target"},
+	)
+
+	// NetworkDiagnosticErrors counts failed network diagnostics runs by failing step.
+	NetworkDiagnosticErrors = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cloud_network_diagnostic_errors_total",
+			Help: "Total number of failed network diagnostics runs by target and failing step.",
+		},
+		[]string{"service", "instance", "target", "step"},
+	)
+
+	// TLSCertExpiry exposes the notAfter date of the certificate presented by a target.
+	TLSCertExpiry = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloud_tls_cert_expiry_timestamp_seconds",
+			Help: "Unix timestamp at which the TLS certificate presented by the target expires.",
+		},
+		[]string{"service", "instance", "target"},
+	)
+
+	// TLSCertInfo exposes certificate issuer and negotiated TLS parameters of a target.
+	TLSCertInfo = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloud_tls_cert_info",
+			Help: "Certificate subject and issuer, negotiated TLS version and cipher suite of the target (always 1).",
+		},
+		[]string{"service", "instance", "target", "subject", "issuer", "tls_version", "cipher_suite"},
+	)
+
 	// ConnectionTimeouts counts the total number of connection timeouts.
 	ConnectionTimeouts = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -98,6 +215,15 @@ var (
 		[]string{"service", "instance"},
 	)
 
+	// TestsSkipped counts scheduled tests that did not run, e.g. because the circuit breaker is open.
+	TestsSkipped = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cloud_tests_skipped_total",
+			Help: "Total number of skipped tests by reason.",
+		},
+		[]string{"service", "instance", "reason"},
+	)
+
 	// TestDurationHistogram provides histogram data for test durations.
 	TestDurationHistogram = promauto.NewHistogramVec(
 		prometheus.HistogramOpts{
@@ -117,6 +243,124 @@ var (
 		[]string{"service", "instance"},
 	)
 
+	// TestIntegrityOK indicates if the downloaded data matched the uploaded data.
+	TestIntegrityOK = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloud_test_integrity_ok",
+			Help: "Indicates if the SHA-256 checksum of the downloaded file matched the uploaded data (1=ok, 0=corrupted).",
+		},
+		[]string{"service", "instance"},
+	)
+
+	// NextTestRunTimestamp exposes when the next test of an instance is scheduled.
+	NextTestRunTimestamp = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloud_test_next_run_timestamp_seconds",
+			Help: "Unix timestamp of the next scheduled test run of the instance.",
+		},
+		[]string{"service", "instance"},
+	)
+
+	// RunningTests counts the tests currently executing per service.
+	RunningTests = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloud_tests_running",
+			Help: "Number of tests currently running per service.",
+		},
+		[]string{"service"},
+	)
+
+	// TestQueueWaitSeconds measures how long the last test waited for a free execution slot.
+	TestQueueWaitSeconds = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloud_test_queue_wait_seconds",
+			Help: "Time the last run of the test waited for a free execution slot in seconds.",
+		},
+		[]string{"service", "test_id"},
+	)
+
+	// InstanceLabel exposes the free-form labels configured for an instance.
+	InstanceLabel = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloud_instance_label",
+			Help: "Labels configured for an instance in the config file (always 1).",
+		},
+		[]string{"service", "instance", "label", "value"},
+	)
+
+	// SmallFilesPerSecond is the throughput of the last many-small-files test per operation.
+	SmallFilesPerSecond = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloud_small_files_per_second",
+			Help: "Files processed per second in the last small files test by operation.",
+		},
+		[]string{"service", "instance", "operation"},
+	)
+
+	// SmallFileLatency holds per-request latency percentiles of the last many-small-files test.
+	SmallFileLatency = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloud_small_files_latency_seconds",
+			Help: "Per-file latency percentiles of the last small files test by operation in seconds.",
+		},
+		[]string{"service", "instance", "operation", "quantile"},
+	)
+
+	// SmallFileOperationDuration records the latency of every small file request.
+	SmallFileOperationDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "cloud_small_files_operation_duration_seconds",
+			Help:    "Latency of single requests in the small files test in seconds.",
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 12), // 10ms, 20ms, 40ms, ..., 20s
+		},
+		[]string{"service", "instance", "operation"},
+	)
+
+	// HTTPPhaseDuration breaks single provider HTTP requests into their network and server phases.
+	HTTPPhaseDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "cloud_http_phase_duration_seconds",
+			Help:    "Duration of the phases (dns, connect, tls, ttfb, transfer) of upload, download and chunk requests in seconds.",
+			Buckets: prometheus.ExponentialBuckets(0.001, 4, 10), // 1ms, 4ms, 16ms, ..., 262s
+		},
+		[]string{"service", "instance", "operation", "phase"},
+	)
+
+	// HTTPPhaseLastSeconds holds the phase durations of the last traced request per operation.
+	HTTPPhaseLastSeconds = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cloud_http_phase_seconds",
+			Help: "Phase durations of the last upload, download or chunk request in seconds.",
+		},
+		[]string{"service", "instance", "operation", "phase"},
+	)
+
+	// ConfigReloads counts configuration reloads by result (success, failure).
+	ConfigReloads = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cloud_config_reloads_total",
+			Help: "Total number of configuration reloads by result.",
+		},
+		[]string{"result"},
+	)
+
+	// ConfigLastReloadSuccess is the time of the last successful configuration (re)load.
+	ConfigLastReloadSuccess = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cloud_config_last_reload_success_timestamp_seconds",
+			Help: "Unix timestamp of the last successful configuration load.",
+		},
+	)
+
+	// OnDemandRuns counts tests triggered through the API by final status (completed, failed, cancelled).
+	OnDemandRuns = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cloud_on_demand_runs_total",
+			Help: "Total number of on-demand test runs by final status.",
+		},
+		[]string{"service", "instance", "status"},
+	)
+
 	// HiDrive Legacy specific metrics - REMOVED: Now using generic cloud metrics above
 	// hidriveLegacyTestDuration and hidriveLegacyTestSpeed have been removed
 	// All services now use the generic TestDuration and TestSpeedMbytesPerSec metrics
@@ -188,3 +432,60 @@ var (
 		[]string{"service", "instance"},
 	)
 )
+
+// instanceMetrics are the metrics with service and instance labels
+var instanceMetrics = []interface {
+	DeletePartialMatch(labels prometheus.Labels) int
+}{
+	TestDuration,
+	TestSuccess,
+	TestSpeedMbytesPerSec,
+	TestErrors,
+	ChunksUploaded,
+	ChunkRetries,
+	HTTPRetries,
+	HTTPConnections,
+	ConnectionTestDuration,
+	RateLimitedSeconds,
+	OAuthTokenExpiry,
+	OAuthTokenRefreshes,
+	OAuthTokenRefreshFailures,
+	ChunkUploadDuration,
+	NetworkLatency,
+	NetworkStepDuration,
+	NetworkResolvedIP,
+	NetworkIPFamily,
+	NetworkDiagnosticErrors,
+	TLSCertExpiry,
+	TLSCertInfo,
+	ConnectionTimeouts,
+	CircuitBreakerState,
+	TestsSkipped,
+	TestDurationHistogram,
+	ChunkSize,
+	TestIntegrityOK,
+	NextTestRunTimestamp,
+	InstanceLabel,
+	SmallFilesPerSecond,
+	SmallFileLatency,
+	SmallFileOperationDuration,
+	HTTPPhaseDuration,
+	HTTPPhaseLastSeconds,
+	OnDemandRuns,
+	DailyAverageUploadSpeed,
+	DailyAverageDownloadSpeed,
+	MonthlyAverageUploadSpeed,
+	MonthlyAverageDownloadSpeed,
+	DailyTestCount,
+	MonthlyTestCount,
+	DailySuccessRate,
+	MonthlySuccessRate,
+}
+
+// DeleteInstanceMetrics removes all series of an instance
+func DeleteInstanceMetrics(cfg *Config) {
+	labels := prometheus.Labels{"service": cfg.ServiceType, "instance": cfg.InstanceName}
+	for _, metric := range instanceMetrics {
+		metric.DeletePartialMatch(labels)
+	}
+}
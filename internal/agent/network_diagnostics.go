@@ -0,0 +1,281 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Network diagnostics defaults
+const (
+	DiagnosticsInterval = 30 * time.Second
+	DiagnosticsTimeout  = 10 * time.Second
+)
+
+// Diagnostic steps, used as the "step" label of cloud_network_diagnostic_errors_total
+const (
+	DiagnosticStepDNS     = "dns"
+	DiagnosticStepConnect = "connect"
+	DiagnosticStepTLS     = "tls"
+	DiagnosticStepHTTP    = "http"
+)
+
+// NetworkDiagnostics is the result of one diagnostics run against a target URL.
+// Durations of steps that did not run are zero.
+type NetworkDiagnostics struct {
+	Target      string   // host[:port] of the probed URL, used as the "target" label
+	ResolvedIPs []net.IP // all addresses returned by DNS
+	RemoteIP    net.IP   // address the TCP connection was established to
+
+	DNS           time.Duration
+	Connect       time.Duration
+	TLSHandshake  time.Duration
+	HTTPRoundTrip time.Duration
+	HTTPStatus    int
+	TLS           *TLSInfo // negotiated TLS parameters and server certificate, nil for plain HTTP
+
+	FailedStep string // step that failed, empty on success
+	Err        error
+}
+
+// IPFamily returns 4 or 6 for the connected address, 0 if no connection was made
+func (d *NetworkDiagnostics) IPFamily() int {
+	switch {
+	case d.RemoteIP == nil:
+		return 0
+	case d.RemoteIP.To4() != nil:
+		return 4
+	default:
+		return 6
+	}
+}
+
+// Resolver looks up the addresses of a host; *net.Resolver implements it
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// NetworkDiagnoser measures DNS resolution, TCP connect, TLS handshake and an HTTP HEAD
+// round-trip to a URL as separate steps over a single connection
+type NetworkDiagnoser struct {
+	Resolver  Resolver // optional; net.DefaultResolver if nil
+	TLSConfig *tls.Config // optional; ServerName is filled in per target
+	Timeout   time.Duration
+}
+
+// Diagnose runs all steps against targetURL and stops at the first failing step
+func (n *NetworkDiagnoser) Diagnose(ctx context.Context, targetURL string) *NetworkDiagnostics {
+	u, err := url.Parse(targetURL)
+	if err != nil || u.Hostname() == "" {
+		return &NetworkDiagnostics{Target: targetURL, FailedStep: DiagnosticStepDNS, Err: fmt.Errorf("invalid URL %q", targetURL)}
+	}
+
+	timeout := n.Timeout
+	if timeout <= 0 {
+		timeout = DiagnosticsTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Default to port 443 for HTTPS, 80 for HTTP
+	port := u.Port()
+	if port == "" {
+		if u.Scheme == "https" {
+			port = "443"
+		} else {
+			port = "80"
+		}
+	}
+	d := &NetworkDiagnostics{Target: u.Host}
+	fail := func(step string, err error) *NetworkDiagnostics {
+		d.FailedStep, d.Err = step, err
+		return d
+	}
+
+	var resolver Resolver = net.DefaultResolver
+	if n.Resolver != nil {
+		resolver = n.Resolver
+	}
+	start := time.Now()
+	addrs, err := resolver.LookupIPAddr(ctx, u.Hostname())
+	d.DNS = time.Since(start)
+	if err == nil && len(addrs) == 0 {
+		err = &net.DNSError{Err: "no addresses for host", Name: u.Hostname(), IsNotFound: true}
+	}
+	if err != nil {
+		return fail(DiagnosticStepDNS, fmt.Errorf("DNS lookup of %s failed: %w", u.Hostname(), err))
+	}
+	for _, addr := range addrs {
+		d.ResolvedIPs = append(d.ResolvedIPs, addr.IP)
+	}
+
+	// Dial the resolved addresses in order so that DNS time is not counted twice
+	var conn net.Conn
+	var dialer net.Dialer
+	for _, ip := range d.ResolvedIPs {
+		start = time.Now()
+		conn, err = dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip.String(), port))
+		d.Connect = time.Since(start)
+		if err == nil {
+			d.RemoteIP = ip
+			break
+		}
+	}
+	if conn == nil {
+		return fail(DiagnosticStepConnect, fmt.Errorf("TCP connect to %s failed: %w", u.Host, err))
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if u.Scheme == "https" {
+		tlsConfig := &tls.Config{}
+		if n.TLSConfig != nil {
+			tlsConfig = n.TLSConfig.Clone()
+		}
+		tlsConfig.ServerName = u.Hostname()
+		// The certificate is verified by hand so that it is captured even when it is
+		// expired or untrusted, which are the cases the certificate monitoring is for
+		verify := !tlsConfig.InsecureSkipVerify
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+			d.TLS = newTLSInfo(state)
+			if !verify {
+				return nil
+			}
+			return verifyPeerCertificates(state, tlsConfig.RootCAs)
+		}
+		tlsConn := tls.Client(conn, tlsConfig)
+		start = time.Now()
+		err = tlsConn.HandshakeContext(ctx)
+		d.TLSHandshake = time.Since(start)
+		if err != nil {
+			return fail(DiagnosticStepTLS, fmt.Errorf("TLS handshake with %s failed: %w", u.Host, err))
+		}
+		conn = tlsConn
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, u.String(), nil)
+	if err != nil {
+		return fail(DiagnosticStepHTTP, err)
+	}
+	req.Header.Set("Connection", "close")
+	start = time.Now()
+	if err := req.Write(conn); err != nil {
+		return fail(DiagnosticStepHTTP, fmt.Errorf("HEAD %s failed: %w", u.Host, err))
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	d.HTTPRoundTrip = time.Since(start)
+	if err != nil {
+		return fail(DiagnosticStepHTTP, fmt.Errorf("HEAD %s failed: %w", u.Host, err))
+	}
+	resp.Body.Close()
+	// Any status proves the server answers; authentication is not part of the probe
+	d.HTTPStatus = resp.StatusCode
+	return d
+}
+
+// diagnosticTargets returns the URLs probed for an instance: the hosts the provider
+// client actually talks to, or the instance URL
+func diagnosticTargets(cfg *Config) []string {
+	if def, ok := GetProvider(cfg.ServiceType); ok && def.DiagnosticURLs != nil {
+		return def.DiagnosticURLs(cfg)
+	}
+	if cfg.URL == "" {
+		return nil
+	}
+	return []string{cfg.URL}
+}
+
+// exportNetworkDiagnostics updates the network metrics of an instance with a diagnostics result
+func exportNetworkDiagnostics(serviceType, instanceName string, d *NetworkDiagnostics) {
+	labels := prometheus.Labels{"service": serviceType, "instance": instanceName, "target": d.Target}
+	NetworkResolvedIP.DeletePartialMatch(labels)
+	for _, ip := range d.ResolvedIPs {
+		family := "ipv6"
+		if ip.To4() != nil {
+			family = "ipv4"
+		}
+		NetworkResolvedIP.WithLabelValues(serviceType, instanceName, d.Target, ip.String(), family).Set(1)
+	}
+
+	if d.FailedStep != "" {
+		NetworkDiagnosticErrors.WithLabelValues(serviceType, instanceName, d.Target, d.FailedStep).Inc()
+		if d.FailedStep == DiagnosticStepConnect {
+			ConnectionTimeouts.WithLabelValues(serviceType, instanceName).Inc()
+		}
+		LogServiceOperation(WARN, serviceType, instanceName, "network", "diagnostics_failed",
+			fmt.Sprintf("Network diagnostics for %s failed at %s step", d.Target, d.FailedStep),
+			WithError(d.Err))
+	}
+
+	steps := []struct {
+		step     string
+		duration time.Duration
+	}{
+		{DiagnosticStepDNS, d.DNS},
+		{DiagnosticStepConnect, d.Connect},
+		{DiagnosticStepTLS, d.TLSHandshake},
+		{DiagnosticStepHTTP, d.HTTPRoundTrip},
+	}
+	for _, s := range steps {
+		if s.step == d.FailedStep {
+			break
+		}
+		if s.duration > 0 {
+			NetworkStepDuration.WithLabelValues(serviceType, instanceName, d.Target, s.step).Set(s.duration.Seconds())
+		}
+	}
+	if d.TLS != nil {
+		exportTLSInfo(serviceType, instanceName, d.Target, d.TLS)
+	}
+	if d.RemoteIP != nil {
+		NetworkIPFamily.WithLabelValues(serviceType, instanceName, d.Target).Set(float64(d.IPFamily()))
+		if d.FailedStep != DiagnosticStepConnect {
+			// Kept for existing dashboards: TCP connect time in milliseconds
+			NetworkLatency.WithLabelValues(serviceType, instanceName).Set(float64(d.Connect.Milliseconds()))
+		}
+	}
+}
+
+// UpdateNetworkDiagnostics periodically runs the network diagnostics for all targets of
+// an instance and checks their certificates until ctx is cancelled
+func UpdateNetworkDiagnostics(ctx context.Context, cfg *Config) {
+	diagnoser := &NetworkDiagnoser{}
+	warningDays := GetCertExpiryWarningDays()
+	expiring := false
+	run := func() {
+		var results []*NetworkDiagnostics
+		for _, target := range diagnosticTargets(cfg) {
+			d := diagnoser.Diagnose(ctx, target)
+			if ctx.Err() != nil {
+				// The instance was stopped, the result is meaningless
+				return
+			}
+			exportNetworkDiagnostics(cfg.ServiceType, cfg.InstanceName, d)
+			results = append(results, d)
+		}
+		expiring = checkCertificateExpiry(cfg, results, warningDays, time.Now(), expiring)
+	}
+
+	ticker := time.NewTicker(DiagnosticsInterval)
+	defer ticker.Stop()
+
+	run()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			run()
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestNetworkDiagnoserHTTPS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	diagnoser := &NetworkDiagnoser{
+		TLSConfig: server.Client().Transport.(*http.Transport).TLSClientConfig,
+	}
+	d := diagnoser.Diagnose(context.Background(), server.URL)
+	if d.Err != nil {
+		t.Fatalf("expected success, failed at %s: %v", d.FailedStep, d.Err)
+	}
+	if d.HTTPStatus != http.StatusUnauthorized {
+		t.Errorf("expected HEAD status 401, got %d", d.HTTPStatus)
+	}
+	if d.Connect <= 0 || d.TLSHandshake <= 0 || d.HTTPRoundTrip <= 0 {
+		t.Errorf("expected connect, TLS and HTTP durations, got %+v", d)
+	}
+	if d.IPFamily() != 4 || len(d.ResolvedIPs) != 1 {
+		t.Errorf("expected one IPv4 address, got %v (family %d)", d.ResolvedIPs, d.IPFamily())
+	}
+
+	exportNetworkDiagnostics("nextcloud", "diag-https", d)
+	if v := testutil.ToFloat64(NetworkIPFamily.WithLabelValues("nextcloud", "diag-https", d.Target)); v != 4 {
+		t.Errorf("expected ip family metric 4, got %v", v)
+	}
+	if v := testutil.ToFloat64(NetworkResolvedIP.WithLabelValues("nextcloud", "diag-https", d.Target, "127.0.0.1", "ipv4")); v != 1 {
+		t.Errorf("expected resolved ip metric 1, got %v", v)
+	}
+}
+
+func TestNetworkDiagnoserTLSFailure(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// The test certificate is not trusted by the default configuration
+	d := (&NetworkDiagnoser{TLSConfig: &tls.Config{}}).Diagnose(context.Background(), server.URL)
+	if d.FailedStep != DiagnosticStepTLS {
+		t.Fatalf("expected failure at tls step, got %q (%v)", d.FailedStep, d.Err)
+	}
+	if d.Connect <= 0 {
+		t.Error("expected connect duration before the TLS failure")
+	}
+
+	exportNetworkDiagnostics("nextcloud", "diag-tls", d)
+	if v := testutil.ToFloat64(NetworkDiagnosticErrors.WithLabelValues("nextcloud", "diag-tls", d.Target, DiagnosticStepTLS)); v != 1 {
+		t.Errorf("expected one tls diagnostics error, got %v", v)
+	}
+}
+
+func TestNetworkDiagnoserConnectFailure(t *testing.T) {
+	// Reserve a port and close it again so that nothing listens there
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	d := (&NetworkDiagnoser{}).Diagnose(context.Background(), "http://"+addr)
+	if d.FailedStep != DiagnosticStepConnect {
+		t.Fatalf("expected failure at connect step, got %q (%v)", d.FailedStep, d.Err)
+	}
+	if !strings.Contains(d.Err.Error(), "TCP connect") {
+		t.Errorf("unexpected error: %v", d.Err)
+	}
+}
+
+// stubResolver answers every lookup with a fixed result
+type stubResolver struct {
+	addrs []net.IPAddr
+	err   error
+}
+
+func (r stubResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	return r.addrs, r.err
+}
+
+func TestNetworkDiagnoserNoAddresses(t *testing.T) {
+	d := (&NetworkDiagnoser{Resolver: stubResolver{}}).Diagnose(context.Background(), "https://cloud.example.com")
+	if d.FailedStep != DiagnosticStepDNS {
+		t.Fatalf("expected failure at dns step, got %q (%v)", d.FailedStep, d.Err)
+	}
+	if d.Err == nil || !strings.Contains(d.Err.Error(), "no addresses for host") {
+		t.Errorf("unexpected error: %v", d.Err)
+	}
+}
+
+func TestDiagnosticTargets(t *testing.T) {
+	if got := diagnosticTargets(&Config{ServiceType: "nextcloud", URL: "https://cloud.example.com"}); len(got) != 1 || got[0] != "https://cloud.example.com" {
+		t.Errorf("expected instance URL as target, got %v", got)
+	}
+	if got := diagnosticTargets(&Config{ServiceType: "dropbox", URL: "https://api.dropboxapi.com"}); len(got) != 2 {
+		t.Errorf("expected API and content host for dropbox, got %v", got)
+	}
+}
@@ -0,0 +1,345 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Statuses of an on-demand run
+const (
+	RunStatusQueued    = "queued"
+	RunStatusRunning   = "running"
+	RunStatusCompleted = "completed" // the test ran and succeeded
+	RunStatusFailed    = "failed"    // the test ran and failed
+	RunStatusCancelled = "cancelled" // the run was aborted, e.g. by a shutdown
+)
+
+const (
+	// MaxFinishedRuns is how many finished on-demand runs are kept for polling
+	MaxFinishedRuns = 100
+	// MaxRunWait bounds the long-polling wait of GET /api/v1/runs/{id}
+	MaxRunWait = 5 * time.Minute
+)
+
+// RunView is the API representation of an on-demand run
+type RunView struct {
+	ID       string      `json:"id"`
+	Service  string      `json:"service"`
+	Instance string      `json:"instance"`
+	Status   string      `json:"status"`
+	Created  time.Time   `json:"created"`
+	Started  *time.Time  `json:"started,omitempty"`
+	Finished *time.Time  `json:"finished,omitempty"`
+	Result   *ResultView `json:"result,omitempty"`
+}
+
+// Done reports whether the run has reached a final status
+func (v *RunView) Done() bool {
+	return v.Status == RunStatusCompleted || v.Status == RunStatusFailed || v.Status == RunStatusCancelled
+}
+
+// onDemandRun is the mutable state of a run; guarded by OnDemandRunner.mu
+type onDemandRun struct {
+	view RunView
+	key  string
+	// changed is closed and replaced on every status change
+	changed chan struct{}
+}
+
+// OnDemandRunner triggers tests outside the schedule. The tests go through the
+// TestManager, so they respect the execution limits and never overlap with a
+// scheduled test of the same instance.
+type OnDemandRunner struct {
+	ctx     context.Context
+	tests   *TestManager
+	configs func() []*Config
+	run     func(ctx context.Context, cfg *Config) *RunResult
+	token   string
+
+	mu       sync.Mutex
+	runs     map[string]*onDemandRun
+	active   map[string]*onDemandRun // queued or running run per instance key
+	finished []string                // IDs of finished runs, oldest first
+}
+
+// APITokenFromEnv returns the bearer token of the on-demand API from AGENT_API_TOKEN
+func APITokenFromEnv() string {
+	return strings.TrimSpace(os.Getenv("AGENT_API_TOKEN"))
+}
+
+// NewOnDemandRunner creates a runner that tests the instances returned by configs with
+// run. Runs are cancelled when ctx is cancelled. An empty token disables the API.
+func NewOnDemandRunner(ctx context.Context, tests *TestManager, configs func() []*Config, run func(ctx context.Context, cfg *Config) *RunResult, token string) *OnDemandRunner {
+	return &OnDemandRunner{
+		ctx:     ctx,
+		tests:   tests,
+		configs: configs,
+		run:     run,
+		token:   token,
+		runs:    make(map[string]*onDemandRun),
+		active:  make(map[string]*onDemandRun),
+	}
+}
+
+// Trigger starts a run for every instance named instance, or for all instances if
+// instance is empty. An instance that already has a queued or running on-demand run
+// is not triggered again; its pending run is returned instead.
+func (o *OnDemandRunner) Trigger(instance string) ([]RunView, error) {
+	var targets []*Config
+	for _, cfg := range o.configs() {
+		if instance == "" || cfg.InstanceName == instance {
+			targets = append(targets, cfg)
+		}
+	}
+	if len(targets) == 0 {
+		if instance == "" {
+			return nil, fmt.Errorf("no instances configured")
+		}
+		return nil, fmt.Errorf("unknown instance %q", instance)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	views := make([]RunView, 0, len(targets))
+	for _, cfg := range targets {
+		key := InstanceKey(cfg)
+		if pending, ok := o.active[key]; ok {
+			views = append(views, pending.view)
+			continue
+		}
+		run := &onDemandRun{
+			view: RunView{
+				ID:       newRunID(),
+				Service:  cfg.ServiceType,
+				Instance: cfg.InstanceName,
+				Status:   RunStatusQueued,
+				Created:  time.Now(),
+			},
+			key:     key,
+			changed: make(chan struct{}),
+		}
+		o.runs[run.view.ID] = run
+		o.active[key] = run
+		views = append(views, run.view)
+
+		LogServiceOperation(INFO, cfg.ServiceType, cfg.InstanceName, "on_demand", "queued",
+			fmt.Sprintf("On-demand test %s queued", run.view.ID))
+		go o.execute(run, cfg)
+	}
+	return views, nil
+}
+
+// execute waits for a test slot and runs the test
+func (o *OnDemandRunner) execute(run *onDemandRun, cfg *Config) {
+	var result *RunResult
+	err := o.tests.Execute(o.ctx, run.key, cfg.ServiceType, func(ctx context.Context) error {
+		o.update(run, func(v *RunView) {
+			now := time.Now()
+			v.Status = RunStatusRunning
+			v.Started = &now
+		})
+		result = o.run(ctx, cfg)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return result.Err
+	})
+
+	o.update(run, func(v *RunView) {
+		now := time.Now()
+		v.Finished = &now
+		switch {
+		case result == nil || (err != nil && o.ctx.Err() != nil):
+			v.Status = RunStatusCancelled
+		case result.Err != nil:
+			v.Status = RunStatusFailed
+		default:
+			v.Status = RunStatusCompleted
+		}
+		if result != nil {
+			rec := newHistoryRecord(result)
+			view := newResultView(&rec)
+			v.Result = &view
+		}
+	})
+
+	o.mu.Lock()
+	status := run.view.Status
+	delete(o.active, run.key)
+	o.finished = append(o.finished, run.view.ID)
+	for len(o.finished) > MaxFinishedRuns {
+		delete(o.runs, o.finished[0])
+		o.finished = o.finished[1:]
+	}
+	o.mu.Unlock()
+
+	OnDemandRuns.WithLabelValues(cfg.ServiceType, cfg.InstanceName, status).Inc()
+	LogServiceOperation(INFO, cfg.ServiceType, cfg.InstanceName, "on_demand", status,
+		fmt.Sprintf("On-demand test %s %s", run.view.ID, status))
+}
+
+// update changes the state of a run and wakes up its waiters
+func (o *OnDemandRunner) update(run *onDemandRun, change func(v *RunView)) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	change(&run.view)
+	close(run.changed)
+	run.changed = make(chan struct{})
+}
+
+// Get returns the current state of a run and a channel that is closed on its next change
+func (o *OnDemandRunner) Get(id string) (RunView, <-chan struct{}, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	run, ok := o.runs[id]
+	if !ok {
+		return RunView{}, nil, false
+	}
+	return run.view, run.changed, true
+}
+
+// Wait returns the state of a run once it has finished or when ctx is done
+func (o *OnDemandRunner) Wait(ctx context.Context, id string) (RunView, bool) {
+	for {
+		view, changed, ok := o.Get(id)
+		if !ok || view.Done() {
+			return view, ok
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return view, true
+		}
+	}
+}
+
+// newRunID returns a random run ID
+func newRunID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// authorized checks the bearer token of a request
+func (o *OnDemandRunner) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(o.token)) == 1
+}
+
+// Handler returns the handler of the on-demand API:
+//
+//	POST /api/v1/runs?instance=<name>       trigger a test (all instances without instance)
+//	GET  /api/v1/runs/{id}[?wait=30s]       state of a run, optionally waiting until it finished
+//	GET  /api/v1/runs/{id}/events           server-sent events on every status change
+//
+// All requests need "Authorization: Bearer <AGENT_API_TOKEN>".
+func (o *OnDemandRunner) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/runs", o.handleTrigger)
+	mux.HandleFunc("GET /api/v1/runs/{id}", o.handleGet)
+	mux.HandleFunc("GET /api/v1/runs/{id}/events", o.handleEvents)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if o.token == "" {
+			http.Error(w, "On-demand API disabled, set AGENT_API_TOKEN", http.StatusForbidden)
+			return
+		}
+		if !o.authorized(r) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="monitor-agent"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func (o *OnDemandRunner) handleTrigger(w http.ResponseWriter, r *http.Request) {
+	runs, err := o.Trigger(r.URL.Query().Get("instance"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, struct {
+		Runs []RunView `json:"runs"`
+	}{runs})
+}
+
+func (o *OnDemandRunner) handleGet(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	view, _, ok := o.Get(id)
+	if value := r.URL.Query().Get("wait"); ok && value != "" {
+		wait, err := time.ParseDuration(value)
+		if err != nil || wait < 0 {
+			http.Error(w, fmt.Sprintf("invalid wait %q", value), http.StatusBadRequest)
+			return
+		}
+		if wait > MaxRunWait {
+			wait = MaxRunWait
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), wait)
+		defer cancel()
+		view, ok = o.Wait(ctx, id)
+	}
+	if !ok {
+		http.Error(w, "Run not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, view)
+}
+
+func (o *OnDemandRunner) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	id := r.PathValue("id")
+	if _, _, ok := o.Get(id); !ok {
+		http.Error(w, "Run not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	for {
+		view, changed, ok := o.Get(id)
+		if !ok {
+			return
+		}
+		data, err := json.Marshal(view)
+		if err != nil {
+			return
+		}
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", view.Status, data)
+		flusher.Flush()
+		if view.Done() {
+			return
+		}
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeJSON writes v as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
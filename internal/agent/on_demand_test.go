@@ -0,0 +1,174 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const onDemandTestToken = "secret-token"
+
+// newOnDemandTestRunner returns a runner over two instances whose tests block until
+// release is closed; the test of "od-broken" fails
+func newOnDemandTestRunner(t *testing.T, release chan struct{}) *OnDemandRunner {
+	t.Helper()
+	configs := []*Config{
+		{ServiceType: "nextcloud", InstanceName: "od-ok"},
+		{ServiceType: "hidrive", InstanceName: "od-broken"},
+	}
+	run := func(ctx context.Context, cfg *Config) *RunResult {
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		result := &RunResult{ServiceType: cfg.ServiceType, InstanceName: cfg.InstanceName, StartTime: time.Now(), ErrorCode: "none"}
+		if cfg.InstanceName == "od-broken" {
+			result.Err = errors.New("upload failed with status 503")
+			result.ErrorCode = "http_503_service_unavailable"
+		}
+		return result
+	}
+	tm := NewTestManager(NewShutdownManager(time.Second))
+	tm.SetLimits(ExecutionLimits{Mode: ExecutionConcurrent, MaxConcurrent: 4, MaxPerService: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return NewOnDemandRunner(ctx, tm, func() []*Config { return configs }, run, onDemandTestToken)
+}
+
+func onDemandRequest(method, target string) *http.Request {
+	req := httptest.NewRequest(method, target, nil)
+	req.Header.Set("Authorization", "Bearer "+onDemandTestToken)
+	return req
+}
+
+func TestOnDemandTriggerAndWait(t *testing.T) {
+	release := make(chan struct{})
+	runner := newOnDemandTestRunner(t, release)
+	handler := runner.Handler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, onDemandRequest(http.MethodPost, "/api/v1/runs"))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d: %s", w.Code, w.Body)
+	}
+	var triggered struct {
+		Runs []RunView `json:"runs"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&triggered); err != nil {
+		t.Fatalf("decoding response failed: %v", err)
+	}
+	if len(triggered.Runs) != 2 {
+		t.Fatalf("expected a run per instance, got %d", len(triggered.Runs))
+	}
+
+	// A second trigger while the first is pending returns the same run
+	again, err := runner.Trigger("od-ok")
+	if err != nil {
+		t.Fatalf("Trigger failed: %v", err)
+	}
+	if again[0].ID != triggered.Runs[0].ID {
+		t.Errorf("expected pending run %s to be reused, got %s", triggered.Runs[0].ID, again[0].ID)
+	}
+
+	close(release)
+
+	want := map[string]string{"od-ok": RunStatusCompleted, "od-broken": RunStatusFailed}
+	for _, run := range triggered.Runs {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, onDemandRequest(http.MethodGet, "/api/v1/runs/"+run.ID+"?wait=5s"))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		var view RunView
+		if err := json.NewDecoder(w.Body).Decode(&view); err != nil {
+			t.Fatalf("decoding run failed: %v", err)
+		}
+		if view.Status != want[view.Instance] {
+			t.Errorf("%s: expected status %s, got %s", view.Instance, want[view.Instance], view.Status)
+		}
+		if view.Result == nil || view.Started == nil || view.Finished == nil {
+			t.Errorf("%s: expected result and timestamps, got %+v", view.Instance, view)
+		}
+	}
+}
+
+func TestOnDemandEventsStream(t *testing.T) {
+	release := make(chan struct{})
+	runner := newOnDemandTestRunner(t, release)
+	server := httptest.NewServer(runner.Handler())
+	defer server.Close()
+
+	runs, err := runner.Trigger("od-ok")
+	if err != nil {
+		t.Fatalf("Trigger failed: %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/v1/runs/"+runs[0].ID+"/events", nil)
+	req.Header.Set("Authorization", "Bearer "+onDemandTestToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("stream request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected event stream, got %q", ct)
+	}
+	close(release)
+
+	var events []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if event, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+			events = append(events, event)
+		}
+	}
+	if len(events) == 0 || events[len(events)-1] != RunStatusCompleted {
+		t.Errorf("expected stream to end with %s, got %v", RunStatusCompleted, events)
+	}
+}
+
+func TestOnDemandAuthentication(t *testing.T) {
+	runner := newOnDemandTestRunner(t, make(chan struct{}))
+	handler := runner.Handler()
+
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"missing token", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer wrong", http.StatusUnauthorized},
+		{"unknown run", "Bearer " + onDemandTestToken, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/runs/unknown", nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.want, w.Code)
+		}
+	}
+
+	// Without a token the API is disabled
+	disabled := NewOnDemandRunner(context.Background(), runner.tests, runner.configs, runner.run, "")
+	w := httptest.NewRecorder()
+	disabled.Handler().ServeHTTP(w, onDemandRequest(http.MethodPost, "/api/v1/runs"))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 without configured token, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, onDemandRequest(http.MethodPost, "/api/v1/runs?instance=missing"))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for unknown instance, got %d", w.Code)
+	}
+}
@@ -0,0 +1,139 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// StorageProvider is the common interface implemented by all cloud storage clients.
+// Every method aborts its requests when ctx is cancelled or its deadline expires.
+type StorageProvider interface {
+	// EnsureDirectory makes sure the given directory exists on the remote storage
+	EnsureDirectory(ctx context.Context, dirPath string) error
+	// UploadFile uploads size bytes from reader to filePath, using chunkSize where the provider supports chunking
+	UploadFile(ctx context.Context, filePath string, reader io.Reader, size int64, chunkSize int64) error
+	// DownloadFile returns a stream of the remote file contents; the caller must close it.
+	// Reading the stream fails once ctx is done.
+	DownloadFile(ctx context.Context, filePath string) (io.ReadCloser, error)
+	// DeleteFile removes the remote file
+	DeleteFile(ctx context.Context, filePath string) error
+	// ListDirectory returns the names of the entries of a remote directory
+	ListDirectory(ctx context.Context, dirPath string) ([]string, error)
+	// StatFile returns the size of a remote file as reported by its metadata
+	StatFile(ctx context.Context, filePath string) (int64, error)
+}
+
+// ProviderFactory creates a ready-to-use StorageProvider for an instance configuration.
+// Providers that need to authenticate (e.g. OAuth2 refresh) do so inside the factory.
+type ProviderFactory func(ctx context.Context, cfg *Config) (StorageProvider, error)
+
+// ProviderDefinition describes everything the agent needs to know about a service type:
+// how its instances are read from the environment, how they are validated and how a
+// client is created. Tests are run by the generic runner for every provider.
+type ProviderDefinition struct {
+	ServiceType string
+	// Env describes the environment variable layout (e.g. NC_INSTANCE_<n>_URL)
+	Env ServiceConfig
+	// LoadEnv loads the instance with the given index from the environment
+	LoadEnv func(svc ServiceConfig, index, fileSize, interval, chunkSize int) (*Config, bool, error)
+	// Validate checks the service-specific fields of a configuration
+	Validate func(cfg *Config) error
+	// New creates a client for the instance
+	New ProviderFactory
+	// CredentialURL returns the URL used by TestCredentials, nil if not supported
+	CredentialURL func(cfg *Config) string
+	// DiagnosticURLs returns the URLs probed by the network diagnostics, nil means cfg.URL
+	DiagnosticURLs func(cfg *Config) []string
+}
+
+var (
+	providerRegistryMu sync.RWMutex
+	providerRegistry   = make(map[string]*ProviderDefinition)
+	providerOrder      []string
+)
+
+// RegisterProvider adds a provider definition to the registry.
+// Registering the same service type twice replaces the previous definition.
+func RegisterProvider(def *ProviderDefinition) {
+	providerRegistryMu.Lock()
+	defer providerRegistryMu.Unlock()
+
+	if def.Env.ServiceType == "" {
+		def.Env.ServiceType = def.ServiceType
+	}
+	if _, exists := providerRegistry[def.ServiceType]; !exists {
+		providerOrder = append(providerOrder, def.ServiceType)
+	}
+	providerRegistry[def.ServiceType] = def
+}
+
+// GetProvider returns the provider definition for a service type
+func GetProvider(serviceType string) (*ProviderDefinition, bool) {
+	providerRegistryMu.RLock()
+	defer providerRegistryMu.RUnlock()
+
+	def, ok := providerRegistry[serviceType]
+	return def, ok
+}
+
+// RegisteredProviders returns all provider definitions in registration order
+func RegisteredProviders() []*ProviderDefinition {
+	providerRegistryMu.RLock()
+	defer providerRegistryMu.RUnlock()
+
+	defs := make([]*ProviderDefinition, 0, len(providerOrder))
+	for _, serviceType := range providerOrder {
+		defs = append(defs, providerRegistry[serviceType])
+	}
+	return defs
+}
+
+// ProviderTypes returns the registered service types in registration order
+func ProviderTypes() []string {
+	providerRegistryMu.RLock()
+	defer providerRegistryMu.RUnlock()
+
+	return append([]string(nil), providerOrder...)
+}
+
+// NewProvider creates a StorageProvider for the given configuration using the registry
+func NewProvider(ctx context.Context, cfg *Config) (StorageProvider, error) {
+	def, ok := GetProvider(cfg.ServiceType)
+	if !ok {
+		return nil, fmt.Errorf("unknown service type: %s", cfg.ServiceType)
+	}
+	return def.New(ctx, cfg)
+}
+
+// RunInstanceTest runs the generic performance test with the long-lived client of an
+// instance, creating the client on first use. With ColdWarmRuns the test runs on new and
+// on kept-alive connections.
+func RunInstanceTest(ctx context.Context, cfg *Config) *RunResult {
+	provider, err := providerFor(ctx, cfg)
+	if err != nil {
+		LogServiceOperation(ERROR, cfg.ServiceType, cfg.InstanceName, "connection", "error",
+			"Could not create provider client",
+			WithError(err))
+		connErrCode := ExtractErrorCode(err, "connection")
+		TestErrors.WithLabelValues(cfg.ServiceType, cfg.InstanceName, "connection", connErrCode).Inc()
+		// Set failed test metrics to trigger alerts
+		TestSuccess.WithLabelValues(cfg.ServiceType, cfg.InstanceName, "upload", connErrCode).Set(0)
+		TestSuccess.WithLabelValues(cfg.ServiceType, cfg.InstanceName, "download", connErrCode).Set(0)
+		return &RunResult{
+			ServiceType:  cfg.ServiceType,
+			InstanceName: cfg.InstanceName,
+			StartTime:    time.Now(),
+			Phases:       []PhaseResult{{Phase: "connection", ErrorCode: connErrCode, Err: err}},
+			ErrorCode:    connErrCode,
+			Err:          fmt.Errorf("connection failed: %w", err),
+		}
+	}
+
+	if cfg.ColdWarmRuns {
+		return runColdWarm(ctx, cfg, provider)
+	}
+	return RunProviderTest(ctx, cfg, provider)
+}
@@ -0,0 +1,60 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestBuiltinProvidersRegistered(t *testing.T) {
+	expected := []string{"nextcloud", "hidrive", "hidrive_legacy", "dropbox", "magentacloud", "webdav", "s3", "gdrive"}
+	types := ProviderTypes()
+	if len(types) < len(expected) {
+		t.Fatalf("expected at least %d providers, got %v", len(expected), types)
+	}
+	for i, serviceType := range expected {
+		if types[i] != serviceType {
+			t.Errorf("provider %d: expected %s, got %s", i, serviceType, types[i])
+		}
+		def, ok := GetProvider(serviceType)
+		if !ok {
+			t.Fatalf("provider %s not registered", serviceType)
+		}
+		if def.Env.ServiceType != serviceType {
+			t.Errorf("provider %s: env service type is %q", serviceType, def.Env.ServiceType)
+		}
+		if def.LoadEnv == nil || def.Validate == nil || def.New == nil {
+			t.Errorf("provider %s is missing required functions", serviceType)
+		}
+	}
+}
+
+func TestNewProviderUnknownServiceType(t *testing.T) {
+	_, err := NewProvider(context.Background(), &Config{ServiceType: "unknown"})
+	if err == nil || !strings.Contains(err.Error(), "unknown service type") {
+		t.Errorf("expected unknown service type error, got %v", err)
+	}
+}
+
+func TestNewProviderWebDAV(t *testing.T) {
+	cfg := &Config{
+		ServiceType: "nextcloud",
+		URL:         "https://cloud.example.com",
+		Username:    "user",
+		Password:    "pass",
+	}
+	provider, err := NewProvider(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if provider == nil {
+		t.Fatal("expected provider, got nil")
+	}
+}
+
+func TestTestCredentialsUnsupportedServiceType(t *testing.T) {
+	err := TestCredentials(context.Background(), &Config{ServiceType: "dropbox"})
+	if err == nil || !strings.Contains(err.Error(), "unsupported service type") {
+		t.Errorf("expected unsupported service type error, got %v", err)
+	}
+}
@@ -0,0 +1,249 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	dropbox "github.com/xXRoxXeRXx/cloud-performance-monitor/internal/dropbox"
+	"github.com/xXRoxXeRXx/cloud-performance-monitor/internal/gdrive"
+	hidrive "github.com/xXRoxXeRXx/cloud-performance-monitor/internal/hidrive"
+	hidrive_legacy "github.com/xXRoxXeRXx/cloud-performance-monitor/internal/hidrive_legacy"
+	magentacloud "github.com/xXRoxXeRXx/cloud-performance-monitor/internal/magentacloud"
+	"github.com/xXRoxXeRXx/cloud-performance-monitor/internal/nextcloud"
+	"github.com/xXRoxXeRXx/cloud-performance-monitor/internal/s3"
+	"github.com/xXRoxXeRXx/cloud-performance-monitor/internal/webdav"
+)
+
+// Compile-time checks that all clients implement StorageProvider
+var (
+	_ StorageProvider = (*nextcloud.Client)(nil)
+	_ StorageProvider = (*hidrive.Client)(nil)
+	_ StorageProvider = (*hidrive_legacy.Client)(nil)
+	_ StorageProvider = (*dropbox.Client)(nil)
+	_ StorageProvider = (*magentacloud.Client)(nil)
+	_ StorageProvider = (*webdav.Client)(nil)
+	_ StorageProvider = (*s3.Client)(nil)
+	_ StorageProvider = (*gdrive.Client)(nil)
+)
+
+// Built-in providers are registered in the order in which their environment
+// variables are scanned by LoadConfigs.
+func init() {
+	RegisterProvider(&ProviderDefinition{
+		ServiceType: "nextcloud",
+		Env: ServiceConfig{
+			Prefix:  "NC_INSTANCE",
+			URLKey:  "URL",
+			UserKey: "USER",
+			PassKey: "PASS",
+		},
+		LoadEnv:  loadWebDAVConfig,
+		Validate: validateWebDAVConfig,
+		New: func(ctx context.Context, cfg *Config) (StorageProvider, error) {
+			client := nextcloud.NewClient(cfg.URL, cfg.Username, cfg.Password)
+			instrumentHTTPClient(client.HTTPClient, cfg)
+			return client, nil
+		},
+		CredentialURL: webDAVCredentialURL,
+	})
+
+	RegisterProvider(&ProviderDefinition{
+		ServiceType: "hidrive",
+		Env: ServiceConfig{
+			Prefix:  "HIDRIVE_INSTANCE",
+			URLKey:  "URL",
+			UserKey: "USER",
+			PassKey: "PASS",
+		},
+		LoadEnv:  loadWebDAVConfig,
+		Validate: validateWebDAVConfig,
+		New: func(ctx context.Context, cfg *Config) (StorageProvider, error) {
+			client := hidrive.NewClient(cfg.URL, cfg.Username, cfg.Password)
+			instrumentHTTPClient(client.HTTPClient, cfg)
+			return client, nil
+		},
+		CredentialURL: webDAVCredentialURL,
+	})
+
+	RegisterProvider(&ProviderDefinition{
+		ServiceType: "hidrive_legacy",
+		Env: ServiceConfig{
+			Prefix:          "HIDRIVE_LEGACY_INSTANCE",
+			RefreshTokenKey: "REFRESH_TOKEN",
+			ClientIDKey:     "CLIENT_ID",
+			ClientSecretKey: "CLIENT_SECRET",
+			NameKey:         "NAME",
+			DefaultURL:      "https://api.hidrive.strato.com",
+		},
+		LoadEnv:  loadHiDriveLegacyConfig,
+		Validate: validateHiDriveLegacyConfig,
+		New:      newHiDriveLegacyProvider,
+		DiagnosticURLs: func(cfg *Config) []string {
+			return []string{hidrive_legacy.HiDriveAPIBaseURL}
+		},
+	})
+
+	RegisterProvider(&ProviderDefinition{
+		ServiceType: "dropbox",
+		Env: ServiceConfig{
+			Prefix:          "DROPBOX_INSTANCE",
+			RefreshTokenKey: "REFRESH_TOKEN",
+			AppKeyKey:       "APP_KEY",
+			AppSecretKey:    "APP_SECRET",
+			NameKey:         "NAME",
+			DefaultURL:      "https://api.dropboxapi.com",
+		},
+		LoadEnv:  loadDropboxConfig,
+		Validate: validateDropboxConfig,
+		New:      newDropboxProvider,
+		DiagnosticURLs: func(cfg *Config) []string {
+			// API calls and file transfers use different hosts
+			return []string{dropbox.DropboxAPIURL, dropbox.DropboxContentURL}
+		},
+	})
+
+	RegisterProvider(&ProviderDefinition{
+		ServiceType: "magentacloud",
+		Env: ServiceConfig{
+			Prefix:  "MAGENTACLOUD_INSTANCE",
+			URLKey:  "URL",
+			UserKey: "USER",
+			PassKey: "PASS",
+			ANIDKey: "ANID",
+		},
+		LoadEnv:  loadMagentaCloudConfig,
+		Validate: validateMagentaCloudConfig,
+		New: func(ctx context.Context, cfg *Config) (StorageProvider, error) {
+			client := magentacloud.NewClient(cfg.URL, cfg.Username, cfg.Password, cfg.ANID)
+			instrumentHTTPClient(client.HTTPClient, cfg)
+			return client, nil
+		},
+		CredentialURL: func(cfg *Config) string {
+			return cfg.URL + "/remote.php/dav/files/" + cfg.ANID + "/"
+		},
+	})
+
+	RegisterProvider(&ProviderDefinition{
+		ServiceType: "webdav",
+		Env: ServiceConfig{
+			Prefix:      "WEBDAV_INSTANCE",
+			URLKey:      "URL",
+			UserKey:     "USER",
+			PassKey:     "PASS",
+			RootKey:     "ROOT",
+			ChunkingKey: "CHUNKING",
+		},
+		LoadEnv:  loadGenericWebDAVConfig,
+		Validate: validateGenericWebDAVConfig,
+		New: func(ctx context.Context, cfg *Config) (StorageProvider, error) {
+			client := webdav.NewClient(cfg.URL, cfg.WebDAVRoot, cfg.Username, cfg.Password, cfg.WebDAVChunking)
+			instrumentHTTPClient(client.HTTPClient, cfg)
+			return client, nil
+		},
+		CredentialURL: func(cfg *Config) string {
+			return strings.TrimSuffix(cfg.URL+path.Join("/", cfg.WebDAVRoot), "/") + "/"
+		},
+	})
+
+	RegisterProvider(&ProviderDefinition{
+		ServiceType: "s3",
+		Env: ServiceConfig{
+			Prefix:       "S3_INSTANCE",
+			URLKey:       "ENDPOINT",
+			BucketKey:    "BUCKET",
+			RegionKey:    "REGION",
+			AccessKeyKey: "ACCESS_KEY",
+			SecretKeyKey: "SECRET_KEY",
+			NameKey:      "NAME",
+		},
+		LoadEnv:  loadS3Config,
+		Validate: validateS3Config,
+		New: func(ctx context.Context, cfg *Config) (StorageProvider, error) {
+			client := s3.NewClient(cfg.URL, cfg.Bucket, cfg.Region, cfg.AccessKey, cfg.SecretKey)
+			instrumentHTTPClient(client.HTTPClient, cfg)
+			return client, nil
+		},
+	})
+
+	RegisterProvider(&ProviderDefinition{
+		ServiceType: "gdrive",
+		Env: ServiceConfig{
+			Prefix:          "GDRIVE_INSTANCE",
+			URLKey:          "URL",
+			RefreshTokenKey: "REFRESH_TOKEN",
+			ClientIDKey:     "CLIENT_ID",
+			ClientSecretKey: "CLIENT_SECRET",
+			NameKey:         "NAME",
+			DefaultURL:      gdrive.DefaultBaseURL,
+		},
+		LoadEnv:  loadGoogleDriveConfig,
+		Validate: validateGoogleDriveConfig,
+		New:      newGoogleDriveProvider,
+	})
+}
+
+// newHiDriveLegacyProvider creates an OAuth2 HiDrive Legacy client and verifies the connection.
+// The access token is taken from the token store while it is valid.
+func newHiDriveLegacyProvider(ctx context.Context, cfg *Config) (StorageProvider, error) {
+	LogServiceOperation(DEBUG, "hidrive_legacy", cfg.InstanceName, "auth", "oauth2_init",
+		"Using OAuth2 client with refresh token")
+	client := hidrive_legacy.NewClient("")
+	client.RefreshToken = cfg.RefreshToken
+	client.ClientID = cfg.ClientID
+	client.ClientSecret = cfg.ClientSecret
+	instrumentHTTPClient(client.HTTPClient, cfg)
+
+	recorder := &tokenRecorder{cfg: cfg, store: currentTokenStore()}
+	client.TokenObserver = recorder
+	if err := initOAuthToken(ctx, cfg, client, recorder); err != nil {
+		return nil, fmt.Errorf("failed to generate initial access token: %w", err)
+	}
+	if err := client.TestConnection(ctx); err != nil {
+		return nil, fmt.Errorf("connection test failed: %w", err)
+	}
+	return client, nil
+}
+
+// newDropboxProvider creates an OAuth2 Dropbox client. The access token is taken from the
+// token store while it is valid, otherwise a new one is generated.
+func newDropboxProvider(ctx context.Context, cfg *Config) (StorageProvider, error) {
+	LogServiceOperation(DEBUG, "dropbox", cfg.InstanceName, "auth", "oauth2_init",
+		"Using OAuth2 client with refresh token")
+	loggerAdapter := &clientLoggerAdapter{logger: Logger}
+	client := dropbox.NewClientWithOAuth2("", cfg.RefreshToken, cfg.AppKey, cfg.AppSecret, loggerAdapter)
+	instrumentHTTPClient(client.HTTPClient, cfg)
+
+	recorder := &tokenRecorder{cfg: cfg, store: currentTokenStore()}
+	client.TokenObserver = recorder
+	if err := initOAuthToken(ctx, cfg, client, recorder); err != nil {
+		return nil, fmt.Errorf("failed to generate initial access token: %w", err)
+	}
+	LogServiceOperation(INFO, "dropbox", cfg.InstanceName, "auth", "success",
+		"OAuth2 access token ready")
+	return client, nil
+}
+
+// newGoogleDriveProvider creates an OAuth2 Google Drive client. The access token is taken
+// from the token store while it is valid, otherwise a new one is generated.
+func newGoogleDriveProvider(ctx context.Context, cfg *Config) (StorageProvider, error) {
+	LogServiceOperation(DEBUG, "gdrive", cfg.InstanceName, "auth", "oauth2_init",
+		"Using OAuth2 client with refresh token")
+	client := gdrive.NewClient(cfg.URL, cfg.RefreshToken, cfg.ClientID, cfg.ClientSecret)
+	instrumentHTTPClient(client.HTTPClient, cfg)
+
+	recorder := &tokenRecorder{cfg: cfg, store: currentTokenStore()}
+	client.TokenObserver = recorder
+	if err := initOAuthToken(ctx, cfg, client, recorder); err != nil {
+		return nil, fmt.Errorf("failed to generate initial access token: %w", err)
+	}
+	LogServiceOperation(INFO, "gdrive", cfg.InstanceName, "auth", "success",
+		"OAuth2 access token ready")
+	return client, nil
+}
+
+// webDAVCredentialURL returns the user's WebDAV root for Nextcloud-style servers
+func webDAVCredentialURL(cfg *Config) string {
+	return cfg.URL + "/remote.php/dav/files/" + cfg.Username + "/"
+}
@@ -0,0 +1,280 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultConfigReloadInterval is the default poll interval of the config file watcher
+const DefaultConfigReloadInterval = 30 * time.Second
+
+// ConfigDiff describes how an instance set changes on reload
+type ConfigDiff struct {
+	Added   []*Config
+	Removed []*Config // previous configuration of removed instances
+	Changed []*Config // new configuration of changed instances
+}
+
+// Empty reports whether the reload changes nothing
+func (d ConfigDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// String returns a short summary for logging
+func (d ConfigDiff) String() string {
+	return fmt.Sprintf("%d added, %d removed, %d changed", len(d.Added), len(d.Removed), len(d.Changed))
+}
+
+// DiffConfigs compares the current and the next instance set by InstanceKey
+func DiffConfigs(current, next []*Config) ConfigDiff {
+	var diff ConfigDiff
+	old := make(map[string]*Config, len(current))
+	for _, cfg := range current {
+		old[InstanceKey(cfg)] = cfg
+	}
+
+	seen := make(map[string]bool, len(next))
+	for _, cfg := range next {
+		key := InstanceKey(cfg)
+		seen[key] = true
+		prev, ok := old[key]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, cfg)
+		case !reflect.DeepEqual(prev, cfg):
+			diff.Changed = append(diff.Changed, cfg)
+		}
+	}
+	for _, cfg := range current {
+		if !seen[InstanceKey(cfg)] {
+			diff.Removed = append(diff.Removed, cfg)
+		}
+	}
+	return diff
+}
+
+// managedInstance is an instance started by the InstanceManager
+type managedInstance struct {
+	cfg         *Config
+	stopLatency context.CancelFunc
+}
+
+// InstanceManager owns the running instances: their test schedules, network
+// diagnostics, health registration and label metrics. Apply switches to a new
+// instance set while leaving unchanged instances untouched. Test metrics are kept
+// for changed instances and deleted for removed ones.
+type InstanceManager struct {
+	ctx       context.Context
+	scheduler *Scheduler
+	health    *HealthChecker
+	latency   func(ctx context.Context, cfg *Config)
+
+	// applyMu serializes Apply; mu only guards instances, so that Configs does not wait
+	// for running tests while a reload stops their schedules
+	applyMu   sync.Mutex
+	mu        sync.Mutex
+	instances map[string]*managedInstance
+	wg        sync.WaitGroup
+}
+
+// NewInstanceManager creates an instance manager; everything it starts stops when ctx is cancelled
+func NewInstanceManager(ctx context.Context, scheduler *Scheduler, health *HealthChecker) *InstanceManager {
+	return &InstanceManager{
+		ctx:       ctx,
+		scheduler: scheduler,
+		health:    health,
+		latency:   UpdateNetworkDiagnostics,
+		instances: make(map[string]*managedInstance),
+	}
+}
+
+// Configs returns the configurations of all running instances
+func (m *InstanceManager) Configs() []*Config {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	configs := make([]*Config, 0, len(m.instances))
+	for _, inst := range m.instances {
+		configs = append(configs, inst.cfg)
+	}
+	sort.Slice(configs, func(i, j int) bool { return InstanceKey(configs[i]) < InstanceKey(configs[j]) })
+	return configs
+}
+
+// Apply starts, stops and restarts instances so that exactly the given configurations run.
+// The instance set is switched first; stopping a schedule waits for its running test, which
+// happens afterwards without holding the lock used by Configs.
+func (m *InstanceManager) Apply(configs []*Config) ConfigDiff {
+	m.applyMu.Lock()
+	defer m.applyMu.Unlock()
+
+	m.mu.Lock()
+	current := make([]*Config, 0, len(m.instances))
+	for _, inst := range m.instances {
+		current = append(current, inst.cfg)
+	}
+	diff := DiffConfigs(current, configs)
+
+	replaced := make(map[string]*Config, len(diff.Changed))
+	for _, cfg := range diff.Changed {
+		replaced[InstanceKey(cfg)] = m.instances[InstanceKey(cfg)].cfg
+	}
+	for _, cfg := range diff.Removed {
+		m.unregister(cfg)
+	}
+	started := make(map[string]context.Context, len(diff.Changed)+len(diff.Added))
+	for _, cfg := range append(append([]*Config(nil), diff.Changed...), diff.Added...) {
+		m.unregister(cfg)
+		started[InstanceKey(cfg)] = m.register(cfg)
+	}
+	m.mu.Unlock()
+
+	for _, cfg := range diff.Removed {
+		m.stop(cfg)
+		DeleteInstanceMetrics(cfg)
+		m.health.UnregisterService(cfg.InstanceName)
+		LogServiceOperation(INFO, cfg.ServiceType, cfg.InstanceName, "config", "reload", "Instance removed")
+	}
+	for _, cfg := range diff.Changed {
+		// The health state is kept, only schedule and monitoring pick up the new settings
+		m.stop(replaced[InstanceKey(cfg)])
+		m.start(started[InstanceKey(cfg)], cfg)
+		LogServiceOperation(INFO, cfg.ServiceType, cfg.InstanceName, "config", "reload", "Instance configuration changed")
+	}
+	for _, cfg := range diff.Added {
+		m.health.RegisterService(cfg.InstanceName)
+		m.start(started[InstanceKey(cfg)], cfg)
+		LogServiceOperation(INFO, cfg.ServiceType, cfg.InstanceName, "config", "reload", "Instance added")
+	}
+	return diff
+}
+
+// Reload loads the configuration from path (or the environment if path is empty)
+// and applies it. On error the running instances are kept.
+func (m *InstanceManager) Reload(path string) (ConfigDiff, error) {
+	configs, err := LoadConfigsFrom(path)
+	if err != nil {
+		ConfigReloads.WithLabelValues("failure").Inc()
+		return ConfigDiff{}, err
+	}
+	diff := m.Apply(configs)
+	ConfigReloads.WithLabelValues("success").Inc()
+	ConfigLastReloadSuccess.SetToCurrentTime()
+	return diff, nil
+}
+
+// Wait blocks until the network diagnostics of all instances have stopped
+func (m *InstanceManager) Wait() {
+	m.wg.Wait()
+}
+
+// register adds an instance to the running set and returns the context of its latency
+// monitoring; m.mu must be held
+func (m *InstanceManager) register(cfg *Config) context.Context {
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.instances[InstanceKey(cfg)] = &managedInstance{cfg: cfg, stopLatency: cancel}
+	return ctx
+}
+
+// unregister removes an instance from the running set and ends its latency monitoring;
+// m.mu must be held
+func (m *InstanceManager) unregister(cfg *Config) {
+	key := InstanceKey(cfg)
+	if inst, ok := m.instances[key]; ok {
+		inst.stopLatency()
+		delete(m.instances, key)
+	}
+}
+
+// start launches schedule and latency monitoring of a registered instance
+func (m *InstanceManager) start(ctx context.Context, cfg *Config) {
+	ExportInstanceLabels(cfg)
+	m.scheduler.Add(cfg)
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		m.latency(ctx, cfg)
+	}()
+}
+
+// stop ends schedule, circuit breaker and client of an unregistered instance. It waits for
+// a running test of the instance to finish.
+func (m *InstanceManager) stop(cfg *Config) {
+	m.scheduler.Remove(InstanceKey(cfg))
+	RemoveCircuitBreaker(cfg)
+	RemoveProviderClient(cfg)
+	DeleteInstanceLabels(cfg)
+}
+
+// GetConfigReloadInterval returns the config file poll interval from
+// CONFIG_RELOAD_INTERVAL_SECONDS; 0 disables polling (SIGHUP still reloads)
+func GetConfigReloadInterval() time.Duration {
+	value := os.Getenv("CONFIG_RELOAD_INTERVAL_SECONDS")
+	if value == "" {
+		return DefaultConfigReloadInterval
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return DefaultConfigReloadInterval
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// WatchConfig calls reload when the process receives SIGHUP or, if path is set and
+// pollInterval is positive, when the contents of the config file change.
+// It blocks until ctx is cancelled.
+func WatchConfig(ctx context.Context, path string, pollInterval time.Duration, reload func(reason string)) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var poll <-chan time.Time
+	if path != "" && pollInterval > 0 {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+	lastHash := fileHash(path)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			lastHash = fileHash(path)
+			reload("SIGHUP")
+		case <-poll:
+			hash := fileHash(path)
+			// An unreadable file (e.g. while being replaced) is retried on the next tick
+			if hash == nil || bytes.Equal(hash, lastHash) {
+				continue
+			}
+			lastHash = hash
+			reload("config file changed")
+		}
+	}
+}
+
+// fileHash returns the SHA-256 of a file's contents, nil if it cannot be read
+func fileHash(path string) []byte {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
@@ -0,0 +1,233 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestDiffConfigs(t *testing.T) {
+	a := &Config{ServiceType: "nextcloud", InstanceName: "a", TestIntervalSec: 60}
+	b := &Config{ServiceType: "nextcloud", InstanceName: "b", TestIntervalSec: 60}
+	bChanged := &Config{ServiceType: "nextcloud", InstanceName: "b", TestIntervalSec: 120}
+	aSame := &Config{ServiceType: "nextcloud", InstanceName: "a", TestIntervalSec: 60}
+	c := &Config{ServiceType: "dropbox", InstanceName: "a", TestIntervalSec: 60}
+
+	diff := DiffConfigs([]*Config{a, b}, []*Config{aSame, bChanged, c})
+	if len(diff.Added) != 1 || diff.Added[0] != c {
+		t.Errorf("expected dropbox/a to be added, got %v", diff.Added)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0] != bChanged {
+		t.Errorf("expected nextcloud/b to be changed, got %v", diff.Changed)
+	}
+	if len(diff.Removed) != 0 {
+		t.Errorf("expected nothing removed, got %v", diff.Removed)
+	}
+
+	diff = DiffConfigs([]*Config{a, b}, []*Config{aSame})
+	if len(diff.Removed) != 1 || diff.Removed[0] != b || len(diff.Added) != 0 || len(diff.Changed) != 0 {
+		t.Errorf("expected only nextcloud/b removed, got %s", diff)
+	}
+
+	if !DiffConfigs([]*Config{a}, []*Config{aSame}).Empty() {
+		t.Error("expected no difference for equal configurations")
+	}
+}
+
+func TestInstanceManagerApply(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	scheduler := NewScheduler(ctx, 0, func(ctx context.Context, cfg *Config) {})
+	health := NewHealthChecker("test")
+	m := NewInstanceManager(ctx, scheduler, health)
+
+	var mu sync.Mutex
+	latencyRunning := make(map[string]bool)
+	m.latency = func(ctx context.Context, cfg *Config) {
+		mu.Lock()
+		latencyRunning[cfg.InstanceName] = true
+		mu.Unlock()
+		<-ctx.Done()
+		mu.Lock()
+		latencyRunning[cfg.InstanceName] = false
+		mu.Unlock()
+	}
+	waitLatency := func(name string, want bool) bool {
+		deadline := time.Now().Add(time.Second)
+		for {
+			mu.Lock()
+			running := latencyRunning[name]
+			mu.Unlock()
+			if running == want || time.Now().After(deadline) {
+				return running == want
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	keep := &Config{ServiceType: "nextcloud", InstanceName: "reload-keep", TestIntervalSec: 3600}
+	drop := &Config{ServiceType: "hidrive", InstanceName: "reload-drop", TestIntervalSec: 3600,
+		Labels: map[string]string{"env": "prod"}}
+	m.Apply([]*Config{keep, drop})
+	health.UpdateServiceHealth("reload-keep", "healthy", time.Second, nil)
+
+	if !waitLatency("reload-drop", true) {
+		t.Fatal("expected latency monitoring to start for new instance")
+	}
+	if got := testutil.ToFloat64(InstanceLabel.WithLabelValues("hidrive", "reload-drop", "env", "prod")); got != 1 {
+		t.Errorf("expected instance label to be exported, got %v", got)
+	}
+	TestSuccess.WithLabelValues("hidrive", "reload-drop", "upload", "").Set(1)
+	TestSuccess.WithLabelValues("nextcloud", "reload-keep", "upload", "").Set(1)
+
+	added := &Config{ServiceType: "dropbox", InstanceName: "reload-added", TestIntervalSec: 3600}
+	diff := m.Apply([]*Config{keep, added})
+	if len(diff.Added) != 1 || len(diff.Removed) != 1 || len(diff.Changed) != 0 {
+		t.Fatalf("unexpected diff: %s", diff)
+	}
+
+	keys := scheduler.Keys()
+	sort.Strings(keys)
+	if len(keys) != 2 || keys[0] != "dropbox/reload-added" || keys[1] != "nextcloud/reload-keep" {
+		t.Errorf("unexpected scheduled instances: %v", keys)
+	}
+	if !waitLatency("reload-drop", false) {
+		t.Error("expected latency monitoring of removed instance to stop")
+	}
+
+	services := make(map[string]ServiceHealth)
+	for _, svc := range health.GetHealthStatus().Services {
+		services[svc.Name] = svc
+	}
+	if _, ok := services["reload-drop"]; ok {
+		t.Error("expected removed instance to be unregistered from health checks")
+	}
+	if services["reload-keep"].Status != "healthy" {
+		t.Errorf("expected health of unchanged instance to be kept, got %q", services["reload-keep"].Status)
+	}
+	if services["reload-added"].Status != "unknown" {
+		t.Errorf("expected new instance to be registered, got %+v", services["reload-added"])
+	}
+	if InstanceLabel.DeleteLabelValues("hidrive", "reload-drop", "env", "prod") {
+		t.Error("expected labels of removed instance to be deleted")
+	}
+	if TestSuccess.DeleteLabelValues("hidrive", "reload-drop", "upload", "") {
+		t.Error("expected test metrics of removed instance to be deleted")
+	}
+	if !TestSuccess.DeleteLabelValues("nextcloud", "reload-keep", "upload", "") {
+		t.Error("expected test metrics of unchanged instance to be kept")
+	}
+
+	cancel()
+	scheduler.Wait()
+	m.Wait()
+}
+
+func TestInstanceManagerApplyDoesNotBlockConfigs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	// The test ignores cancellation like a test in its cleanup phase
+	scheduler := NewScheduler(ctx, 0, func(ctx context.Context, cfg *Config) {
+		close(started)
+		<-release
+	})
+	m := NewInstanceManager(ctx, scheduler, NewHealthChecker("test"))
+	m.latency = func(ctx context.Context, cfg *Config) { <-ctx.Done() }
+
+	m.Apply([]*Config{{ServiceType: "nextcloud", InstanceName: "reload-busy", TestIntervalSec: 3600}})
+	<-started
+
+	applied := make(chan struct{})
+	go func() {
+		m.Apply(nil)
+		close(applied)
+	}()
+	for len(scheduler.Keys()) != 0 {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	configs := make(chan []*Config)
+	go func() { configs <- m.Configs() }()
+	select {
+	case got := <-configs:
+		if len(got) != 0 {
+			t.Errorf("expected the removed instance to be gone, got %d configs", len(got))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Configs blocked while Apply waited for the running test")
+	}
+
+	close(release)
+	<-applied
+	cancel()
+	scheduler.Wait()
+	m.Wait()
+}
+
+func TestInstanceManagerReloadKeepsInstancesOnError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	scheduler := NewScheduler(ctx, 0, func(ctx context.Context, cfg *Config) {})
+	m := NewInstanceManager(ctx, scheduler, NewHealthChecker("test"))
+	m.latency = func(ctx context.Context, cfg *Config) {}
+
+	path := writeConfigFile(t, "reload.yaml", "instances:\n  - {name: one, service: nextcloud, url: https://c, username: u, password: p}\n")
+	if _, err := m.Reload(path); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("instances: [\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	failures := testutil.ToFloat64(ConfigReloads.WithLabelValues("failure"))
+	if _, err := m.Reload(path); err == nil {
+		t.Fatal("expected reload of invalid file to fail")
+	}
+	if got := testutil.ToFloat64(ConfigReloads.WithLabelValues("failure")); got != failures+1 {
+		t.Errorf("expected failure to be counted, got %v", got)
+	}
+	if configs := m.Configs(); len(configs) != 1 || configs[0].InstanceName != "one" {
+		t.Errorf("expected running instances to be kept, got %v", configs)
+	}
+
+	cancel()
+	scheduler.Wait()
+}
+
+func TestWatchConfigDetectsFileChange(t *testing.T) {
+	path := writeConfigFile(t, "watch.yaml", "instances: []\n")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reloads := make(chan string, 1)
+	go WatchConfig(ctx, path, 10*time.Millisecond, func(reason string) { reloads <- reason })
+
+	// Unchanged contents do not trigger a reload
+	select {
+	case reason := <-reloads:
+		t.Fatalf("unexpected reload: %s", reason)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := os.WriteFile(path, []byte("instances: [{}]\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case reason := <-reloads:
+		if reason != "config file changed" {
+			t.Errorf("unexpected reload reason %q", reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected reload after config file change")
+	}
+}
@@ -0,0 +1,222 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Limits of the results API
+const (
+	DefaultResultsLimit = 50
+	MaxResultsLimit     = 1000
+)
+
+// PhaseView is the API representation of a test phase
+type PhaseView struct {
+	Phase      string  `json:"phase"`
+	Type       string  `json:"type"`
+	DurationMs float64 `json:"duration_ms"`
+	Bytes      int64   `json:"bytes,omitempty"`
+	SpeedMBps  float64 `json:"speed_mbps,omitempty"`
+	Files      int     `json:"files,omitempty"`
+	Checksum   string  `json:"checksum,omitempty"`
+	ErrorCode  string  `json:"error_code"`
+	Success    bool    `json:"success"`
+}
+
+// ResultView is the API representation of a test run
+type ResultView struct {
+	Service    string      `json:"service"`
+	Instance   string      `json:"instance"`
+	StartTime  time.Time   `json:"start_time"`
+	DurationMs float64     `json:"duration_ms"`
+	Success    bool        `json:"success"`
+	ErrorCode  string      `json:"error_code"`
+	Error      string      `json:"error,omitempty"`
+	Phases     []PhaseView `json:"phases"`
+}
+
+// ResultsResponse is the body returned by the results endpoint
+type ResultsResponse struct {
+	Count   int          `json:"count"`
+	Results []ResultView `json:"results"`
+}
+
+// ResultsQuery selects test runs from the history
+type ResultsQuery struct {
+	Service  string
+	Instance string
+	// Type is a test type (transfer, small_files, metadata) or a metric type (upload, download, list, stat)
+	Type  string
+	Since time.Time
+	// Status is "success", "failure" or empty for both
+	Status string
+	Limit  int
+}
+
+// testTypeFor returns the test type a phase belongs to
+func testTypeFor(phase string) string {
+	switch phase {
+	case PhaseSmallFilesUpload, PhaseSmallFilesList, PhaseSmallFilesDownload, PhaseSmallFilesDelete:
+		return TestTypeSmallFiles
+	case PhaseMetadataSetup, PhaseList, PhaseStat:
+		return TestTypeMetadata
+	}
+	return TestTypeTransfer
+}
+
+// matches reports whether a record satisfies the query filters other than since and limit
+func (q *ResultsQuery) matches(rec *HistoryRecord) bool {
+	if q.Service != "" && rec.ServiceType != q.Service {
+		return false
+	}
+	if q.Instance != "" && rec.InstanceName != q.Instance {
+		return false
+	}
+	switch q.Status {
+	case "success":
+		if rec.Error != "" {
+			return false
+		}
+	case "failure":
+		if rec.Error == "" {
+			return false
+		}
+	}
+	if q.Type == "" {
+		return true
+	}
+	for _, p := range rec.Phases {
+		if testTypeFor(p.Phase) == q.Type || metricTypeFor(p.Phase) == q.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// Query returns the newest runs matching q, newest first
+func (s *HistoryStore) Query(q ResultsQuery) []HistoryRecord {
+	records := s.Records(q.Since)
+	var out []HistoryRecord
+	for i := len(records) - 1; i >= 0 && len(out) < q.Limit; i-- {
+		if q.matches(&records[i]) {
+			out = append(out, records[i])
+		}
+	}
+	return out
+}
+
+// parseResultsQuery reads the query parameters instance, service, type, since, status and limit.
+// since is an RFC 3339 timestamp or a duration before now (e.g. "24h").
+func parseResultsQuery(r *http.Request, now time.Time) (ResultsQuery, error) {
+	params := r.URL.Query()
+	q := ResultsQuery{
+		Service:  params.Get("service"),
+		Instance: params.Get("instance"),
+		Type:     params.Get("type"),
+		Status:   params.Get("status"),
+		Limit:    DefaultResultsLimit,
+	}
+
+	if q.Status != "" && q.Status != "success" && q.Status != "failure" {
+		return q, fmt.Errorf("invalid status %q, must be success or failure", q.Status)
+	}
+	if q.Type != "" && !isKnownTestType(q.Type) && !isKnownMetricType(q.Type) {
+		return q, fmt.Errorf("invalid type %q", q.Type)
+	}
+
+	if value := params.Get("since"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			q.Since = now.Add(-d)
+		} else if t, err := time.Parse(time.RFC3339, value); err == nil {
+			q.Since = t
+		} else {
+			return q, fmt.Errorf("invalid since %q, must be an RFC 3339 timestamp or a duration", value)
+		}
+	}
+
+	if value := params.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return q, fmt.Errorf("invalid limit %q", value)
+		}
+		if limit > MaxResultsLimit {
+			limit = MaxResultsLimit
+		}
+		q.Limit = limit
+	}
+	return q, nil
+}
+
+// isKnownMetricType reports whether t is a "type" label of the phase metrics
+func isKnownMetricType(t string) bool {
+	for _, phase := range []string{PhaseUpload, PhaseDownload, PhaseCleanup, PhaseList, PhaseStat} {
+		if t == phase {
+			return true
+		}
+	}
+	return false
+}
+
+// newResultView converts a history record into its API representation
+func newResultView(rec *HistoryRecord) ResultView {
+	view := ResultView{
+		Service:    rec.ServiceType,
+		Instance:   rec.InstanceName,
+		StartTime:  rec.StartTime,
+		DurationMs: durationMs(rec.Duration),
+		Success:    rec.Error == "",
+		ErrorCode:  rec.ErrorCode,
+		Error:      rec.Error,
+		Phases:     make([]PhaseView, 0, len(rec.Phases)),
+	}
+	for _, p := range rec.Phases {
+		view.Phases = append(view.Phases, PhaseView{
+			Phase:      p.Phase,
+			Type:       metricTypeFor(p.Phase),
+			DurationMs: durationMs(p.Duration),
+			Bytes:      p.Bytes,
+			SpeedMBps:  p.SpeedMBps,
+			Files:      p.Files,
+			Checksum:   p.Checksum,
+			ErrorCode:  p.ErrorCode,
+			Success:    p.ErrorCode == "" || p.ErrorCode == "none",
+		})
+	}
+	return view
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// ResultsHandler returns the handler of GET /api/v1/results
+func ResultsHandler(store *HistoryStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		q, err := parseResultsQuery(r, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		records := store.Query(q)
+		resp := ResultsResponse{Count: len(records), Results: make([]ResultView, 0, len(records))}
+		for i := range records {
+			resp.Results = append(resp.Results, newResultView(&records[i]))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, "Failed to encode results", http.StatusInternalServerError)
+			return
+		}
+	}
+}
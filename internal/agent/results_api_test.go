@@ -0,0 +1,132 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func resultsTestStore(t *testing.T, now time.Time) *HistoryStore {
+	t.Helper()
+	store, err := NewHistoryStore("")
+	if err != nil {
+		t.Fatalf("NewHistoryStore failed: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		var err error
+		if i%2 == 1 {
+			err = errors.New("download failed with status 500")
+		}
+		store.Add(historyTestResult("api-a", now.Add(-time.Duration(i)*time.Hour), 10, err))
+	}
+	metadata := &RunResult{
+		ServiceType:  "hidrive",
+		InstanceName: "api-b",
+		StartTime:    now.Add(-30 * time.Minute),
+		ErrorCode:    "none",
+		Phases: []PhaseResult{
+			{Phase: PhaseList, Duration: 120 * time.Millisecond, ErrorCode: "none"},
+			{Phase: PhaseStat, Duration: 80 * time.Millisecond, ErrorCode: "none"},
+		},
+	}
+	store.Add(metadata)
+	return store
+}
+
+func getResults(t *testing.T, store *HistoryStore, query string) (int, ResultsResponse) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/results"+query, nil)
+	w := httptest.NewRecorder()
+	ResultsHandler(store)(w, req)
+
+	var resp ResultsResponse
+	if w.Code == http.StatusOK {
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("decoding response failed: %v", err)
+		}
+	}
+	return w.Code, resp
+}
+
+func TestResultsHandlerFilters(t *testing.T) {
+	store := resultsTestStore(t, time.Now())
+
+	tests := []struct {
+		query     string
+		wantCount int
+	}{
+		{"", 6},
+		{"?instance=api-a", 5},
+		{"?instance=api-a&status=failure", 2},
+		{"?instance=api-a&since=150m", 3},
+		{"?type=metadata", 1},
+		{"?type=stat", 1},
+		{"?type=upload", 5},
+		{"?service=hidrive", 1},
+		{"?limit=2", 2},
+	}
+	for _, tt := range tests {
+		code, resp := getResults(t, store, tt.query)
+		if code != http.StatusOK {
+			t.Errorf("%q: expected status 200, got %d", tt.query, code)
+			continue
+		}
+		if resp.Count != tt.wantCount || len(resp.Results) != tt.wantCount {
+			t.Errorf("%q: expected %d results, got count %d with %d results", tt.query, tt.wantCount, resp.Count, len(resp.Results))
+		}
+	}
+}
+
+func TestResultsHandlerReturnsNewestFirstWithPhases(t *testing.T) {
+	now := time.Now()
+	store := resultsTestStore(t, now)
+
+	_, resp := getResults(t, store, "?instance=api-a&limit=2")
+	if len(resp.Results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(resp.Results))
+	}
+	newest, failed := resp.Results[0], resp.Results[1]
+	if !newest.StartTime.After(failed.StartTime) {
+		t.Errorf("expected newest run first, got %v before %v", newest.StartTime, failed.StartTime)
+	}
+	if !newest.Success || newest.DurationMs != 10000 {
+		t.Errorf("unexpected newest run: %+v", newest)
+	}
+	if failed.Success || failed.ErrorCode != "http_500_server_error" || failed.Error == "" {
+		t.Errorf("expected failed run with error code and message, got %+v", failed)
+	}
+	if len(failed.Phases) != 2 || failed.Phases[1].Success || failed.Phases[1].ErrorCode != "http_500_server_error" {
+		t.Errorf("expected failed download phase, got %+v", failed.Phases)
+	}
+}
+
+func TestResultsHandlerRejectsInvalidQueries(t *testing.T) {
+	store := resultsTestStore(t, time.Now())
+
+	for _, query := range []string{"?since=yesterday", "?limit=0", "?limit=abc", "?status=broken", "?type=unknown"} {
+		if code, _ := getResults(t, store, query); code != http.StatusBadRequest {
+			t.Errorf("%q: expected status 400, got %d", query, code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/results", nil)
+	w := httptest.NewRecorder()
+	ResultsHandler(store)(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405 for POST, got %d", w.Code)
+	}
+}
+
+func TestResultsHandlerSinceTimestamp(t *testing.T) {
+	now := time.Now()
+	store := resultsTestStore(t, now)
+
+	since := now.Add(-90 * time.Minute).UTC().Format(time.RFC3339)
+	_, resp := getResults(t, store, "?instance=api-a&since="+since)
+	if resp.Count != 2 {
+		t.Errorf("expected 2 runs since %s, got %d", since, resp.Count)
+	}
+}
@@ -0,0 +1,453 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+	"time"
+)
+
+// TestDirectory is the remote directory used by all performance tests
+const TestDirectory = "/performance_tests"
+
+// Test types that can be enabled per instance
+const (
+	// TestTypeTransfer uploads, downloads and deletes one file of TestFileSizeMB
+	TestTypeTransfer = "transfer"
+	// TestTypeSmallFiles uploads, lists, downloads and deletes SmallFileCount files of SmallFileSizeKB
+	TestTypeSmallFiles = "small_files"
+	// TestTypeMetadata measures directory listing and file stat latency
+	TestTypeMetadata = "metadata"
+)
+
+// TestTypes returns all supported test types
+func TestTypes() []string {
+	return []string{TestTypeTransfer, TestTypeSmallFiles, TestTypeMetadata}
+}
+
+// isKnownTestType reports whether testType is a supported test type
+func isKnownTestType(testType string) bool {
+	for _, t := range TestTypes() {
+		if t == testType {
+			return true
+		}
+	}
+	return false
+}
+
+// Test phases, used as the "type" label of the test metrics
+const (
+	PhaseSetup    = "setup"
+	PhaseUpload   = "upload"
+	PhaseDownload = "download"
+	PhaseCleanup  = "cleanup"
+
+	PhaseSmallFilesUpload   = "small_files_upload"
+	PhaseSmallFilesList     = "small_files_list"
+	PhaseSmallFilesDownload = "small_files_download"
+	PhaseSmallFilesDelete   = "small_files_delete"
+
+	PhaseMetadataSetup = "metadata_setup"
+	PhaseList          = "list"
+	PhaseStat          = "stat"
+)
+
+// metricTypeFor returns the "type" label under which failures of a phase are reported
+func metricTypeFor(phase string) string {
+	switch phase {
+	case PhaseSetup:
+		// A failed setup means the upload could not be attempted
+		return PhaseUpload
+	case PhaseMetadataSetup:
+		// Without the probe file the stat could not be attempted
+		return PhaseStat
+	case PhaseSmallFilesUpload, PhaseSmallFilesList, PhaseSmallFilesDownload, PhaseSmallFilesDelete:
+		return TestTypeSmallFiles
+	}
+	return phase
+}
+
+// PhaseResult holds the outcome of a single test phase
+type PhaseResult struct {
+	Phase     string        `json:"phase"`
+	Duration  time.Duration `json:"duration"`
+	Bytes     int64         `json:"bytes,omitempty"`
+	SpeedMBps float64       `json:"speed_mbps,omitempty"`
+	Files     int           `json:"files,omitempty"`
+	Checksum  string        `json:"checksum,omitempty"`
+	ErrorCode string        `json:"error_code"`
+	Err       error         `json:"-"`
+}
+
+// Success reports whether the phase completed without error
+func (p *PhaseResult) Success() bool {
+	return p.Err == nil
+}
+
+// RunResult holds the outcome of a complete test run against one instance
+type RunResult struct {
+	ServiceType  string        `json:"service"`
+	InstanceName string        `json:"instance"`
+	StartTime    time.Time     `json:"start_time"`
+	Duration     time.Duration `json:"duration"`
+	Phases       []PhaseResult `json:"phases"`
+	ErrorCode    string        `json:"error_code"`
+	// Skipped is set if the test did not run because the circuit breaker is open
+	Skipped bool `json:"skipped,omitempty"`
+	// Connection is ConnectionCold or ConnectionWarm for the runs of cold/warm measurements
+	Connection string `json:"connection,omitempty"`
+	Err        error  `json:"-"`
+}
+
+// Success reports whether all mandatory phases of the run succeeded
+func (r *RunResult) Success() bool {
+	return r.Err == nil
+}
+
+// Phase returns the result of the named phase, or nil if it did not run
+func (r *RunResult) Phase(name string) *PhaseResult {
+	for i := range r.Phases {
+		if r.Phases[i].Phase == name {
+			return &r.Phases[i]
+		}
+	}
+	return nil
+}
+
+// codedError attaches a fixed error code to an error so that the runner
+// does not have to guess it from the error message
+type codedError struct {
+	code string
+	err  error
+}
+
+func (e *codedError) Error() string { return e.err.Error() }
+func (e *codedError) Unwrap() error { return e.err }
+
+// withErrorCode wraps err so that the runner reports it with the given error code
+func withErrorCode(code string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &codedError{code: code, err: err}
+}
+
+// errorCodeFor returns the error code for err in the given phase
+func errorCodeFor(err error, phase string) string {
+	var coded *codedError
+	if errors.As(err, &coded) {
+		return coded.code
+	}
+	// Small files phases fall back to the codes of the underlying operation
+	return ExtractErrorCode(err, strings.TrimPrefix(phase, TestTypeSmallFiles+"_"))
+}
+
+// testRun carries the state of a single run through its phases
+type testRun struct {
+	// ctx ends when the run is cancelled or exceeds the test timeout
+	ctx      context.Context
+	cfg      *Config
+	provider StorageProvider
+	result   *RunResult
+
+	filePath  string
+	fileSize  int64
+	chunkSize int64
+
+	// uploadHash and downloadHash are fed while the data is streamed
+	uploadHash   hash.Hash
+	downloadHash hash.Hash
+
+	// phaseFiles is set by phases that process several files
+	phaseFiles int
+	// integrityFailed is set once any downloaded data did not match
+	integrityFailed bool
+}
+
+// RunProviderTest runs the upload → download → delete test against a provider and
+// emits the test metrics identically for every service type. The run is aborted once it
+// exceeds the instance's test timeout, each phase once it exceeds the phase timeout.
+func RunProviderTest(ctx context.Context, cfg *Config, provider StorageProvider) *RunResult {
+	testTimeout := cfg.TestTimeout()
+	ctx, cancel := context.WithTimeoutCause(ctx, testTimeout, fmt.Errorf("test timeout of %v exceeded", testTimeout))
+	defer cancel()
+
+	run := &testRun{
+		ctx:          ctx,
+		cfg:          cfg,
+		provider:     provider,
+		filePath:     fmt.Sprintf("%s/testfile_%d.tmp", TestDirectory, time.Now().UnixNano()),
+		fileSize:     int64(cfg.TestFileSizeMB) * 1024 * 1024,
+		chunkSize:    int64(cfg.TestChunkSizeMB) * 1024 * 1024,
+		uploadHash:   sha256.New(),
+		downloadHash: sha256.New(),
+		result: &RunResult{
+			ServiceType:  cfg.ServiceType,
+			InstanceName: cfg.InstanceName,
+			StartTime:    time.Now(),
+			ErrorCode:    "none",
+		},
+	}
+
+	LogServiceOperation(INFO, cfg.ServiceType, cfg.InstanceName, "test", "start",
+		"Starting performance test",
+		WithSize(run.fileSize))
+
+	// Record chunk size for monitoring
+	ChunkSize.WithLabelValues(cfg.ServiceType, cfg.InstanceName).Set(float64(run.chunkSize))
+
+	run.execute()
+
+	run.result.Duration = time.Since(run.result.StartTime)
+	if run.result.Err != nil {
+		LogServiceOperation(ERROR, cfg.ServiceType, cfg.InstanceName, "test", "failed",
+			"Performance test failed",
+			WithError(run.result.Err),
+			WithDuration(run.result.Duration))
+		return run.result
+	}
+
+	// Reset previous error states after a fully successful test to prevent false alerts
+	var metricTypes []string
+	if cfg.TestEnabled(TestTypeTransfer) {
+		metricTypes = append(metricTypes, PhaseUpload, PhaseDownload)
+	}
+	if cfg.TestEnabled(TestTypeSmallFiles) {
+		metricTypes = append(metricTypes, TestTypeSmallFiles)
+	}
+	if cfg.TestEnabled(TestTypeMetadata) {
+		metricTypes = append(metricTypes, PhaseList, PhaseStat)
+	}
+	for _, errorCode := range GetAllErrorCodes() {
+		for _, metricType := range metricTypes {
+			TestSuccess.WithLabelValues(cfg.ServiceType, cfg.InstanceName, metricType, errorCode).Set(1)
+		}
+	}
+	LogServiceOperation(INFO, cfg.ServiceType, cfg.InstanceName, "test", "complete",
+		"Performance test completed successfully",
+		WithDuration(run.result.Duration))
+
+	return run.result
+}
+
+// execute prepares the test directory and runs every enabled test type
+func (r *testRun) execute() {
+	if !r.runPhase(PhaseSetup, r.setup) {
+		return
+	}
+	if r.cfg.TestEnabled(TestTypeTransfer) {
+		r.transfer()
+	}
+	if r.cfg.TestEnabled(TestTypeSmallFiles) {
+		r.smallFiles()
+	}
+	if r.cfg.TestEnabled(TestTypeMetadata) {
+		r.metadata()
+	}
+}
+
+// transfer runs the upload → download → delete test of a single large file and
+// stops at the first failing mandatory phase
+func (r *testRun) transfer() {
+	if !r.runPhase(PhaseUpload, r.upload) {
+		// Try to clean up partially uploaded data
+		ctx, cancel := r.cleanupContext()
+		defer cancel()
+		_ = r.provider.DeleteFile(ctx, r.filePath)
+		return
+	}
+	r.runPhase(PhaseDownload, r.download)
+	r.cleanup()
+}
+
+// runPhase executes fn as the given phase, records its result and metrics and
+// returns true if the phase succeeded. fn receives a context that ends with the run
+// or when the phase timeout expires.
+func (r *testRun) runPhase(phase string, fn func(ctx context.Context) (int64, error)) bool {
+	cfg := r.cfg
+	if err := r.ctx.Err(); err != nil {
+		err = deadlineError(r.ctx, err)
+		r.recordFailure(PhaseResult{Phase: phase, Err: err, ErrorCode: errorCodeFor(err, phase)})
+		return false
+	}
+
+	LogServiceOperation(INFO, cfg.ServiceType, cfg.InstanceName, phase, "start",
+		fmt.Sprintf("Starting %s phase", phase))
+
+	phaseTimeout := cfg.PhaseTimeout()
+	ctx, cancel := context.WithTimeoutCause(r.ctx, phaseTimeout,
+		fmt.Errorf("%s phase timeout of %v exceeded", phase, phaseTimeout))
+	defer cancel()
+
+	start := time.Now()
+	r.phaseFiles = 0
+	bytes, err := fn(ctx)
+	err = deadlineError(ctx, err)
+	res := PhaseResult{
+		Phase:     phase,
+		Duration:  time.Since(start),
+		Bytes:     bytes,
+		Files:     r.phaseFiles,
+		ErrorCode: "none",
+		Err:       err,
+	}
+
+	// Transfer and metadata phases carry duration metrics, only transfers carry speed
+	transfer := phase == PhaseUpload || phase == PhaseDownload
+	timed := transfer || phase == PhaseList || phase == PhaseStat
+	if timed {
+		TestDurationHistogram.WithLabelValues(cfg.ServiceType, cfg.InstanceName, phase).Observe(res.Duration.Seconds())
+		TestDuration.WithLabelValues(cfg.ServiceType, cfg.InstanceName, phase).Set(res.Duration.Seconds())
+	}
+
+	if err != nil {
+		res.ErrorCode = errorCodeFor(err, phase)
+		r.recordFailure(res)
+		return false
+	}
+
+	if transfer {
+		res.Checksum = r.checksum(phase)
+		if res.Duration > 0 {
+			res.SpeedMBps = (float64(bytes) / (1024 * 1024)) / res.Duration.Seconds()
+		}
+		// Only record speed for successful transfers
+		TestSpeedMbytesPerSec.WithLabelValues(cfg.ServiceType, cfg.InstanceName, phase).Set(res.SpeedMBps)
+	}
+	if timed {
+		TestSuccess.WithLabelValues(cfg.ServiceType, cfg.InstanceName, phase, "none").Set(1)
+	}
+
+	LogServiceOperation(INFO, cfg.ServiceType, cfg.InstanceName, phase, "success",
+		fmt.Sprintf("%s phase completed", phase),
+		WithDuration(res.Duration),
+		WithSize(res.Bytes),
+		WithSpeed(res.SpeedMBps))
+
+	r.result.Phases = append(r.result.Phases, res)
+	return true
+}
+
+// deadlineError reports err as network_timeout if ctx expired, whatever error the
+// provider returned for the aborted request. The error names the expired timeout.
+func deadlineError(ctx context.Context, err error) error {
+	if err == nil || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	return withErrorCode("network_timeout", fmt.Errorf("%w: %w", context.Cause(ctx), err))
+}
+
+// cleanupContext returns the context for removing test data. Cleanup also runs after the
+// run was cancelled or timed out, but is bounded by the phase timeout.
+func (r *testRun) cleanupContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(r.ctx), r.cfg.PhaseTimeout())
+}
+
+// recordFailure stores a failed phase and updates the error metrics
+func (r *testRun) recordFailure(res PhaseResult) {
+	cfg := r.cfg
+	metricType := metricTypeFor(res.Phase)
+
+	LogServiceOperation(ERROR, cfg.ServiceType, cfg.InstanceName, res.Phase, "error",
+		fmt.Sprintf("%s phase failed", res.Phase),
+		WithError(res.Err),
+		WithDuration(res.Duration))
+	TestErrors.WithLabelValues(cfg.ServiceType, cfg.InstanceName, metricType, res.ErrorCode).Inc()
+	TestSuccess.WithLabelValues(cfg.ServiceType, cfg.InstanceName, metricType, res.ErrorCode).Set(0)
+
+	r.result.Phases = append(r.result.Phases, res)
+	if r.result.Err == nil {
+		r.result.Err = fmt.Errorf("%s failed: %w", res.Phase, res.Err)
+		r.result.ErrorCode = res.ErrorCode
+	}
+}
+
+// setup makes sure the test directory exists
+func (r *testRun) setup(ctx context.Context) (int64, error) {
+	return 0, withErrorCode("directory_creation", r.provider.EnsureDirectory(ctx, TestDirectory))
+}
+
+// upload streams fileSize bytes of random data to the provider, hashing it on the way
+func (r *testRun) upload(ctx context.Context) (int64, error) {
+	reader := io.TeeReader(io.LimitReader(&randomReader{}, r.fileSize), r.uploadHash)
+	if err := r.provider.UploadFile(ctx, r.filePath, reader, r.fileSize, r.chunkSize); err != nil {
+		return 0, err
+	}
+
+	// Calculate expected chunks for monitoring
+	if r.chunkSize > 0 {
+		expectedChunks := (r.fileSize + r.chunkSize - 1) / r.chunkSize // Ceiling division
+		ChunksUploaded.WithLabelValues(r.cfg.ServiceType, r.cfg.InstanceName).Add(float64(expectedChunks))
+	}
+	return r.fileSize, nil
+}
+
+// download reads the uploaded file back completely and verifies its size and checksum
+func (r *testRun) download(ctx context.Context) (int64, error) {
+	body, err := r.provider.DownloadFile(ctx, r.filePath)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+
+	// Read the whole body to get an accurate time measurement
+	n, err := io.Copy(r.downloadHash, body)
+	if err != nil {
+		return n, err
+	}
+	if n != r.fileSize {
+		return n, withErrorCode("size_mismatch",
+			fmt.Errorf("downloaded file size mismatch: expected %d bytes, got %d", r.fileSize, n))
+	}
+
+	uploaded, downloaded := r.checksum(PhaseUpload), r.checksum(PhaseDownload)
+	if uploaded != downloaded {
+		r.integrityFailed = true
+		TestIntegrityOK.WithLabelValues(r.cfg.ServiceType, r.cfg.InstanceName).Set(0)
+		return n, withErrorCode("data_corruption",
+			fmt.Errorf("checksum mismatch: uploaded sha256 %s, downloaded sha256 %s", uploaded, downloaded))
+	}
+	TestIntegrityOK.WithLabelValues(r.cfg.ServiceType, r.cfg.InstanceName).Set(1)
+	return n, nil
+}
+
+// checksum returns the hex encoded SHA-256 of the data streamed in the given phase
+func (r *testRun) checksum(phase string) string {
+	h := r.downloadHash
+	if phase == PhaseUpload {
+		h = r.uploadHash
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cleanup deletes the test file; failures are reported but do not fail the run
+func (r *testRun) cleanup() {
+	cfg := r.cfg
+	ctx, cancel := r.cleanupContext()
+	defer cancel()
+
+	start := time.Now()
+	err := r.provider.DeleteFile(ctx, r.filePath)
+	res := PhaseResult{
+		Phase:     PhaseCleanup,
+		Duration:  time.Since(start),
+		ErrorCode: "none",
+		Err:       err,
+	}
+	if err != nil {
+		res.ErrorCode = "delete_failed"
+		LogServiceOperation(WARN, cfg.ServiceType, cfg.InstanceName, PhaseCleanup, "warning",
+			"Could not delete test file",
+			WithError(err))
+		TestErrors.WithLabelValues(cfg.ServiceType, cfg.InstanceName, PhaseCleanup, res.ErrorCode).Inc()
+	} else {
+		LogServiceOperation(DEBUG, cfg.ServiceType, cfg.InstanceName, PhaseCleanup, "success",
+			"Test file cleanup completed")
+	}
+	r.result.Phases = append(r.result.Phases, res)
+}
@@ -0,0 +1,313 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// memoryProvider is an in-memory StorageProvider used to test the runner
+type memoryProvider struct {
+	mu          sync.Mutex
+	files       map[string][]byte
+	ensureErr   error
+	uploadErr   error
+	downloadErr error
+	deleteErr   error
+	listErr     error
+	statErr     error
+	truncate    bool
+}
+
+func newMemoryProvider() *memoryProvider {
+	return &memoryProvider{files: make(map[string][]byte)}
+}
+
+func (m *memoryProvider) EnsureDirectory(ctx context.Context, dirPath string) error {
+	return m.ensureErr
+}
+
+func (m *memoryProvider) UploadFile(ctx context.Context, filePath string, reader io.Reader, size int64, chunkSize int64) error {
+	if m.uploadErr != nil {
+		return m.uploadErr
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[filePath] = data
+	return nil
+}
+
+func (m *memoryProvider) DownloadFile(ctx context.Context, filePath string) (io.ReadCloser, error) {
+	if m.downloadErr != nil {
+		return nil, m.downloadErr
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.files[filePath]
+	if !ok {
+		return nil, errors.New("file not found")
+	}
+	if m.truncate {
+		data = data[:len(data)/2]
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m *memoryProvider) DeleteFile(ctx context.Context, filePath string) error {
+	if m.deleteErr != nil {
+		return m.deleteErr
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.files, filePath)
+	return nil
+}
+
+func (m *memoryProvider) ListDirectory(ctx context.Context, dirPath string) ([]string, error) {
+	if m.listErr != nil {
+		return nil, m.listErr
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var names []string
+	for name := range m.files {
+		if path.Dir(name) == dirPath {
+			names = append(names, path.Base(name))
+		}
+	}
+	return names, nil
+}
+
+func (m *memoryProvider) StatFile(ctx context.Context, filePath string) (int64, error) {
+	if m.statErr != nil {
+		return 0, m.statErr
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.files[filePath]
+	if !ok {
+		return 0, errors.New("file not found")
+	}
+	return int64(len(data)), nil
+}
+
+func runnerTestConfig(instance string) *Config {
+	return &Config{
+		InstanceName:    instance,
+		ServiceType:     "nextcloud",
+		TestFileSizeMB:  1,
+		TestChunkSizeMB: 1,
+		TestIntervalSec: 60,
+	}
+}
+
+func TestRunProviderTestSuccess(t *testing.T) {
+	cfg := runnerTestConfig("runner-success")
+	provider := newMemoryProvider()
+
+	result := RunProviderTest(context.Background(), cfg, provider)
+	if !result.Success() {
+		t.Fatalf("expected success, got %v", result.Err)
+	}
+	if result.ErrorCode != "none" {
+		t.Errorf("expected error code none, got %s", result.ErrorCode)
+	}
+	for _, phase := range []string{PhaseSetup, PhaseUpload, PhaseDownload, PhaseCleanup} {
+		if result.Phase(phase) == nil {
+			t.Errorf("expected phase %s in result", phase)
+		}
+	}
+	if download := result.Phase(PhaseDownload); download.Bytes != 1024*1024 {
+		t.Errorf("expected 1 MiB downloaded, got %d", download.Bytes)
+	}
+	if len(provider.files) != 0 {
+		t.Errorf("expected test file to be deleted, %d files left", len(provider.files))
+	}
+	if v := testutil.ToFloat64(TestSuccess.WithLabelValues(cfg.ServiceType, cfg.InstanceName, PhaseUpload, "none")); v != 1 {
+		t.Errorf("expected upload success metric 1, got %v", v)
+	}
+	if v := testutil.ToFloat64(TestSuccess.WithLabelValues(cfg.ServiceType, cfg.InstanceName, PhaseDownload, "none")); v != 1 {
+		t.Errorf("expected download success metric 1, got %v", v)
+	}
+}
+
+func TestRunProviderTestSetupFailure(t *testing.T) {
+	cfg := runnerTestConfig("runner-setup")
+	provider := newMemoryProvider()
+	provider.ensureErr = errors.New("MKCOL failed with status 500")
+
+	result := RunProviderTest(context.Background(), cfg, provider)
+	if result.Success() {
+		t.Fatal("expected failure")
+	}
+	if result.ErrorCode != "directory_creation" {
+		t.Errorf("expected directory_creation, got %s", result.ErrorCode)
+	}
+	if result.Phase(PhaseUpload) != nil {
+		t.Error("upload must not run after setup failure")
+	}
+	if v := testutil.ToFloat64(TestErrors.WithLabelValues(cfg.ServiceType, cfg.InstanceName, PhaseUpload, "directory_creation")); v != 1 {
+		t.Errorf("expected one directory_creation error, got %v", v)
+	}
+}
+
+func TestRunProviderTestUploadFailure(t *testing.T) {
+	cfg := runnerTestConfig("runner-upload")
+	provider := newMemoryProvider()
+	provider.uploadErr = errors.New("upload failed with status 503")
+
+	result := RunProviderTest(context.Background(), cfg, provider)
+	if result.ErrorCode != "http_503_unavailable" {
+		t.Errorf("expected http_503_unavailable, got %s", result.ErrorCode)
+	}
+	if result.Phase(PhaseDownload) != nil {
+		t.Error("download must not run after upload failure")
+	}
+	if v := testutil.ToFloat64(TestSuccess.WithLabelValues(cfg.ServiceType, cfg.InstanceName, PhaseUpload, "http_503_unavailable")); v != 0 {
+		t.Errorf("expected upload failure metric 0, got %v", v)
+	}
+}
+
+func TestRunProviderTestSizeMismatch(t *testing.T) {
+	cfg := runnerTestConfig("runner-size")
+	provider := newMemoryProvider()
+	provider.truncate = true
+
+	result := RunProviderTest(context.Background(), cfg, provider)
+	if result.ErrorCode != "size_mismatch" {
+		t.Errorf("expected size_mismatch, got %s", result.ErrorCode)
+	}
+	if result.Phase(PhaseCleanup) == nil {
+		t.Error("cleanup must run after download failure")
+	}
+	if len(provider.files) != 0 {
+		t.Errorf("expected test file to be deleted, %d files left", len(provider.files))
+	}
+}
+
+func TestRunProviderTestCleanupFailureDoesNotFailRun(t *testing.T) {
+	cfg := runnerTestConfig("runner-cleanup")
+	provider := newMemoryProvider()
+	provider.deleteErr = errors.New("delete failed")
+
+	result := RunProviderTest(context.Background(), cfg, provider)
+	if !result.Success() {
+		t.Fatalf("expected success, got %v", result.Err)
+	}
+	if cleanup := result.Phase(PhaseCleanup); cleanup == nil || cleanup.ErrorCode != "delete_failed" {
+		t.Errorf("expected cleanup phase with delete_failed, got %+v", cleanup)
+	}
+}
+
+func TestRunProviderTestCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result := RunProviderTest(ctx, runnerTestConfig("runner-cancel"), newMemoryProvider())
+	if result.Success() {
+		t.Fatal("expected failure for cancelled context")
+	}
+	if !errors.Is(result.Err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", result.Err)
+	}
+}
+
+// corruptingProvider flips one byte of every downloaded file
+type corruptingProvider struct {
+	*memoryProvider
+}
+
+func (c *corruptingProvider) DownloadFile(ctx context.Context, filePath string) (io.ReadCloser, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data := append([]byte(nil), c.files[filePath]...)
+	data[len(data)/2] ^= 0xff
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func TestRunProviderTestDetectsCorruption(t *testing.T) {
+	cfg := runnerTestConfig("runner-corrupt")
+	provider := &corruptingProvider{newMemoryProvider()}
+
+	result := RunProviderTest(context.Background(), cfg, provider)
+	if result.ErrorCode != "data_corruption" {
+		t.Errorf("expected data_corruption, got %s", result.ErrorCode)
+	}
+	if v := testutil.ToFloat64(TestIntegrityOK.WithLabelValues(cfg.ServiceType, cfg.InstanceName)); v != 0 {
+		t.Errorf("expected integrity metric 0, got %v", v)
+	}
+}
+
+// stallingProvider never finishes an upload until the request context ends
+type stallingProvider struct {
+	*memoryProvider
+}
+
+func (s *stallingProvider) UploadFile(ctx context.Context, filePath string, reader io.Reader, size int64, chunkSize int64) error {
+	s.mu.Lock()
+	s.files[filePath] = nil
+	s.mu.Unlock()
+	<-ctx.Done()
+	return fmt.Errorf("PUT %s: %w", filePath, ctx.Err())
+}
+
+func TestRunProviderTestTimeouts(t *testing.T) {
+	tests := []struct {
+		name         string
+		testTimeout  int
+		phaseTimeout int
+		message      string
+	}{
+		{name: "phase", testTimeout: 60, phaseTimeout: 1, message: "upload phase timeout of 1s exceeded"},
+		{name: "test", testTimeout: 1, phaseTimeout: 60, message: "test timeout of 1s exceeded"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := runnerTestConfig("runner-timeout-" + tt.name)
+			cfg.TestTimeoutSec = tt.testTimeout
+			cfg.PhaseTimeoutSec = tt.phaseTimeout
+			provider := &stallingProvider{newMemoryProvider()}
+
+			result := RunProviderTest(context.Background(), cfg, provider)
+			if result.ErrorCode != "network_timeout" {
+				t.Errorf("expected network_timeout, got %s (%v)", result.ErrorCode, result.Err)
+			}
+			if !errors.Is(result.Err, context.DeadlineExceeded) || !strings.Contains(result.Err.Error(), tt.message) {
+				t.Errorf("expected %q, got %v", tt.message, result.Err)
+			}
+			// The partial upload is removed even though the run's context has expired
+			if len(provider.files) != 0 {
+				t.Errorf("expected partial upload to be deleted, %d files left", len(provider.files))
+			}
+		})
+	}
+}
+
+func TestRunProviderTestIntegrityOK(t *testing.T) {
+	cfg := runnerTestConfig("runner-integrity")
+
+	result := RunProviderTest(context.Background(), cfg, newMemoryProvider())
+	if !result.Success() {
+		t.Fatalf("expected success, got %v", result.Err)
+	}
+	upload, download := result.Phase(PhaseUpload), result.Phase(PhaseDownload)
+	if upload.Checksum == "" || upload.Checksum != download.Checksum {
+		t.Errorf("expected matching checksums, got %q and %q", upload.Checksum, download.Checksum)
+	}
+	if v := testutil.ToFloat64(TestIntegrityOK.WithLabelValues(cfg.ServiceType, cfg.InstanceName)); v != 1 {
+		t.Errorf("expected integrity metric 1, got %v", v)
+	}
+}
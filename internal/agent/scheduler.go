@@ -0,0 +1,173 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultJitterPercent is the default random spread applied to every scheduled run
+const DefaultJitterPercent = 10
+
+// ScheduledFunc runs one test for an instance
+type ScheduledFunc func(ctx context.Context, cfg *Config)
+
+// scheduledInstance is the schedule of a single instance
+type scheduledInstance struct {
+	cfg    *Config
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Scheduler triggers the test of every instance at the instance's own interval.
+// Each run is shifted by a random jitter so that instances with the same interval
+// do not hit the network and the providers at the same moment.
+type Scheduler struct {
+	ctx    context.Context
+	run    ScheduledFunc
+	jitter float64 // fraction of the interval, 0.1 = ±10%
+
+	mu        sync.Mutex
+	instances map[string]*scheduledInstance
+	wg        sync.WaitGroup
+}
+
+// NewScheduler creates a scheduler whose schedules stop when ctx is cancelled.
+// jitterPercent is the maximum random deviation from the interval in percent.
+func NewScheduler(ctx context.Context, jitterPercent int, run ScheduledFunc) *Scheduler {
+	if jitterPercent < 0 {
+		jitterPercent = 0
+	}
+	if jitterPercent > 100 {
+		jitterPercent = 100
+	}
+	return &Scheduler{
+		ctx:       ctx,
+		run:       run,
+		jitter:    float64(jitterPercent) / 100,
+		instances: make(map[string]*scheduledInstance),
+	}
+}
+
+// GetJitterPercent returns the schedule jitter from SCHEDULE_JITTER_PERCENT
+func GetJitterPercent() int {
+	value := os.Getenv("SCHEDULE_JITTER_PERCENT")
+	if value == "" {
+		return DefaultJitterPercent
+	}
+	percent, err := strconv.Atoi(value)
+	if err != nil || percent < 0 {
+		return DefaultJitterPercent
+	}
+	return percent
+}
+
+// InstanceKey returns the key identifying an instance across configurations
+func InstanceKey(cfg *Config) string {
+	return cfg.ServiceType + "/" + cfg.InstanceName
+}
+
+// Add starts the schedule of an instance. An existing schedule for the same
+// instance is replaced.
+func (s *Scheduler) Add(cfg *Config) {
+	key := InstanceKey(cfg)
+	s.Remove(key)
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	inst := &scheduledInstance{cfg: cfg, cancel: cancel, done: make(chan struct{})}
+
+	s.mu.Lock()
+	s.instances[key] = inst
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer close(inst.done)
+		s.loop(ctx, cfg)
+	}()
+
+	LogServiceOperation(INFO, cfg.ServiceType, cfg.InstanceName, "scheduler", "add",
+		fmt.Sprintf("Scheduled test every %ds (jitter ±%.0f%%)", cfg.TestIntervalSec, s.jitter*100))
+}
+
+// Remove stops the schedule of an instance and waits for a running test to finish
+func (s *Scheduler) Remove(key string) {
+	s.mu.Lock()
+	inst, ok := s.instances[key]
+	delete(s.instances, key)
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	inst.cancel()
+	<-inst.done
+	NextTestRunTimestamp.DeleteLabelValues(inst.cfg.ServiceType, inst.cfg.InstanceName)
+	LogServiceOperation(INFO, inst.cfg.ServiceType, inst.cfg.InstanceName, "scheduler", "remove",
+		"Removed test schedule")
+}
+
+// Keys returns the keys of all scheduled instances
+func (s *Scheduler) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.instances))
+	for key := range s.instances {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Wait blocks until all schedules have stopped
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+// loop runs the test of one instance until ctx is cancelled
+func (s *Scheduler) loop(ctx context.Context, cfg *Config) {
+	interval := time.Duration(cfg.TestIntervalSec) * time.Second
+
+	// Spread the first runs over the jitter window instead of starting all at once
+	next := time.Now().Add(s.initialDelay(interval))
+	for {
+		NextTestRunTimestamp.WithLabelValues(cfg.ServiceType, cfg.InstanceName).Set(float64(next.Unix()))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		start := time.Now()
+		s.run(ctx, cfg)
+
+		// Intervals are measured start to start; an overrunning test is followed immediately
+		next = start.Add(s.nextDelay(interval))
+	}
+}
+
+// initialDelay returns a random delay within the jitter window for the first run
+func (s *Scheduler) initialDelay(interval time.Duration) time.Duration {
+	window := time.Duration(float64(interval) * s.jitter)
+	if window <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(window)))
+}
+
+// nextDelay returns the interval shifted by a random jitter of ±jitter*interval
+func (s *Scheduler) nextDelay(interval time.Duration) time.Duration {
+	window := time.Duration(float64(interval) * s.jitter)
+	if window <= 0 {
+		return interval
+	}
+	return interval - window + time.Duration(rand.Int63n(int64(2*window)+1))
+}
@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestSchedulerJitterBounds(t *testing.T) {
+	s := NewScheduler(context.Background(), 10, nil)
+	interval := 100 * time.Second
+
+	for i := 0; i < 1000; i++ {
+		if d := s.nextDelay(interval); d < 90*time.Second || d > 110*time.Second {
+			t.Fatalf("next delay %v outside ±10%% of %v", d, interval)
+		}
+		if d := s.initialDelay(interval); d < 0 || d >= 10*time.Second {
+			t.Fatalf("initial delay %v outside jitter window", d)
+		}
+	}
+
+	noJitter := NewScheduler(context.Background(), 0, nil)
+	if d := noJitter.nextDelay(interval); d != interval {
+		t.Errorf("expected exact interval without jitter, got %v", d)
+	}
+	if d := noJitter.initialDelay(interval); d != 0 {
+		t.Errorf("expected immediate first run without jitter, got %v", d)
+	}
+}
+
+func TestSchedulerRunsInstancesIndependently(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	runs := make(map[string]int)
+	s := NewScheduler(ctx, 0, func(ctx context.Context, cfg *Config) {
+		mu.Lock()
+		runs[cfg.InstanceName]++
+		mu.Unlock()
+	})
+
+	fast := &Config{ServiceType: "nextcloud", InstanceName: "sched-fast", TestIntervalSec: 1}
+	slow := &Config{ServiceType: "nextcloud", InstanceName: "sched-slow", TestIntervalSec: 3600}
+	s.Add(fast)
+	s.Add(slow)
+
+	time.Sleep(1300 * time.Millisecond)
+
+	mu.Lock()
+	fastRuns, slowRuns := runs["sched-fast"], runs["sched-slow"]
+	mu.Unlock()
+	if fastRuns != 2 {
+		t.Errorf("expected 2 runs of the fast instance, got %d", fastRuns)
+	}
+	if slowRuns != 1 {
+		t.Errorf("expected 1 run of the slow instance, got %d", slowRuns)
+	}
+
+	next := testutil.ToFloat64(NextTestRunTimestamp.WithLabelValues("nextcloud", "sched-slow"))
+	if expected := float64(time.Now().Add(time.Hour).Unix()); next < expected-5 || next > expected+5 {
+		t.Errorf("expected next run in about one hour, got %v", next)
+	}
+
+	s.Remove(InstanceKey(slow))
+	if keys := s.Keys(); len(keys) != 1 || keys[0] != InstanceKey(fast) {
+		t.Errorf("unexpected scheduled instances after remove: %v", keys)
+	}
+
+	cancel()
+	s.Wait()
+}
@@ -132,14 +132,23 @@ type TestManager struct {
 	runningTests map[string]context.CancelFunc
 	wg           sync.WaitGroup
 	shutdown     *ShutdownManager
+	
+	// Concurrency limits used by Execute, see executor.go
+	limits        ExecutionLimits
+	globalSlots   chan struct{}
+	serviceSlots  map[string]chan struct{}
+	instanceLocks map[string]chan struct{}
 }
 
 // NewTestManager creates a new test manager
 func NewTestManager(shutdown *ShutdownManager) *TestManager {
 	tm := &TestManager{
-		runningTests: make(map[string]context.CancelFunc),
-		shutdown:     shutdown,
+		runningTests:  make(map[string]context.CancelFunc),
+		shutdown:      shutdown,
+		serviceSlots:  make(map[string]chan struct{}),
+		instanceLocks: make(map[string]chan struct{}),
 	}
+	tm.SetLimits(SequentialLimits())
 	
 	// Register shutdown hook
 	shutdown.AddHook(tm.shutdownHook)
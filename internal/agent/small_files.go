@@ -0,0 +1,236 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// smallFileQuantiles are the latency percentiles exported for every small files operation
+var smallFileQuantiles = []float64{0.5, 0.9, 0.99}
+
+// smallFilesRun carries the state of one many-small-files test
+type smallFilesRun struct {
+	*testRun
+
+	count    int
+	size     int64
+	files    []string
+	checksum map[string][sha256.Size]byte
+	uploaded []string // files that must be deleted again
+}
+
+// smallFiles uploads, lists, downloads and deletes SmallFileCount files of SmallFileSizeKB
+// in the test directory. Per-request overhead dominates this workload, so files per
+// second and per-request latency percentiles are reported for every operation.
+func (r *testRun) smallFiles() {
+	sf := &smallFilesRun{
+		testRun:  r,
+		count:    firstPositive(r.cfg.SmallFileCount, DefaultSmallFileCount),
+		size:     int64(firstPositive(r.cfg.SmallFileSizeKB, DefaultSmallFileSizeKB)) * 1024,
+		checksum: make(map[string][sha256.Size]byte),
+	}
+	runID := time.Now().UnixNano()
+	for i := 0; i < sf.count; i++ {
+		sf.files = append(sf.files, fmt.Sprintf("%s/smallfile_%d_%04d.tmp", TestDirectory, runID, i))
+	}
+
+	LogServiceOperation(INFO, r.cfg.ServiceType, r.cfg.InstanceName, TestTypeSmallFiles, "start",
+		fmt.Sprintf("Starting small files test with %d files", sf.count),
+		WithSize(sf.size))
+
+	defer sf.cleanup()
+	ok := r.runPhase(PhaseSmallFilesUpload, sf.upload) &&
+		r.runPhase(PhaseSmallFilesList, sf.list) &&
+		r.runPhase(PhaseSmallFilesDownload, sf.download) &&
+		r.runPhase(PhaseSmallFilesDelete, sf.deleteFiles)
+	if ok {
+		TestSuccess.WithLabelValues(r.cfg.ServiceType, r.cfg.InstanceName, TestTypeSmallFiles, "none").Set(1)
+	}
+}
+
+// cleanup tries to remove the files left behind by a failed phase, including a failed
+// delete phase; the failure is already reported
+func (sf *smallFilesRun) cleanup() {
+	if len(sf.uploaded) == 0 {
+		return
+	}
+	ctx, cancel := sf.cleanupContext()
+	defer cancel()
+	for _, file := range sf.uploaded {
+		_ = sf.provider.DeleteFile(ctx, file)
+	}
+}
+
+// upload uploads all files with random content and remembers their checksums
+func (sf *smallFilesRun) upload(ctx context.Context) (int64, error) {
+	latencies := make([]time.Duration, 0, sf.count)
+	var total int64
+	for _, file := range sf.files {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		h := sha256.New()
+		reader := io.TeeReader(io.LimitReader(&randomReader{}, sf.size), h)
+
+		start := time.Now()
+		if err := sf.provider.UploadFile(ctx, file, reader, sf.size, sf.chunkSize); err != nil {
+			// The file may exist partially
+			sf.uploaded = append(sf.uploaded, file)
+			return total, fmt.Errorf("upload of %s failed: %w", path.Base(file), err)
+		}
+		latencies = append(latencies, time.Since(start))
+
+		var sum [sha256.Size]byte
+		copy(sum[:], h.Sum(nil))
+		sf.checksum[file] = sum
+		sf.uploaded = append(sf.uploaded, file)
+		total += sf.size
+	}
+	sf.report("upload", latencies, len(latencies))
+	return total, nil
+}
+
+// list lists the test directory once and checks that every uploaded file is visible
+func (sf *smallFilesRun) list(ctx context.Context) (int64, error) {
+	start := time.Now()
+	names, err := sf.provider.ListDirectory(ctx, TestDirectory)
+	if err != nil {
+		return 0, err
+	}
+	latency := time.Since(start)
+
+	listed := make(map[string]bool, len(names))
+	for _, name := range names {
+		listed[name] = true
+	}
+	missing := 0
+	for _, file := range sf.files {
+		if !listed[path.Base(file)] {
+			missing++
+		}
+	}
+	if missing > 0 {
+		return 0, withErrorCode("listing_incomplete",
+			fmt.Errorf("directory listing incomplete: %d of %d uploaded files missing", missing, len(sf.files)))
+	}
+	sf.report("list", []time.Duration{latency}, len(sf.files))
+	return 0, nil
+}
+
+// download reads every file back and verifies its size and checksum
+func (sf *smallFilesRun) download(ctx context.Context) (int64, error) {
+	latencies := make([]time.Duration, 0, sf.count)
+	var total int64
+	for _, file := range sf.files {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		start := time.Now()
+		n, sum, err := sf.downloadFile(ctx, file)
+		if err != nil {
+			return total, err
+		}
+		latencies = append(latencies, time.Since(start))
+		total += n
+
+		if n != sf.size {
+			return total, withErrorCode("size_mismatch",
+				fmt.Errorf("downloaded file size mismatch for %s: expected %d bytes, got %d", path.Base(file), sf.size, n))
+		}
+		if sum != sf.checksum[file] {
+			sf.integrityFailed = true
+			TestIntegrityOK.WithLabelValues(sf.cfg.ServiceType, sf.cfg.InstanceName).Set(0)
+			return total, withErrorCode("data_corruption",
+				fmt.Errorf("checksum mismatch for %s", path.Base(file)))
+		}
+	}
+	if !sf.integrityFailed {
+		TestIntegrityOK.WithLabelValues(sf.cfg.ServiceType, sf.cfg.InstanceName).Set(1)
+	}
+	sf.report("download", latencies, len(latencies))
+	return total, nil
+}
+
+// downloadFile reads one file completely and returns its size and checksum
+func (sf *smallFilesRun) downloadFile(ctx context.Context, file string) (int64, [sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+	body, err := sf.provider.DownloadFile(ctx, file)
+	if err != nil {
+		return 0, sum, fmt.Errorf("download of %s failed: %w", path.Base(file), err)
+	}
+	defer body.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, body)
+	if err != nil {
+		return n, sum, fmt.Errorf("download of %s failed: %w", path.Base(file), err)
+	}
+	copy(sum[:], h.Sum(nil))
+	return n, sum, nil
+}
+
+// deleteFiles removes every file; unlike the transfer cleanup, deletion is a measured
+// operation of this workload and its failure fails the test
+func (sf *smallFilesRun) deleteFiles(ctx context.Context) (int64, error) {
+	latencies := make([]time.Duration, 0, sf.count)
+	for i, file := range sf.uploaded {
+		if err := ctx.Err(); err != nil {
+			sf.uploaded = sf.uploaded[i:]
+			return 0, err
+		}
+		start := time.Now()
+		if err := sf.provider.DeleteFile(ctx, file); err != nil {
+			sf.uploaded = sf.uploaded[i:]
+			return 0, fmt.Errorf("delete of %s failed: %w", path.Base(file), err)
+		}
+		latencies = append(latencies, time.Since(start))
+	}
+	sf.uploaded = nil
+	sf.report("delete", latencies, len(latencies))
+	return 0, nil
+}
+
+// report exports files per second and latency percentiles of a completed operation
+func (sf *smallFilesRun) report(operation string, latencies []time.Duration, files int) {
+	cfg := sf.cfg
+	sf.phaseFiles = files
+
+	var total time.Duration
+	for _, l := range latencies {
+		total += l
+		SmallFileOperationDuration.WithLabelValues(cfg.ServiceType, cfg.InstanceName, operation).Observe(l.Seconds())
+	}
+	if total > 0 {
+		SmallFilesPerSecond.WithLabelValues(cfg.ServiceType, cfg.InstanceName, operation).Set(float64(files) / total.Seconds())
+	}
+	for _, q := range smallFileQuantiles {
+		SmallFileLatency.WithLabelValues(cfg.ServiceType, cfg.InstanceName, operation,
+			strconv.FormatFloat(q, 'f', -1, 64)).Set(percentile(latencies, q).Seconds())
+	}
+}
+
+// percentile returns the q-th percentile (0 < q <= 1) of the durations using the
+// nearest-rank method
+func percentile(durations []time.Duration, q float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := int(math.Ceil(q*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
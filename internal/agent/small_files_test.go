@@ -0,0 +1,164 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func smallFilesTestConfig(instance string) *Config {
+	cfg := runnerTestConfig(instance)
+	cfg.TestTypes = []string{TestTypeSmallFiles}
+	cfg.SmallFileCount = 20
+	cfg.SmallFileSizeKB = 4
+	return cfg
+}
+
+func TestSmallFilesSuccess(t *testing.T) {
+	cfg := smallFilesTestConfig("small-success")
+	provider := newMemoryProvider()
+
+	result := RunProviderTest(context.Background(), cfg, provider)
+	if !result.Success() {
+		t.Fatalf("expected success, got %v", result.Err)
+	}
+	if result.Phase(PhaseUpload) != nil {
+		t.Error("transfer test must not run when only small_files is enabled")
+	}
+	for _, phase := range []string{PhaseSmallFilesUpload, PhaseSmallFilesList, PhaseSmallFilesDownload, PhaseSmallFilesDelete} {
+		res := result.Phase(phase)
+		if res == nil {
+			t.Fatalf("expected phase %s in result", phase)
+		}
+		if res.Files != 20 {
+			t.Errorf("expected 20 files in phase %s, got %d", phase, res.Files)
+		}
+	}
+	if download := result.Phase(PhaseSmallFilesDownload); download.Bytes != 20*4*1024 {
+		t.Errorf("expected %d bytes downloaded, got %d", 20*4*1024, download.Bytes)
+	}
+	if len(provider.files) != 0 {
+		t.Errorf("expected all small files to be deleted, %d files left", len(provider.files))
+	}
+
+	for _, op := range []string{"upload", "list", "download", "delete"} {
+		if v := testutil.ToFloat64(SmallFilesPerSecond.WithLabelValues(cfg.ServiceType, cfg.InstanceName, op)); v <= 0 {
+			t.Errorf("expected positive files/s for %s, got %v", op, v)
+		}
+		p50 := testutil.ToFloat64(SmallFileLatency.WithLabelValues(cfg.ServiceType, cfg.InstanceName, op, "0.5"))
+		p99 := testutil.ToFloat64(SmallFileLatency.WithLabelValues(cfg.ServiceType, cfg.InstanceName, op, "0.99"))
+		if p99 < p50 {
+			t.Errorf("expected p99 >= p50 for %s, got %v < %v", op, p99, p50)
+		}
+	}
+	if v := testutil.ToFloat64(TestSuccess.WithLabelValues(cfg.ServiceType, cfg.InstanceName, TestTypeSmallFiles, "none")); v != 1 {
+		t.Errorf("expected small_files success metric 1, got %v", v)
+	}
+}
+
+func TestSmallFilesListFailureCleansUp(t *testing.T) {
+	cfg := smallFilesTestConfig("small-list")
+	provider := newMemoryProvider()
+	provider.listErr = errors.New("PROPFIND failed, status: 500 Internal Server Error")
+
+	result := RunProviderTest(context.Background(), cfg, provider)
+	if result.Success() {
+		t.Fatal("expected failure")
+	}
+	if result.ErrorCode != "http_500_server_error" {
+		t.Errorf("expected http_500_server_error, got %s", result.ErrorCode)
+	}
+	if result.Phase(PhaseSmallFilesDownload) != nil {
+		t.Error("download must not run after list failure")
+	}
+	if len(provider.files) != 0 {
+		t.Errorf("expected uploaded files to be removed, %d files left", len(provider.files))
+	}
+	if v := testutil.ToFloat64(TestErrors.WithLabelValues(cfg.ServiceType, cfg.InstanceName, TestTypeSmallFiles, "http_500_server_error")); v != 1 {
+		t.Errorf("expected one small_files error, got %v", v)
+	}
+}
+
+// flakyDeleteProvider fails the first DeleteFile call
+type flakyDeleteProvider struct {
+	*memoryProvider
+	failed bool
+}
+
+func (p *flakyDeleteProvider) DeleteFile(ctx context.Context, filePath string) error {
+	if !p.failed {
+		p.failed = true
+		return errors.New("DELETE failed, status: 500 Internal Server Error")
+	}
+	return p.memoryProvider.DeleteFile(ctx, filePath)
+}
+
+func TestSmallFilesDeleteFailureCleansUp(t *testing.T) {
+	cfg := smallFilesTestConfig("small-delete")
+	provider := &flakyDeleteProvider{memoryProvider: newMemoryProvider()}
+
+	result := RunProviderTest(context.Background(), cfg, provider)
+	if result.Success() {
+		t.Fatal("expected failure")
+	}
+	if phase := result.Phase(PhaseSmallFilesDelete); phase == nil || phase.Err == nil {
+		t.Fatal("expected the delete phase to fail")
+	}
+	if len(provider.files) != 0 {
+		t.Errorf("expected the remaining files to be removed, %d files left", len(provider.files))
+	}
+}
+
+func TestSmallFilesDetectsCorruption(t *testing.T) {
+	cfg := smallFilesTestConfig("small-corrupt")
+	provider := &corruptingProvider{newMemoryProvider()}
+
+	result := RunProviderTest(context.Background(), cfg, provider)
+	if result.ErrorCode != "data_corruption" {
+		t.Errorf("expected data_corruption, got %s", result.ErrorCode)
+	}
+	if len(provider.files) != 0 {
+		t.Errorf("expected uploaded files to be removed, %d files left", len(provider.files))
+	}
+}
+
+func TestTransferAndSmallFilesRunTogether(t *testing.T) {
+	cfg := smallFilesTestConfig("small-both")
+	cfg.TestTypes = []string{TestTypeTransfer, TestTypeSmallFiles}
+
+	result := RunProviderTest(context.Background(), cfg, newMemoryProvider())
+	if !result.Success() {
+		t.Fatalf("expected success, got %v", result.Err)
+	}
+	if result.Phase(PhaseDownload) == nil || result.Phase(PhaseSmallFilesDelete) == nil {
+		t.Errorf("expected phases of both test types, got %+v", result.Phases)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var durations []time.Duration
+	for i := 10; i >= 1; i-- {
+		durations = append(durations, time.Duration(i)*time.Millisecond)
+	}
+
+	tests := []struct {
+		q    float64
+		want time.Duration
+	}{
+		{0.5, 5 * time.Millisecond},
+		{0.9, 9 * time.Millisecond},
+		{0.99, 10 * time.Millisecond},
+		{1, 10 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := percentile(durations, tt.q); got != tt.want {
+			t.Errorf("percentile(%v) = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+	if got := percentile(nil, 0.5); got != 0 {
+		t.Errorf("expected 0 for empty input, got %v", got)
+	}
+}
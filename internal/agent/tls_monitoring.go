@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultCertExpiryWarningDays is the default number of days before certificate expiry
+// at which the certificate_expiring error code is reported
+const DefaultCertExpiryWarningDays = 14
+
+// TLSInfo describes the negotiated TLS connection and the server certificate of a target
+type TLSInfo struct {
+	Version     string
+	CipherSuite string
+	Subject     string
+	Issuer      string
+	NotAfter    time.Time
+}
+
+// newTLSInfo extracts the monitored fields from a completed handshake
+func newTLSInfo(state tls.ConnectionState) *TLSInfo {
+	info := &TLSInfo{
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+	}
+	if len(state.PeerCertificates) > 0 {
+		leaf := state.PeerCertificates[0]
+		info.Subject = leaf.Subject.CommonName
+		info.Issuer = leaf.Issuer.CommonName
+		if info.Issuer == "" {
+			info.Issuer = leaf.Issuer.String()
+		}
+		info.NotAfter = leaf.NotAfter
+	}
+	return info
+}
+
+// verifyPeerCertificates verifies the server certificate chain against roots (the system
+// roots if nil) and the server name, like crypto/tls does without InsecureSkipVerify
+func verifyPeerCertificates(state tls.ConnectionState, roots *x509.CertPool) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("tls: server presented no certificate")
+	}
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		DNSName:       state.ServerName,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range state.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	if _, err := state.PeerCertificates[0].Verify(opts); err != nil {
+		return &tls.CertificateVerificationError{UnverifiedCertificates: state.PeerCertificates, Err: err}
+	}
+	return nil
+}
+
+// DaysRemaining returns the number of days until the certificate expires
+func (i *TLSInfo) DaysRemaining(now time.Time) float64 {
+	return i.NotAfter.Sub(now).Hours() / 24
+}
+
+// GetCertExpiryWarningDays returns the warning threshold from TLS_CERT_EXPIRY_WARNING_DAYS
+func GetCertExpiryWarningDays() int {
+	value := os.Getenv("TLS_CERT_EXPIRY_WARNING_DAYS")
+	if value == "" {
+		return DefaultCertExpiryWarningDays
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 0 {
+		return DefaultCertExpiryWarningDays
+	}
+	return days
+}
+
+// exportTLSInfo updates the certificate metrics of a target
+func exportTLSInfo(serviceType, instanceName, target string, info *TLSInfo) {
+	labels := prometheus.Labels{"service": serviceType, "instance": instanceName, "target": target}
+	// The labels change when the certificate is renewed or the server is reconfigured
+	TLSCertInfo.DeletePartialMatch(labels)
+	TLSCertInfo.WithLabelValues(serviceType, instanceName, target,
+		info.Subject, info.Issuer, info.Version, info.CipherSuite).Set(1)
+	TLSCertExpiry.WithLabelValues(serviceType, instanceName, target).Set(float64(info.NotAfter.Unix()))
+}
+
+// checkCertificateExpiry reports the certificate_expiring error code for an instance if
+// any of its targets presents a certificate that expires within warningDays or has
+// expired. The certificate is known even if the handshake failed on it. It returns
+// whether a certificate is expiring; wasExpiring is the result of the previous check,
+// so that the error is counted once when a certificate starts expiring rather than on
+// every check. Without a certificate to check the previous result is kept.
+func checkCertificateExpiry(cfg *Config, results []*NetworkDiagnostics, warningDays int, now time.Time, wasExpiring bool) bool {
+	const metricType, errorCode = "tls", "certificate_expiring"
+
+	checked, expiring := false, false
+	for _, d := range results {
+		if d.TLS == nil || d.TLS.NotAfter.IsZero() {
+			continue
+		}
+		checked = true
+		days := d.TLS.DaysRemaining(now)
+		switch {
+		case days < 0:
+			expiring = true
+			LogServiceOperation(WARN, cfg.ServiceType, cfg.InstanceName, "tls", "certificate_expiring",
+				fmt.Sprintf("TLS certificate of %s (issuer %s) expired on %s",
+					d.Target, d.TLS.Issuer, d.TLS.NotAfter.Format(time.RFC3339)))
+		case days < float64(warningDays):
+			expiring = true
+			LogServiceOperation(WARN, cfg.ServiceType, cfg.InstanceName, "tls", "certificate_expiring",
+				fmt.Sprintf("TLS certificate of %s (issuer %s) expires in %.0f days on %s",
+					d.Target, d.TLS.Issuer, days, d.TLS.NotAfter.Format(time.RFC3339)))
+		}
+	}
+	if !checked {
+		return wasExpiring
+	}
+
+	if expiring {
+		if !wasExpiring {
+			TestErrors.WithLabelValues(cfg.ServiceType, cfg.InstanceName, metricType, errorCode).Inc()
+		}
+		TestSuccess.WithLabelValues(cfg.ServiceType, cfg.InstanceName, metricType, errorCode).Set(0)
+	} else {
+		TestSuccess.WithLabelValues(cfg.ServiceType, cfg.InstanceName, metricType, errorCode).Set(1)
+	}
+	return expiring
+}
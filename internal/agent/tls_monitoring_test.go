@@ -0,0 +1,169 @@
+package agent
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestDiagnoseCollectsTLSInfo(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	diagnoser := &NetworkDiagnoser{
+		TLSConfig: server.Client().Transport.(*http.Transport).TLSClientConfig,
+	}
+	d := diagnoser.Diagnose(context.Background(), server.URL)
+	if d.Err != nil {
+		t.Fatalf("expected success, got %v", d.Err)
+	}
+	if d.TLS == nil {
+		t.Fatal("expected TLS info for https target")
+	}
+	if d.TLS.Version == "" || d.TLS.CipherSuite == "" || d.TLS.NotAfter.IsZero() {
+		t.Errorf("incomplete TLS info: %+v", d.TLS)
+	}
+
+	exportTLSInfo("nextcloud", "tls-info", d.Target, d.TLS)
+	if v := testutil.ToFloat64(TLSCertExpiry.WithLabelValues("nextcloud", "tls-info", d.Target)); v != float64(d.TLS.NotAfter.Unix()) {
+		t.Errorf("expected expiry timestamp %d, got %v", d.TLS.NotAfter.Unix(), v)
+	}
+}
+
+// newExpiredTLSServer starts a TLS server with a self-signed certificate for 127.0.0.1
+// that expired a day ago and returns it with a pool trusting the certificate
+func newExpiredTLSServer(t *testing.T) (*httptest.Server, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "expired.example.com"},
+		NotBefore:             time.Now().Add(-48 * time.Hour),
+		NotAfter:              time.Now().Add(-24 * time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	return server, roots
+}
+
+func TestDiagnoseReportsExpiredCertificate(t *testing.T) {
+	server, roots := newExpiredTLSServer(t)
+
+	d := (&NetworkDiagnoser{TLSConfig: &tls.Config{RootCAs: roots}}).Diagnose(context.Background(), server.URL)
+	if d.FailedStep != DiagnosticStepTLS {
+		t.Fatalf("expected the expired certificate to fail the tls step, got %q (%v)", d.FailedStep, d.Err)
+	}
+	var invalid x509.CertificateInvalidError
+	if !errors.As(d.Err, &invalid) || invalid.Reason != x509.Expired {
+		t.Errorf("expected an expired certificate error, got %v", d.Err)
+	}
+	if d.TLS == nil || d.TLS.Subject != "expired.example.com" || !d.TLS.NotAfter.Before(time.Now()) {
+		t.Fatalf("expected the expired certificate to be captured, got %+v", d.TLS)
+	}
+
+	cfg := &Config{ServiceType: "nextcloud", InstanceName: "tls-expired"}
+	exportNetworkDiagnostics(cfg.ServiceType, cfg.InstanceName, d)
+	if v := testutil.ToFloat64(TLSCertExpiry.WithLabelValues(cfg.ServiceType, cfg.InstanceName, d.Target)); v != float64(d.TLS.NotAfter.Unix()) {
+		t.Errorf("expected expiry timestamp %d, got %v", d.TLS.NotAfter.Unix(), v)
+	}
+	if !checkCertificateExpiry(cfg, []*NetworkDiagnostics{d}, 14, time.Now(), false) {
+		t.Error("expected the expired certificate to be reported")
+	}
+	if v := testutil.ToFloat64(TestSuccess.WithLabelValues(cfg.ServiceType, cfg.InstanceName, "tls", "certificate_expiring")); v != 0 {
+		t.Errorf("expected tls success metric 0, got %v", v)
+	}
+	if code := ExtractErrorCode(d.Err, "network"); code != "network_tls_error" {
+		t.Errorf("expected network_tls_error, got %s", code)
+	}
+}
+
+func TestCheckCertificateExpiry(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	cfg := &Config{ServiceType: "nextcloud", InstanceName: "tls-expiry"}
+	result := func(days int) []*NetworkDiagnostics {
+		return []*NetworkDiagnostics{{Target: "cloud.example.com", TLS: &TLSInfo{NotAfter: now.AddDate(0, 0, days)}}}
+	}
+
+	if checkCertificateExpiry(cfg, result(30), 14, now, false) {
+		t.Error("certificate valid for 30 days must not be reported with a 14 day threshold")
+	}
+	if v := testutil.ToFloat64(TestSuccess.WithLabelValues(cfg.ServiceType, cfg.InstanceName, "tls", "certificate_expiring")); v != 1 {
+		t.Errorf("expected tls success metric 1, got %v", v)
+	}
+
+	if !checkCertificateExpiry(cfg, result(5), 14, now, false) {
+		t.Error("certificate valid for 5 days must be reported with a 14 day threshold")
+	}
+	if v := testutil.ToFloat64(TestSuccess.WithLabelValues(cfg.ServiceType, cfg.InstanceName, "tls", "certificate_expiring")); v != 0 {
+		t.Errorf("expected tls success metric 0, got %v", v)
+	}
+	if v := testutil.ToFloat64(TestErrors.WithLabelValues(cfg.ServiceType, cfg.InstanceName, "tls", "certificate_expiring")); v != 1 {
+		t.Errorf("expected one certificate_expiring error, got %v", v)
+	}
+
+	// Plain HTTP targets have no certificate to check
+	if checkCertificateExpiry(cfg, []*NetworkDiagnostics{{Target: "cloud.example.com"}}, 14, now, false) {
+		t.Error("targets without TLS must not be reported")
+	}
+}
+
+func TestCheckCertificateExpiryCountsOnce(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	cfg := &Config{ServiceType: "nextcloud", InstanceName: "tls-expiry-once"}
+	results := []*NetworkDiagnostics{{Target: "cloud.example.com", TLS: &TLSInfo{NotAfter: now.AddDate(0, 0, 5)}}}
+
+	expiring := checkCertificateExpiry(cfg, results, 14, now, false)
+	expiring = checkCertificateExpiry(cfg, results, 14, now.Add(DiagnosticsInterval), expiring)
+	if !expiring {
+		t.Error("expected the certificate to be reported as expiring")
+	}
+	if v := testutil.ToFloat64(TestErrors.WithLabelValues(cfg.ServiceType, cfg.InstanceName, "tls", "certificate_expiring")); v != 1 {
+		t.Errorf("expected one certificate_expiring error for two checks, got %v", v)
+	}
+}
+
+func TestGetCertExpiryWarningDays(t *testing.T) {
+	t.Setenv("TLS_CERT_EXPIRY_WARNING_DAYS", "30")
+	if got := GetCertExpiryWarningDays(); got != 30 {
+		t.Errorf("expected 30, got %d", got)
+	}
+	t.Setenv("TLS_CERT_EXPIRY_WARNING_DAYS", "invalid")
+	if got := GetCertExpiryWarningDays(); got != DefaultCertExpiryWarningDays {
+		t.Errorf("expected default for invalid value, got %d", got)
+	}
+}
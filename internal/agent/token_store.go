@@ -0,0 +1,232 @@
+package agent
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/xXRoxXeRXx/cloud-performance-monitor/internal/utils"
+)
+
+// storedToken is the persisted token of one instance
+type storedToken struct {
+	utils.OAuthToken
+	// Seed is the hash of the configured refresh token the token chain started from.
+	// Configuring a new refresh token discards the stored tokens.
+	Seed string `json:"seed"`
+}
+
+// TokenStore keeps the OAuth tokens of the instances so that valid access tokens are
+// reused across test runs and rotated refresh tokens are not lost. With a path the
+// tokens are also written to a file encrypted with AES-256-GCM, so that they survive
+// restarts.
+type TokenStore struct {
+	path string
+	aead cipher.AEAD
+
+	mu     sync.Mutex
+	tokens map[string]storedToken
+}
+
+// TokenStoreFilePath returns the token file from TOKEN_STORE_FILE, empty for memory only
+func TokenStoreFilePath() string {
+	return os.Getenv("TOKEN_STORE_FILE")
+}
+
+// TokenStoreKey returns the passphrase the token file is encrypted with from TOKEN_STORE_KEY
+func TokenStoreKey() string {
+	return os.Getenv("TOKEN_STORE_KEY")
+}
+
+// NewTokenStore opens the encrypted token file at path, loading the stored tokens.
+// An empty path keeps the tokens in memory only; a file requires a key.
+func NewTokenStore(path, key string) (*TokenStore, error) {
+	s := &TokenStore{path: path, tokens: make(map[string]storedToken)}
+	if path == "" {
+		return s, nil
+	}
+	if key == "" {
+		return nil, errors.New("TOKEN_STORE_KEY is required to encrypt the token store")
+	}
+
+	// AES-256 key derived from the passphrase
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, fmt.Errorf("could not create token store cipher: %w", err)
+	}
+	if s.aead, err = cipher.NewGCM(block); err != nil {
+		return nil, fmt.Errorf("could not create token store cipher: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("could not create token store directory: %w", err)
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load decrypts the token file; a missing file is an empty store
+func (s *TokenStore) load() error {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not read token store: %w", err)
+	}
+
+	nonceSize := s.aead.NonceSize()
+	if len(data) < nonceSize {
+		return errors.New("could not read token store: file is truncated")
+	}
+	plain, err := s.aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+	if err != nil {
+		return fmt.Errorf("could not decrypt token store, is TOKEN_STORE_KEY correct? %w", err)
+	}
+	if err := json.Unmarshal(plain, &s.tokens); err != nil {
+		return fmt.Errorf("could not parse token store: %w", err)
+	}
+	return nil
+}
+
+// save encrypts all tokens and replaces the token file; the caller must hold mu
+func (s *TokenStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+	plain, err := json.Marshal(s.tokens)
+	if err != nil {
+		return fmt.Errorf("could not encode token store: %w", err)
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return fmt.Errorf("could not create nonce: %w", err)
+	}
+	data := s.aead.Seal(nonce, nonce, plain, nil)
+
+	// Write to a temporary file first so that a crash cannot leave a half-written store
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("could not write token store: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("could not replace token store: %w", err)
+	}
+	return nil
+}
+
+// Get returns the stored token of an instance. Tokens stored for a different configured
+// refresh token are ignored.
+func (s *TokenStore) Get(cfg *Config) (utils.OAuthToken, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.tokens[tokenStoreKey(cfg)]
+	if !ok || stored.Seed != tokenSeed(cfg.RefreshToken) {
+		return utils.OAuthToken{}, false
+	}
+	return stored.OAuthToken, true
+}
+
+// Put stores the token of an instance
+func (s *TokenStore) Put(cfg *Config, token utils.OAuthToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens[tokenStoreKey(cfg)] = storedToken{OAuthToken: token, Seed: tokenSeed(cfg.RefreshToken)}
+	return s.save()
+}
+
+// tokenStoreKey identifies an instance in the token store
+func tokenStoreKey(cfg *Config) string {
+	return cfg.ServiceType + "/" + cfg.InstanceName
+}
+
+// tokenSeed hashes the configured refresh token so that it is not stored in clear
+func tokenSeed(refreshToken string) string {
+	sum := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(sum[:])
+}
+
+var (
+	tokenStoreMu sync.RWMutex
+	tokenStore   = &TokenStore{tokens: make(map[string]storedToken)}
+)
+
+// SetTokenStore sets the token store used by the OAuth2 providers. Without a call the
+// tokens are kept in memory only.
+func SetTokenStore(store *TokenStore) {
+	tokenStoreMu.Lock()
+	defer tokenStoreMu.Unlock()
+	tokenStore = store
+}
+
+// currentTokenStore returns the token store used by the OAuth2 providers
+func currentTokenStore() *TokenStore {
+	tokenStoreMu.RLock()
+	defer tokenStoreMu.RUnlock()
+	return tokenStore
+}
+
+// oauthClient is implemented by the clients of the OAuth2 providers
+type oauthClient interface {
+	SetToken(token utils.OAuthToken)
+	EnsureToken(ctx context.Context) error
+}
+
+// tokenRecorder persists the refreshed tokens of an instance and exports the token
+// metrics; it implements utils.TokenObserver
+type tokenRecorder struct {
+	cfg   *Config
+	store *TokenStore
+}
+
+func (t *tokenRecorder) TokenRefreshed(token utils.OAuthToken) {
+	OAuthTokenRefreshes.WithLabelValues(t.cfg.ServiceType, t.cfg.InstanceName).Inc()
+	t.recordExpiry(token)
+	if err := t.store.Put(t.cfg, token); err != nil {
+		LogServiceOperation(WARN, t.cfg.ServiceType, t.cfg.InstanceName, "auth", "token_store",
+			"Could not persist refreshed OAuth token",
+			WithError(err))
+	}
+}
+
+func (t *tokenRecorder) TokenRefreshFailed(err error) {
+	OAuthTokenRefreshFailures.WithLabelValues(t.cfg.ServiceType, t.cfg.InstanceName).Inc()
+}
+
+// recordExpiry exports the expiry of the instance's access token, if known
+func (t *tokenRecorder) recordExpiry(token utils.OAuthToken) {
+	if !token.Expiry.IsZero() {
+		OAuthTokenExpiry.WithLabelValues(t.cfg.ServiceType, t.cfg.InstanceName).Set(float64(token.Expiry.Unix()))
+	}
+}
+
+// initOAuthToken restores the instance's token from the token store and refreshes the
+// access token only if it is missing or about to expire. recorder must already be set as
+// the client's token observer so that a refreshed token is stored.
+func initOAuthToken(ctx context.Context, cfg *Config, client oauthClient, recorder *tokenRecorder) error {
+	if token, ok := recorder.store.Get(cfg); ok {
+		client.SetToken(token)
+		recorder.recordExpiry(token)
+		if !token.NeedsRefresh(time.Now(), utils.TokenRefreshMargin) {
+			LogServiceOperation(DEBUG, cfg.ServiceType, cfg.InstanceName, "auth", "token_reused",
+				"Reusing stored OAuth2 access token")
+		}
+	}
+	return client.EnsureToken(ctx)
+}
@@ -0,0 +1,123 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/xXRoxXeRXx/cloud-performance-monitor/internal/utils"
+)
+
+func tokenTestConfig(instance string) *Config {
+	return &Config{ServiceType: "dropbox", InstanceName: instance, RefreshToken: "configured-refresh"}
+}
+
+func TestTokenStorePersistsEncrypted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.enc")
+	store, err := NewTokenStore(path, "secret")
+	if err != nil {
+		t.Fatalf("NewTokenStore failed: %v", err)
+	}
+	cfg := tokenTestConfig("persist")
+	token := utils.OAuthToken{AccessToken: "access-1", RefreshToken: "rotated-refresh", Expiry: time.Now().Add(time.Hour).Round(time.Second)}
+	if err := store.Put(cfg, token); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if bytes.Contains(data, []byte("rotated-refresh")) {
+		t.Error("expected the token file to be encrypted")
+	}
+
+	reopened, err := NewTokenStore(path, "secret")
+	if err != nil {
+		t.Fatalf("reopening failed: %v", err)
+	}
+	got, ok := reopened.Get(cfg)
+	if !ok || got.RefreshToken != token.RefreshToken || !got.Expiry.Equal(token.Expiry) {
+		t.Errorf("expected %+v after restart, got %+v (found %v)", token, got, ok)
+	}
+
+	if _, err := NewTokenStore(path, "wrong"); err == nil {
+		t.Error("expected an error for the wrong key")
+	}
+	if _, err := NewTokenStore(path, ""); err == nil {
+		t.Error("expected an error for a token file without key")
+	}
+}
+
+func TestTokenStoreIgnoresTokensOfOldRefreshToken(t *testing.T) {
+	store, _ := NewTokenStore("", "")
+	cfg := tokenTestConfig("reconfigured")
+	_ = store.Put(cfg, utils.OAuthToken{AccessToken: "access", RefreshToken: "rotated"})
+
+	cfg.RefreshToken = "new-configured-refresh"
+	if _, ok := store.Get(cfg); ok {
+		t.Error("expected stored tokens to be discarded for a new configured refresh token")
+	}
+}
+
+// fakeOAuthClient records refreshes instead of calling a provider
+type fakeOAuthClient struct {
+	token     utils.OAuthToken
+	refreshes int
+	observer  utils.TokenObserver
+}
+
+func (f *fakeOAuthClient) SetToken(token utils.OAuthToken) { f.token = token }
+
+func (f *fakeOAuthClient) EnsureToken(ctx context.Context) error {
+	if !f.token.NeedsRefresh(time.Now(), utils.TokenRefreshMargin) {
+		return nil
+	}
+	f.refreshes++
+	f.token = utils.OAuthToken{AccessToken: "refreshed", RefreshToken: "rotated", Expiry: time.Now().Add(4 * time.Hour)}
+	f.observer.TokenRefreshed(f.token)
+	return nil
+}
+
+func TestInitOAuthToken(t *testing.T) {
+	tests := []struct {
+		name      string
+		stored    *utils.OAuthToken
+		refreshes int
+	}{
+		{name: "no stored token", refreshes: 1},
+		{name: "valid stored token", stored: &utils.OAuthToken{AccessToken: "stored", RefreshToken: "r", Expiry: time.Now().Add(time.Hour)}},
+		{name: "expiring stored token", stored: &utils.OAuthToken{AccessToken: "stored", RefreshToken: "r", Expiry: time.Now().Add(time.Minute)}, refreshes: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, _ := NewTokenStore("", "")
+			cfg := tokenTestConfig("init-" + tt.name)
+			if tt.stored != nil {
+				_ = store.Put(cfg, *tt.stored)
+			}
+			recorder := &tokenRecorder{cfg: cfg, store: store}
+			client := &fakeOAuthClient{observer: recorder}
+
+			if err := initOAuthToken(context.Background(), cfg, client, recorder); err != nil {
+				t.Fatalf("initOAuthToken failed: %v", err)
+			}
+			if client.refreshes != tt.refreshes {
+				t.Errorf("expected %d refreshes, got %d", tt.refreshes, client.refreshes)
+			}
+			if refreshes := testutil.ToFloat64(OAuthTokenRefreshes.WithLabelValues(cfg.ServiceType, cfg.InstanceName)); refreshes != float64(tt.refreshes) {
+				t.Errorf("expected refresh metric %d, got %v", tt.refreshes, refreshes)
+			}
+			stored, _ := store.Get(cfg)
+			if stored != client.token {
+				t.Errorf("expected the client token to be stored, got %+v", stored)
+			}
+			if expiry := testutil.ToFloat64(OAuthTokenExpiry.WithLabelValues(cfg.ServiceType, cfg.InstanceName)); expiry != float64(client.token.Expiry.Unix()) {
+				t.Errorf("expected expiry metric %d, got %v", client.token.Expiry.Unix(), expiry)
+			}
+		})
+	}
+}
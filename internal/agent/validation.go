@@ -58,7 +58,7 @@ func validateURL(rawURL string) error {
 
 // validateServiceType validates the service type
 func validateServiceType(serviceType string) error {
-	validTypes := []string{"nextcloud", "hidrive"}
+	validTypes := ProviderTypes()
 	
 	for _, valid := range validTypes {
 		if serviceType == valid {
@@ -119,15 +119,11 @@ func TestCredentials(ctx context.Context, cfg *Config) error {
 		Timeout: 10 * time.Second,
 	}
 	
-	var testURL string
-	switch cfg.ServiceType {
-	case "nextcloud":
-		testURL = cfg.URL + "/remote.php/dav/files/" + cfg.Username + "/"
-	case "hidrive":
-		testURL = cfg.URL + "/remote.php/dav/files/" + cfg.Username + "/"
-	default:
+	def, ok := GetProvider(cfg.ServiceType)
+	if !ok || def.CredentialURL == nil {
 		return fmt.Errorf("unsupported service type for credential test: %s", cfg.ServiceType)
 	}
+	testURL := def.CredentialURL(cfg)
 	
 	req, err := http.NewRequestWithContext(ctx, "PROPFIND", testURL, nil)
 	if err != nil {
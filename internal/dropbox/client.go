@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 	"sync"
 	"time"
 
@@ -21,7 +22,11 @@ type Client struct {
 	AppKey       string
 	AppSecret    string
 	HTTPClient   *http.Client
-	tokenMutex   sync.RWMutex
+	// TokenExpiry is when AccessToken expires, zero if unknown
+	TokenExpiry time.Time
+	// TokenObserver is notified about token refreshes, nil if not needed
+	TokenObserver utils.TokenObserver
+	tokenMutex    sync.RWMutex
 	logger       utils.ClientLogger
 }
 
@@ -84,6 +89,13 @@ type FileMetadata struct {
 	PathDisplay    string    `json:"path_display"`
 }
 
+// ListFolderResult represents the response of list_folder and list_folder/continue
+type ListFolderResult struct {
+	Entries []FileMetadata `json:"entries"`
+	Cursor  string         `json:"cursor"`
+	HasMore bool           `json:"has_more"`
+}
+
 // ErrorResponse represents an error response from Dropbox API
 type ErrorResponse struct {
 	ErrorSummary string `json:"error_summary"`
@@ -92,6 +104,20 @@ type ErrorResponse struct {
 	} `json:"error"`
 }
 
+// parseErrorTag returns the error tag of a Dropbox API error body, e.g. "path/not_found"
+// for the summary "path/not_found/..."
+func parseErrorTag(body string) string {
+	var errResp ErrorResponse
+	if err := json.Unmarshal([]byte(body), &errResp); err != nil {
+		return ""
+	}
+	// The summary is the tag path followed by a random suffix starting with "/."
+	if i := strings.Index(errResp.ErrorSummary, "/."); i > 0 {
+		return errResp.ErrorSummary[:i]
+	}
+	return errResp.Error.Tag
+}
+
 // NewClient creates a new Dropbox API client with OAuth2 refresh capability
 func NewClient(accessToken, refreshToken, appKey, appSecret string, logger utils.ClientLogger) *Client {
 	t := http.DefaultTransport.(*http.Transport).Clone()
@@ -118,27 +144,24 @@ func NewClientWithOAuth2(accessToken, refreshToken, appKey, appSecret string, lo
 }
 
 // RefreshAccessToken refreshes the access token using the refresh token with retry logic
-func (c *Client) RefreshAccessToken() error {
+func (c *Client) RefreshAccessToken(ctx context.Context) error {
+	c.tokenMutex.Lock()
 	if c.RefreshToken == "" || c.AppKey == "" || c.AppSecret == "" {
+		c.tokenMutex.Unlock()
 		return fmt.Errorf("refresh token, app key, or app secret not available")
 	}
 
-	c.tokenMutex.Lock()
-	defer c.tokenMutex.Unlock()
-
 	retryConfig := utils.DefaultRetryConfig()
 	retryConfig.MaxRetries = 2 // Fewer retries for OAuth2 operations
-	retryConfig.RetryableErrors = append(retryConfig.RetryableErrors, 
-		"connection refused", "timeout", "temporary failure", "502", "503", "504")
 
-	return retryConfig.WithRetry(context.Background(), "dropbox_oauth_refresh", func(ctx context.Context) error {
+	err := retryConfig.WithRetry(ctx, "dropbox_oauth_refresh", func(ctx context.Context) error {
 		data := url.Values{}
 		data.Set("grant_type", "refresh_token")
 		data.Set("refresh_token", c.RefreshToken)
 
 		req, err := http.NewRequestWithContext(ctx, "POST", DropboxOAuthURL, bytes.NewBufferString(data.Encode()))
 		if err != nil {
-			return fmt.Errorf("failed to create refresh request: %v", err)
+			return fmt.Errorf("failed to create refresh request: %w", err)
 		}
 
 		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
@@ -149,25 +172,26 @@ func (c *Client) RefreshAccessToken() error {
 			c.logger.LogOperation(utils.ERROR, "dropbox", "oauth", "token", "refresh_request_error", 
 				fmt.Sprintf("Refresh request failed: %v", err), 
 				map[string]interface{}{"error": err.Error()})
-			return fmt.Errorf("refresh request failed: %v", err)
+			return c.wrapError("token_refresh", "", err)
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
+			err := c.statusError("token_refresh", "", resp)
 			c.logger.LogOperation(utils.ERROR, "dropbox", "oauth", "token", "refresh_status_error", 
-				fmt.Sprintf("Refresh failed with status %d: %s", resp.StatusCode, string(body)), 
-				map[string]interface{}{"status_code": resp.StatusCode, "response_body": string(body)})
-			return fmt.Errorf("refresh failed with status %d: %s", resp.StatusCode, string(body))
+				fmt.Sprintf("Refresh failed: %v", err), 
+				map[string]interface{}{"status_code": resp.StatusCode, "error": err.Error()})
+			return err
 		}
 
 		var tokenResp OAuth2TokenResponse
 		if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
-			return fmt.Errorf("failed to decode refresh response: %v", err)
+			return fmt.Errorf("failed to decode refresh response: %w", err)
 		}
 
 		// Update access token
 		c.AccessToken = tokenResp.AccessToken
+		c.TokenExpiry = utils.TokenExpiry(time.Now(), tokenResp.ExpiresIn)
 		
 		// Update refresh token if provided (some providers rotate refresh tokens)
 		if tokenResp.RefreshToken != "" {
@@ -179,16 +203,62 @@ func (c *Client) RefreshAccessToken() error {
 			map[string]interface{}{"expires_in": tokenResp.ExpiresIn})
 		return nil
 	})
+	token := c.token()
+	c.tokenMutex.Unlock()
+
+	// The observer persists the token, requests must not wait for that
+	if c.TokenObserver != nil {
+		if err != nil {
+			c.TokenObserver.TokenRefreshFailed(err)
+		} else {
+			c.TokenObserver.TokenRefreshed(token)
+		}
+	}
+	return err
+}
+
+// Token returns the current OAuth token
+func (c *Client) Token() utils.OAuthToken {
+	c.tokenMutex.RLock()
+	defer c.tokenMutex.RUnlock()
+	return c.token()
+}
+
+// token returns the current OAuth token; the caller must hold tokenMutex
+func (c *Client) token() utils.OAuthToken {
+	return utils.OAuthToken{AccessToken: c.AccessToken, RefreshToken: c.RefreshToken, Expiry: c.TokenExpiry}
+}
+
+// SetToken replaces the tokens of the client, e.g. with a token restored from a token store
+func (c *Client) SetToken(token utils.OAuthToken) {
+	c.tokenMutex.Lock()
+	defer c.tokenMutex.Unlock()
+	c.AccessToken = token.AccessToken
+	c.RefreshToken = token.RefreshToken
+	c.TokenExpiry = token.Expiry
+}
+
+// EnsureToken refreshes the access token ahead of time if it is missing or expires within
+// utils.TokenRefreshMargin. Clients without a refresh token are left unchanged.
+func (c *Client) EnsureToken(ctx context.Context) error {
+	token := c.Token()
+	if token.RefreshToken == "" || !token.NeedsRefresh(time.Now(), utils.TokenRefreshMargin) {
+		return nil
+	}
+	return c.RefreshAccessToken(ctx)
 }
 
 // newAPIRequest creates a new authenticated API request
-func (c *Client) newAPIRequest(method, endpoint string, body io.Reader) (*http.Request, error) {
+func (c *Client) newAPIRequest(ctx context.Context, method, endpoint string, body io.Reader) (*http.Request, error) {
+	if err := c.EnsureToken(ctx); err != nil {
+		return nil, err
+	}
 	c.tokenMutex.RLock()
 	token := c.AccessToken
 	c.tokenMutex.RUnlock()
 
 	fullURL := DropboxAPIURL + endpoint
-	req, err := http.NewRequest(method, fullURL, body)
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, body)
 	if err != nil {
 		return nil, err
 	}
@@ -197,6 +267,22 @@ func (c *Client) newAPIRequest(method, endpoint string, body io.Reader) (*http.R
 	return req, nil
 }
 
+// statusError returns the typed error of a response with an unexpected status
+func (c *Client) statusError(operation, phase string, resp *http.Response) error {
+	return utils.NewStatusError("dropbox", operation, phase, resp, parseErrorTag)
+}
+
+// wrapError annotates a request error with the operation it occurred in
+func (c *Client) wrapError(operation, phase string, err error) error {
+	return utils.WrapProviderError("dropbox", operation, phase, err)
+}
+
+// CloseIdleConnections closes the kept-alive connections of the client, so that the next
+// request has to connect again
+func (c *Client) CloseIdleConnections() {
+	c.HTTPClient.CloseIdleConnections()
+}
+
 // doRequestWithRetry performs an HTTP request with automatic token refresh on 401 errors
 func (c *Client) doRequestWithRetry(req *http.Request) (*http.Response, error) {
 	// Make the initial request
@@ -217,8 +303,8 @@ func (c *Client) doRequestWithRetry(req *http.Request) (*http.Response, error) {
 	c.logger.LogOperation(utils.INFO, "dropbox", "oauth", "token", "refresh_attempt", 
 		"Access token expired, attempting refresh...", 
 		map[string]interface{}{})
-	if err := c.RefreshAccessToken(); err != nil {
-		return nil, fmt.Errorf("failed to refresh access token: %v", err)
+	if err := c.RefreshAccessToken(req.Context()); err != nil {
+		return nil, fmt.Errorf("failed to refresh access token: %w", err)
 	}
 
 	// Update authorization header with new token
@@ -234,13 +320,16 @@ func (c *Client) doRequestWithRetry(req *http.Request) (*http.Response, error) {
 }
 
 // newContentRequest creates a new authenticated content request
-func (c *Client) newContentRequest(method, endpoint string, body io.Reader) (*http.Request, error) {
+func (c *Client) newContentRequest(ctx context.Context, method, endpoint string, body io.Reader) (*http.Request, error) {
+	if err := c.EnsureToken(ctx); err != nil {
+		return nil, err
+	}
 	c.tokenMutex.RLock()
 	token := c.AccessToken
 	c.tokenMutex.RUnlock()
 
 	fullURL := DropboxContentURL + endpoint
-	req, err := http.NewRequest(method, fullURL, body)
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, body)
 	if err != nil {
 		return nil, err
 	}
@@ -249,7 +338,7 @@ func (c *Client) newContentRequest(method, endpoint string, body io.Reader) (*ht
 }
 
 // EnsureDirectory ensures the test directory exists (Dropbox creates folders automatically)
-func (c *Client) EnsureDirectory(dirPath string) error {
+func (c *Client) EnsureDirectory(ctx context.Context, dirPath string) error {
 	// Dropbox creates directories automatically when uploading files
 	// So we just need to validate the path format
 	if dirPath == "" {
@@ -265,19 +354,19 @@ func (c *Client) EnsureDirectory(dirPath string) error {
 }
 
 // UploadFile uploads a file using chunked upload for large files or simple upload for small files
-func (c *Client) UploadFile(filePath string, reader io.Reader, size int64, chunkSize int64) error {
+func (c *Client) UploadFile(ctx context.Context, filePath string, reader io.Reader, size int64, chunkSize int64) error {
 	c.logger.LogOperation(utils.INFO, "dropbox", "api", "upload", "start", 
 		fmt.Sprintf("Starting upload for %s (%d bytes)", filePath, size), 
 		map[string]interface{}{"file_path": filePath, "file_size": size, "chunk_size": chunkSize})
 		
 	if size <= DropboxMaxChunkSize {
-		return c.uploadSimple(filePath, reader)
+		return c.uploadSimple(ctx, filePath, reader)
 	}
-	return c.uploadChunked(filePath, reader, size, chunkSize)
+	return c.uploadChunked(ctx, filePath, reader, size, chunkSize)
 }
 
 // uploadSimple uploads a file in a single request
-func (c *Client) uploadSimple(filePath string, reader io.Reader) error {
+func (c *Client) uploadSimple(ctx context.Context, filePath string, reader io.Reader) error {
 	// Create the API args
 	args := map[string]interface{}{
 		"path":       filePath,
@@ -286,13 +375,14 @@ func (c *Client) uploadSimple(filePath string, reader io.Reader) error {
 	}
 	argsJSON, err := json.Marshal(args)
 	if err != nil {
-		return fmt.Errorf("failed to marshal upload args: %v", err)
+		return fmt.Errorf("failed to marshal upload args: %w", err)
 	}
 
-	req, err := c.newContentRequest("POST", "/files/upload", reader)
+	req, err := c.newContentRequest(ctx, "POST", "/files/upload", reader)
 	if err != nil {
-		return fmt.Errorf("failed to create upload request: %v", err)
+		return fmt.Errorf("failed to create upload request: %w", err)
 	}
+	req = utils.WithHTTPOperation(req, utils.HTTPOperationUpload)
 	req.Header.Set("Dropbox-API-Arg", string(argsJSON))
 	req.Header.Set("Content-Type", "application/octet-stream")
 
@@ -301,16 +391,16 @@ func (c *Client) uploadSimple(filePath string, reader io.Reader) error {
 		c.logger.LogOperation(utils.ERROR, "dropbox", "api", "upload", "request_error", 
 			fmt.Sprintf("Upload request failed: %v", err), 
 			map[string]interface{}{"file_path": filePath, "error": err.Error()})
-		return fmt.Errorf("upload request failed: %v", err)
+		return c.wrapError("upload", "", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
+		err := c.statusError("upload", "", resp)
 		c.logger.LogOperation(utils.ERROR, "dropbox", "api", "upload", "status_error", 
-			fmt.Sprintf("Upload failed with status %d: %s", resp.StatusCode, string(body)), 
-			map[string]interface{}{"file_path": filePath, "status_code": resp.StatusCode, "response_body": string(body)})
-		return fmt.Errorf("upload failed with status %d: %s", resp.StatusCode, string(body))
+			fmt.Sprintf("Upload failed: %v", err), 
+			map[string]interface{}{"file_path": filePath, "status_code": resp.StatusCode, "error": err.Error()})
+		return err
 	}
 
 	c.logger.LogOperation(utils.INFO, "dropbox", "api", "upload", "simple_completed", 
@@ -320,11 +410,11 @@ func (c *Client) uploadSimple(filePath string, reader io.Reader) error {
 }
 
 // uploadChunked uploads a file using chunked upload
-func (c *Client) uploadChunked(filePath string, reader io.Reader, size int64, chunkSize int64) error {
+func (c *Client) uploadChunked(ctx context.Context, filePath string, reader io.Reader, size int64, chunkSize int64) error {
 	// Start upload session
-	sessionID, err := c.startUploadSession()
+	sessionID, err := c.startUploadSession(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to start upload session: %v", err)
+		return fmt.Errorf("failed to start upload session: %w", err)
 	}
 
 	c.logger.LogOperation(utils.INFO, "dropbox", "api", "upload", "session_started", 
@@ -353,10 +443,10 @@ func (c *Client) uploadChunked(filePath string, reader io.Reader, size int64, ch
 
 		if isLast {
 			// Finish the upload session
-			err = c.finishUploadSession(sessionID, offset, bytes.NewReader(chunk), filePath)
+			err = c.finishUploadSession(ctx, sessionID, offset, bytes.NewReader(chunk), filePath)
 		} else {
 			// Append chunk to session
-			err = c.appendUploadSession(sessionID, offset, bytes.NewReader(chunk))
+			err = c.appendUploadSession(ctx, sessionID, offset, bytes.NewReader(chunk))
 		}
 
 		if err != nil {
@@ -385,8 +475,8 @@ func (c *Client) uploadChunked(filePath string, reader io.Reader, size int64, ch
 }
 
 // startUploadSession starts a new upload session
-func (c *Client) startUploadSession() (string, error) {
-	req, err := c.newContentRequest("POST", "/files/upload_session/start", bytes.NewReader([]byte{}))
+func (c *Client) startUploadSession(ctx context.Context) (string, error) {
+	req, err := c.newContentRequest(ctx, "POST", "/files/upload_session/start", bytes.NewReader([]byte{}))
 	if err != nil {
 		return "", err
 	}
@@ -394,25 +484,24 @@ func (c *Client) startUploadSession() (string, error) {
 
 	resp, err := c.doRequestWithRetry(req)
 	if err != nil {
-		return "", err
+		return "", c.wrapError("upload", "start_session", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("start session failed with status %d: %s", resp.StatusCode, string(body))
+		return "", c.statusError("upload", "start_session", resp)
 	}
 
 	var result UploadSessionStartResult
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("failed to decode session start response: %v", err)
+		return "", fmt.Errorf("failed to decode session start response: %w", err)
 	}
 
 	return result.SessionID, nil
 }
 
 // appendUploadSession appends data to an existing upload session
-func (c *Client) appendUploadSession(sessionID string, offset uint64, data io.Reader) error {
+func (c *Client) appendUploadSession(ctx context.Context, sessionID string, offset uint64, data io.Reader) error {
 	args := UploadSessionAppendV2Args{
 		Close: false,
 	}
@@ -424,29 +513,30 @@ func (c *Client) appendUploadSession(sessionID string, offset uint64, data io.Re
 		return err
 	}
 
-	req, err := c.newContentRequest("POST", "/files/upload_session/append_v2", data)
+	req, err := c.newContentRequest(ctx, "POST", "/files/upload_session/append_v2", data)
 	if err != nil {
 		return err
 	}
+	// The cursor carries an explicit offset, so sending the chunk again is safe
+	req = utils.WithIdempotent(utils.WithHTTPOperation(req, utils.HTTPOperationChunk))
 	req.Header.Set("Dropbox-API-Arg", string(argsJSON))
 	req.Header.Set("Content-Type", "application/octet-stream")
 
 	resp, err := c.doRequestWithRetry(req)
 	if err != nil {
-		return err
+		return c.wrapError("upload", "chunk", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("append session failed with status %d: %s", resp.StatusCode, string(body))
+		return c.statusError("upload", "chunk", resp)
 	}
 
 	return nil
 }
 
 // finishUploadSession finishes an upload session
-func (c *Client) finishUploadSession(sessionID string, offset uint64, data io.Reader, filePath string) error {
+func (c *Client) finishUploadSession(ctx context.Context, sessionID string, offset uint64, data io.Reader, filePath string) error {
 	args := UploadSessionFinishArgs{}
 	args.Cursor.SessionID = sessionID
 	args.Cursor.Offset = offset
@@ -459,52 +549,53 @@ func (c *Client) finishUploadSession(sessionID string, offset uint64, data io.Re
 		return err
 	}
 
-	req, err := c.newContentRequest("POST", "/files/upload_session/finish", data)
+	req, err := c.newContentRequest(ctx, "POST", "/files/upload_session/finish", data)
 	if err != nil {
 		return err
 	}
+	req = utils.WithHTTPOperation(req, utils.HTTPOperationChunk)
 	req.Header.Set("Dropbox-API-Arg", string(argsJSON))
 	req.Header.Set("Content-Type", "application/octet-stream")
 
 	resp, err := c.doRequestWithRetry(req)
 	if err != nil {
-		return err
+		return c.wrapError("upload", "finish_session", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("finish session failed with status %d: %s", resp.StatusCode, string(body))
+		return c.statusError("upload", "finish_session", resp)
 	}
 
 	return nil
 }
 
 // DownloadFile downloads a file from Dropbox
-func (c *Client) DownloadFile(filePath string) (io.ReadCloser, error) {
+func (c *Client) DownloadFile(ctx context.Context, filePath string) (io.ReadCloser, error) {
 	args := map[string]string{
 		"path": filePath,
 	}
 	argsJSON, err := json.Marshal(args)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal download args: %v", err)
+		return nil, fmt.Errorf("failed to marshal download args: %w", err)
 	}
 
-	req, err := c.newContentRequest("POST", "/files/download", nil)
+	req, err := c.newContentRequest(ctx, "POST", "/files/download", nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create download request: %v", err)
+		return nil, fmt.Errorf("failed to create download request: %w", err)
 	}
+	// Download is a read despite being a POST
+	req = utils.WithIdempotent(utils.WithHTTPOperation(req, utils.HTTPOperationDownload))
 	req.Header.Set("Dropbox-API-Arg", string(argsJSON))
 
 	resp, err := c.doRequestWithRetry(req)
 	if err != nil {
-		return nil, fmt.Errorf("download request failed: %v", err)
+		return nil, c.wrapError("download", "", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		defer resp.Body.Close()
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("download failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, c.statusError("download", "", resp)
 	}
 
 	c.logger.LogOperation(utils.INFO, "dropbox", "api", "download", "started", 
@@ -514,29 +605,28 @@ func (c *Client) DownloadFile(filePath string) (io.ReadCloser, error) {
 }
 
 // DeleteFile deletes a file from Dropbox
-func (c *Client) DeleteFile(filePath string) error {
+func (c *Client) DeleteFile(ctx context.Context, filePath string) error {
 	args := map[string]string{
 		"path": filePath,
 	}
 	argsJSON, err := json.Marshal(args)
 	if err != nil {
-		return fmt.Errorf("failed to marshal delete args: %v", err)
+		return fmt.Errorf("failed to marshal delete args: %w", err)
 	}
 
-	req, err := c.newAPIRequest("POST", "/files/delete_v2", bytes.NewReader(argsJSON))
+	req, err := c.newAPIRequest(ctx, "POST", "/files/delete_v2", bytes.NewReader(argsJSON))
 	if err != nil {
-		return fmt.Errorf("failed to create delete request: %v", err)
+		return fmt.Errorf("failed to create delete request: %w", err)
 	}
 
 	resp, err := c.doRequestWithRetry(req)
 	if err != nil {
-		return fmt.Errorf("delete request failed: %v", err)
+		return c.wrapError("delete", "", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("delete failed with status %d: %s", resp.StatusCode, string(body))
+		return c.statusError("delete", "", resp)
 	}
 
 	c.logger.LogOperation(utils.INFO, "dropbox", "api", "delete", "success", 
@@ -545,8 +635,62 @@ func (c *Client) DeleteFile(filePath string) error {
 	return nil
 }
 
+// ListDirectory returns the names of the entries in a folder, following the cursor until all entries are read
+func (c *Client) ListDirectory(ctx context.Context, dirPath string) ([]string, error) {
+	args := map[string]interface{}{
+		"path":      dirPath,
+		"recursive": false,
+	}
+	endpoint := "/files/list_folder"
+
+	var names []string
+	for {
+		argsJSON, err := json.Marshal(args)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal list_folder args: %w", err)
+		}
+
+		req, err := c.newAPIRequest(ctx, "POST", endpoint, bytes.NewReader(argsJSON))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create list_folder request: %w", err)
+		}
+
+		resp, err := c.doRequestWithRetry(req)
+		if err != nil {
+			return nil, c.wrapError("list", "", err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			err := c.statusError("list", "", resp)
+			resp.Body.Close()
+			return nil, err
+		}
+
+		var result ListFolderResult
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode list_folder response: %w", err)
+		}
+
+		for _, entry := range result.Entries {
+			names = append(names, entry.Name)
+		}
+		if !result.HasMore {
+			break
+		}
+		args = map[string]interface{}{"cursor": result.Cursor}
+		endpoint = "/files/list_folder/continue"
+	}
+
+	c.logger.LogOperation(utils.DEBUG, "dropbox", "api", "list", "success", 
+		fmt.Sprintf("Listed %d entries in %s", len(names), dirPath), 
+		map[string]interface{}{"dir_path": dirPath, "entries": len(names)})
+	return names, nil
+}
+
 // GetFileInfo gets metadata for a file
-func (c *Client) GetFileInfo(filePath string) (*FileMetadata, error) {
+func (c *Client) GetFileInfo(ctx context.Context, filePath string) (*FileMetadata, error) {
 	args := map[string]interface{}{
 		"path":                        filePath,
 		"include_media_info":          false,
@@ -555,29 +699,37 @@ func (c *Client) GetFileInfo(filePath string) (*FileMetadata, error) {
 	}
 	argsJSON, err := json.Marshal(args)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal get_metadata args: %v", err)
+		return nil, fmt.Errorf("failed to marshal get_metadata args: %w", err)
 	}
 
-	req, err := c.newAPIRequest("POST", "/files/get_metadata", bytes.NewReader(argsJSON))
+	req, err := c.newAPIRequest(ctx, "POST", "/files/get_metadata", bytes.NewReader(argsJSON))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create get_metadata request: %v", err)
+		return nil, fmt.Errorf("failed to create get_metadata request: %w", err)
 	}
 
 	resp, err := c.doRequestWithRetry(req)
 	if err != nil {
-		return nil, fmt.Errorf("get_metadata request failed: %v", err)
+		return nil, c.wrapError("stat", "", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("get_metadata failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, c.statusError("stat", "", resp)
 	}
 
 	var metadata FileMetadata
 	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
-		return nil, fmt.Errorf("failed to decode metadata response: %v", err)
+		return nil, fmt.Errorf("failed to decode metadata response: %w", err)
 	}
 
 	return &metadata, nil
 }
+
+// StatFile returns the size of a remote file using get_metadata
+func (c *Client) StatFile(ctx context.Context, filePath string) (int64, error) {
+	metadata, err := c.GetFileInfo(ctx, filePath)
+	if err != nil {
+		return 0, err
+	}
+	return metadata.Size, nil
+}
@@ -0,0 +1,688 @@
+package gdrive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xXRoxXeRXx/cloud-performance-monitor/internal/utils"
+)
+
+const (
+	// DefaultBaseURL serves the Drive API, the upload endpoint and the OAuth2 token endpoint
+	DefaultBaseURL = "https://www.googleapis.com"
+	DefaultTimeout = 300 * time.Second
+	// folderMimeType marks a Drive file as folder
+	folderMimeType = "application/vnd.google-apps.folder"
+	// rootFolderID is the alias of the user's My Drive folder
+	rootFolderID = "root"
+	// statusResumeIncomplete is returned for every chunk of a resumable upload but the last
+	statusResumeIncomplete = 308
+)
+
+// Client for the Google Drive API v3. Files are addressed by path; the client resolves the
+// folders of a path to Drive IDs and caches them.
+type Client struct {
+	// BaseURL is the scheme and host of the API, DefaultBaseURL unless testing against a fake
+	BaseURL      string
+	AccessToken  string
+	RefreshToken string
+	ClientID     string
+	ClientSecret string
+	HTTPClient   *http.Client
+	// TokenExpiry is when AccessToken expires, zero if unknown
+	TokenExpiry time.Time
+	// TokenObserver is notified about token refreshes, nil if not needed
+	TokenObserver utils.TokenObserver
+	tokenMutex    sync.RWMutex
+	logger        utils.ClientLogger
+
+	// folders caches the IDs of the folders by path
+	folders   map[string]string
+	folderMux sync.Mutex
+}
+
+// OAuth2TokenResponse represents the OAuth2 token response
+type OAuth2TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+}
+
+// File represents the file metadata returned by the Drive API
+type File struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	MimeType string `json:"mimeType"`
+	Size     int64  `json:"size,string"`
+}
+
+// FileList represents the response of files.list
+type FileList struct {
+	Files         []File `json:"files"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+// ErrorResponse represents an error response of the Drive API. The token endpoint
+// answers with the OAuth2 error format instead, e.g. {"error": "invalid_grant"}.
+type ErrorResponse struct {
+	Error json.RawMessage `json:"error"`
+}
+
+// parseErrorTag returns the reason of a Drive API error body, e.g. "rateLimitExceeded",
+// or the OAuth2 error code of a token error
+func parseErrorTag(body string) string {
+	var errResp ErrorResponse
+	if err := json.Unmarshal([]byte(body), &errResp); err != nil || len(errResp.Error) == 0 {
+		return ""
+	}
+	var oauthErr string
+	if err := json.Unmarshal(errResp.Error, &oauthErr); err == nil {
+		return oauthErr
+	}
+	var apiErr struct {
+		Errors []struct {
+			Reason string `json:"reason"`
+		} `json:"errors"`
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(errResp.Error, &apiErr); err != nil {
+		return ""
+	}
+	if len(apiErr.Errors) > 0 && apiErr.Errors[0].Reason != "" {
+		return apiErr.Errors[0].Reason
+	}
+	return apiErr.Status
+}
+
+// NewClient creates a new Google Drive client that obtains its access tokens with the
+// refresh token. An empty baseURL uses DefaultBaseURL.
+func NewClient(baseURL, refreshToken, clientID, clientSecret string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{
+		BaseURL:      strings.TrimSuffix(baseURL, "/"),
+		RefreshToken: refreshToken,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		HTTPClient:   &http.Client{Timeout: DefaultTimeout},
+		logger:       &utils.DefaultClientLogger{},
+		folders:      map[string]string{"/": rootFolderID},
+	}
+}
+
+// RefreshAccessToken obtains a new access token with the refresh token
+func (c *Client) RefreshAccessToken(ctx context.Context) error {
+	c.tokenMutex.Lock()
+	if c.RefreshToken == "" || c.ClientID == "" || c.ClientSecret == "" {
+		c.tokenMutex.Unlock()
+		return fmt.Errorf("refresh token, client ID, or client secret not available")
+	}
+
+	retryConfig := utils.DefaultRetryConfig()
+	retryConfig.MaxRetries = 2 // Fewer retries for OAuth2 operations
+
+	err := retryConfig.WithRetry(ctx, "gdrive_oauth_refresh", func(ctx context.Context) error {
+		data := url.Values{}
+		data.Set("grant_type", "refresh_token")
+		data.Set("refresh_token", c.RefreshToken)
+		data.Set("client_id", c.ClientID)
+		data.Set("client_secret", c.ClientSecret)
+
+		req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+"/oauth2/v4/token", strings.NewReader(data.Encode()))
+		if err != nil {
+			return fmt.Errorf("failed to create refresh request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		resp, err := c.HTTPClient.Do(req)
+		if err != nil {
+			return c.wrapError("token_refresh", "", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			err := c.statusError("token_refresh", "", resp)
+			c.logger.LogOperation(utils.ERROR, "gdrive", "oauth", "token", "refresh_status_error",
+				fmt.Sprintf("Refresh failed: %v", err),
+				map[string]interface{}{"status_code": resp.StatusCode, "error": err.Error()})
+			return err
+		}
+
+		var tokenResp OAuth2TokenResponse
+		if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+			return fmt.Errorf("failed to decode refresh response: %w", err)
+		}
+		c.AccessToken = tokenResp.AccessToken
+		c.TokenExpiry = utils.TokenExpiry(time.Now(), tokenResp.ExpiresIn)
+		// Google only returns a refresh token if it was rotated
+		if tokenResp.RefreshToken != "" {
+			c.RefreshToken = tokenResp.RefreshToken
+		}
+
+		c.logger.LogOperation(utils.INFO, "gdrive", "oauth", "token", "refresh_success",
+			fmt.Sprintf("Access token refreshed successfully (expires in %d seconds)", tokenResp.ExpiresIn),
+			map[string]interface{}{"expires_in": tokenResp.ExpiresIn})
+		return nil
+	})
+	token := c.token()
+	c.tokenMutex.Unlock()
+
+	// The observer persists the token, requests must not wait for that
+	if c.TokenObserver != nil {
+		if err != nil {
+			c.TokenObserver.TokenRefreshFailed(err)
+		} else {
+			c.TokenObserver.TokenRefreshed(token)
+		}
+	}
+	return err
+}
+
+// Token returns the current OAuth token
+func (c *Client) Token() utils.OAuthToken {
+	c.tokenMutex.RLock()
+	defer c.tokenMutex.RUnlock()
+	return c.token()
+}
+
+// token returns the current OAuth token; the caller must hold tokenMutex
+func (c *Client) token() utils.OAuthToken {
+	return utils.OAuthToken{AccessToken: c.AccessToken, RefreshToken: c.RefreshToken, Expiry: c.TokenExpiry}
+}
+
+// SetToken replaces the tokens of the client, e.g. with a token restored from a token store
+func (c *Client) SetToken(token utils.OAuthToken) {
+	c.tokenMutex.Lock()
+	defer c.tokenMutex.Unlock()
+	c.AccessToken = token.AccessToken
+	c.RefreshToken = token.RefreshToken
+	c.TokenExpiry = token.Expiry
+}
+
+// EnsureToken refreshes the access token ahead of time if it is missing or expires within
+// utils.TokenRefreshMargin. Clients without a refresh token are left unchanged.
+func (c *Client) EnsureToken(ctx context.Context) error {
+	token := c.Token()
+	if token.RefreshToken == "" || !token.NeedsRefresh(time.Now(), utils.TokenRefreshMargin) {
+		return nil
+	}
+	return c.RefreshAccessToken(ctx)
+}
+
+// newRequest creates a new authenticated request for a URL below BaseURL
+func (c *Client) newRequest(ctx context.Context, method, endpoint string, body io.Reader) (*http.Request, error) {
+	if err := c.EnsureToken(ctx); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token().AccessToken)
+	return req, nil
+}
+
+// do sends a request. If the access token was rejected, it is refreshed once and the
+// request is sent again, provided its body can be replayed.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || c.Token().RefreshToken == "" {
+		return resp, err
+	}
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	c.logger.LogOperation(utils.INFO, "gdrive", "oauth", "token", "refresh_attempt",
+		"Access token rejected, attempting refresh...", nil)
+	if err := c.RefreshAccessToken(req.Context()); err != nil {
+		return nil, fmt.Errorf("failed to refresh access token: %w", err)
+	}
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	retry.Header.Set("Authorization", "Bearer "+c.Token().AccessToken)
+	return c.HTTPClient.Do(retry)
+}
+
+// statusError returns the typed error of a response with an unexpected status
+func (c *Client) statusError(operation, phase string, resp *http.Response) error {
+	return utils.NewStatusError("gdrive", operation, phase, resp, parseErrorTag)
+}
+
+// wrapError annotates a request error with the operation it occurred in
+func (c *Client) wrapError(operation, phase string, err error) error {
+	return utils.WrapProviderError("gdrive", operation, phase, err)
+}
+
+// notFoundError reports a path that does not exist like a 404 of the API
+func (c *Client) notFoundError(operation, filePath string) error {
+	return &utils.ProviderError{
+		Provider:   "gdrive",
+		Operation:  operation,
+		StatusCode: http.StatusNotFound,
+		Tag:        "notFound",
+		Err:        fmt.Errorf("%s not found", filePath),
+	}
+}
+
+// CloseIdleConnections closes the kept-alive connections of the client, so that the next
+// request has to connect again
+func (c *Client) CloseIdleConnections() {
+	c.HTTPClient.CloseIdleConnections()
+}
+
+// cleanPath normalizes a path of the runner
+func cleanPath(p string) string {
+	return path.Clean("/" + p)
+}
+
+// findChild returns the ID of the entry name in the folder parentID, "" if there is none
+func (c *Client) findChild(ctx context.Context, operation, parentID, name string, folder bool) (string, error) {
+	q := fmt.Sprintf("name = '%s' and '%s' in parents and trashed = false", escapeQuery(name), escapeQuery(parentID))
+	if folder {
+		q += " and mimeType = '" + folderMimeType + "'"
+	}
+	files, _, err := c.listFiles(ctx, operation, q, "")
+	if err != nil || len(files) == 0 {
+		return "", err
+	}
+	return files[0].ID, nil
+}
+
+// escapeQuery escapes a string literal of a files.list query
+func escapeQuery(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s)
+}
+
+// listFiles returns one page of files.list for the query q
+func (c *Client) listFiles(ctx context.Context, operation, q, pageToken string) ([]File, string, error) {
+	query := url.Values{
+		"q":        {q},
+		"fields":   {"nextPageToken,files(id,name,mimeType,size)"},
+		"pageSize": {"1000"},
+		"spaces":   {"drive"},
+	}
+	if pageToken != "" {
+		query.Set("pageToken", pageToken)
+	}
+	req, err := c.newRequest(ctx, "GET", "/drive/v3/files?"+query.Encode(), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, "", c.wrapError(operation, "", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", c.statusError(operation, "", resp)
+	}
+	var list FileList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, "", fmt.Errorf("failed to decode file list: %w", err)
+	}
+	return list.Files, list.NextPageToken, nil
+}
+
+// folderID returns the ID of the folder at dirPath from the cache or by looking up its
+// path, "" if it does not exist
+func (c *Client) folderID(ctx context.Context, operation, dirPath string) (string, error) {
+	dirPath = cleanPath(dirPath)
+	c.folderMux.Lock()
+	id, ok := c.folders[dirPath]
+	c.folderMux.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	parentID, err := c.folderID(ctx, operation, path.Dir(dirPath))
+	if err != nil || parentID == "" {
+		return "", err
+	}
+	id, err = c.findChild(ctx, operation, parentID, path.Base(dirPath), true)
+	if err != nil || id == "" {
+		return "", err
+	}
+	c.cacheFolder(dirPath, id)
+	return id, nil
+}
+
+func (c *Client) cacheFolder(dirPath, id string) {
+	c.folderMux.Lock()
+	defer c.folderMux.Unlock()
+	c.folders[dirPath] = id
+}
+
+// fileID returns the ID of the file or folder at filePath, "" if it does not exist
+func (c *Client) fileID(ctx context.Context, operation, filePath string) (string, error) {
+	filePath = cleanPath(filePath)
+	parentID, err := c.folderID(ctx, operation, path.Dir(filePath))
+	if err != nil || parentID == "" {
+		return "", err
+	}
+	return c.findChild(ctx, operation, parentID, path.Base(filePath), false)
+}
+
+// EnsureDirectory creates the folders of dirPath that do not exist. The folders are
+// looked up again on every call, so that folders deleted in the meantime are recreated.
+func (c *Client) EnsureDirectory(ctx context.Context, dirPath string) error {
+	parentID := rootFolderID
+	current := "/"
+	for _, name := range strings.Split(strings.Trim(cleanPath(dirPath), "/"), "/") {
+		if name == "" {
+			continue
+		}
+		current = path.Join(current, name)
+		id, err := c.findChild(ctx, "mkdir", parentID, name, true)
+		if err != nil {
+			return err
+		}
+		if id == "" {
+			if id, err = c.createFolder(ctx, parentID, name); err != nil {
+				return err
+			}
+		}
+		c.cacheFolder(current, id)
+		parentID = id
+	}
+	return nil
+}
+
+// createFolder creates a folder and returns its ID
+func (c *Client) createFolder(ctx context.Context, parentID, name string) (string, error) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"name":     name,
+		"mimeType": folderMimeType,
+		"parents":  []string{parentID},
+	})
+	req, err := c.newRequest(ctx, "POST", "/drive/v3/files?fields=id", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.do(req)
+	if err != nil {
+		return "", c.wrapError("mkdir", "", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", c.statusError("mkdir", "", resp)
+	}
+	var folder File
+	if err := json.NewDecoder(resp.Body).Decode(&folder); err != nil {
+		return "", fmt.Errorf("failed to decode folder: %w", err)
+	}
+	return folder.ID, nil
+}
+
+// UploadFile uploads a file with the resumable upload protocol: a session is started with
+// the file metadata, then the content is sent in chunks of chunkSize bytes with
+// Content-Range headers
+func (c *Client) UploadFile(ctx context.Context, filePath string, reader io.Reader, size int64, chunkSize int64) error {
+	filePath = cleanPath(filePath)
+	c.logger.LogOperation(utils.INFO, "gdrive", c.BaseURL, "upload", "start",
+		fmt.Sprintf("Starting resumable upload for %s (size: %d bytes, chunk size: %d bytes)", filePath, size, chunkSize),
+		map[string]interface{}{"file_path": filePath, "file_size": size, "chunk_size": chunkSize})
+
+	parentID, err := c.folderID(ctx, "upload", path.Dir(filePath))
+	if err != nil {
+		return err
+	}
+	if parentID == "" {
+		return c.notFoundError("upload", path.Dir(filePath))
+	}
+
+	sessionURL, err := c.startUploadSession(ctx, parentID, path.Base(filePath), size)
+	if err != nil {
+		return err
+	}
+
+	var offset int64
+	chunk := make([]byte, chunkSize)
+	for {
+		n, readErr := io.ReadFull(reader, chunk)
+		if readErr != nil && readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+			return fmt.Errorf("failed to read chunk at offset %d: %w", offset, readErr)
+		}
+		if offset+int64(n) > size {
+			return fmt.Errorf("file is larger than the announced %d bytes", size)
+		}
+		last := offset+int64(n) == size
+		if n == 0 && !last {
+			return fmt.Errorf("file is smaller than the announced %d bytes, got %d", size, offset)
+		}
+		if err := c.uploadChunk(ctx, sessionURL, chunk[:n], offset, size, last); err != nil {
+			return err
+		}
+		offset += int64(n)
+		if last {
+			break
+		}
+	}
+
+	c.logger.LogOperation(utils.INFO, "gdrive", c.BaseURL, "upload", "completed",
+		fmt.Sprintf("Resumable upload successful for %s", filePath),
+		map[string]interface{}{"file_path": filePath})
+	return nil
+}
+
+// startUploadSession starts a resumable upload and returns the session URL
+func (c *Client) startUploadSession(ctx context.Context, parentID, name string, size int64) (string, error) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"name":    name,
+		"parents": []string{parentID},
+	})
+	req, err := c.newRequest(ctx, "POST", "/upload/drive/v3/files?uploadType=resumable", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	req.Header.Set("X-Upload-Content-Type", "application/octet-stream")
+	req.Header.Set("X-Upload-Content-Length", fmt.Sprintf("%d", size))
+	resp, err := c.do(req)
+	if err != nil {
+		return "", c.wrapError("upload", "session", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", c.statusError("upload", "session", resp)
+	}
+	sessionURL := resp.Header.Get("Location")
+	if sessionURL == "" {
+		return "", fmt.Errorf("resumable upload response contains no session URL")
+	}
+	return sessionURL, nil
+}
+
+// uploadChunk sends the chunk at offset of a resumable upload. Every chunk but the last is
+// acknowledged with 308 Resume Incomplete.
+func (c *Client) uploadChunk(ctx context.Context, sessionURL string, chunk []byte, offset, size int64, last bool) error {
+	// Large uploads may outlive the access token
+	if err := c.EnsureToken(ctx); err != nil {
+		return err
+	}
+	// Transient failures are retried by the client's transport (shared retry policy)
+	req, err := http.NewRequestWithContext(ctx, "PUT", sessionURL, bytes.NewReader(chunk))
+	if err != nil {
+		return fmt.Errorf("could not create PUT request for chunk at offset %d: %w", offset, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token().AccessToken)
+	if len(chunk) == 0 {
+		req.Header.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+	} else {
+		req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+int64(len(chunk))-1, size))
+	}
+	req.ContentLength = int64(len(chunk))
+	req = utils.WithHTTPOperation(req, utils.HTTPOperationChunk)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return c.wrapError("upload", "chunk", fmt.Errorf("PUT request for chunk at offset %d failed: %w", offset, err))
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case last && (resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusCreated):
+		return nil
+	case !last && resp.StatusCode == statusResumeIncomplete:
+		c.logger.LogOperation(utils.DEBUG, "gdrive", c.BaseURL, "chunk_upload", "chunk_success",
+			fmt.Sprintf("Chunk at offset %d uploaded (%d bytes, server has %s)", offset, len(chunk), resp.Header.Get("Range")),
+			map[string]interface{}{"offset": offset, "bytes": len(chunk)})
+		return nil
+	}
+	return c.statusError("upload", "chunk", resp)
+}
+
+// DownloadFile downloads the content of a file
+func (c *Client) DownloadFile(ctx context.Context, filePath string) (io.ReadCloser, error) {
+	id, err := c.fileID(ctx, "download", filePath)
+	if err != nil {
+		return nil, err
+	}
+	if id == "" {
+		return nil, c.notFoundError("download", filePath)
+	}
+	req, err := c.newRequest(ctx, "GET", "/drive/v3/files/"+url.PathEscape(id)+"?alt=media", nil)
+	if err != nil {
+		return nil, err
+	}
+	req = utils.WithHTTPOperation(req, utils.HTTPOperationDownload)
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, c.wrapError("download", "", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, c.statusError("download", "", resp)
+	}
+	return resp.Body, nil
+}
+
+// DeleteFile deletes a file or folder permanently, bypassing the trash
+func (c *Client) DeleteFile(ctx context.Context, filePath string) error {
+	filePath = cleanPath(filePath)
+	id, err := c.fileID(ctx, "delete", filePath)
+	if err != nil {
+		return err
+	}
+	if id == "" {
+		return c.notFoundError("delete", filePath)
+	}
+	req, err := c.newRequest(ctx, "DELETE", "/drive/v3/files/"+url.PathEscape(id), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return c.wrapError("delete", "", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return c.statusError("delete", "", resp)
+	}
+
+	// A deleted folder and its subfolders must be looked up again
+	c.folderMux.Lock()
+	for p := range c.folders {
+		if p == filePath || strings.HasPrefix(p, filePath+"/") {
+			delete(c.folders, p)
+		}
+	}
+	c.folderMux.Unlock()
+	return nil
+}
+
+// ListDirectory returns the names of the entries of a folder
+func (c *Client) ListDirectory(ctx context.Context, dirPath string) ([]string, error) {
+	id, err := c.folderID(ctx, "list", dirPath)
+	if err != nil {
+		return nil, err
+	}
+	if id == "" {
+		return nil, c.notFoundError("list", dirPath)
+	}
+
+	var names []string
+	q := fmt.Sprintf("'%s' in parents and trashed = false", escapeQuery(id))
+	pageToken := ""
+	for {
+		files, next, err := c.listFiles(ctx, "list", q, pageToken)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			names = append(names, f.Name)
+		}
+		if next == "" {
+			return names, nil
+		}
+		pageToken = next
+	}
+}
+
+// StatFile returns the size of a file as reported by its metadata
+func (c *Client) StatFile(ctx context.Context, filePath string) (int64, error) {
+	id, err := c.fileID(ctx, "stat", filePath)
+	if err != nil {
+		return 0, err
+	}
+	if id == "" {
+		return 0, c.notFoundError("stat", filePath)
+	}
+	req, err := c.newRequest(ctx, "GET", "/drive/v3/files/"+url.PathEscape(id)+"?fields=size", nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return 0, c.wrapError("stat", "", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, c.statusError("stat", "", resp)
+	}
+	var file File
+	if err := json.NewDecoder(resp.Body).Decode(&file); err != nil {
+		return 0, fmt.Errorf("failed to decode file metadata: %w", err)
+	}
+	return file.Size, nil
+}
+
+// TestConnection verifies that the credentials grant access to the Drive
+func (c *Client) TestConnection(ctx context.Context) error {
+	req, err := c.newRequest(ctx, "GET", "/drive/v3/about?fields=user", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return c.wrapError("connection_test", "", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return c.statusError("connection_test", "", resp)
+	}
+	return nil
+}
@@ -0,0 +1,326 @@
+package gdrive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xXRoxXeRXx/cloud-performance-monitor/internal/utils"
+)
+
+var (
+	childQuery  = regexp.MustCompile(`^name = '(.*)' and '(.*)' in parents and trashed = false`)
+	parentQuery = regexp.MustCompile(`^'(.*)' in parents and trashed = false$`)
+)
+
+// fakeDrive is a minimal in-memory Drive API with the token endpoint, files.list,
+// folder creation, resumable uploads, downloads and deletes
+type fakeDrive struct {
+	mu       sync.Mutex
+	files    map[string]*fakeFile
+	sessions map[string]*fakeFile
+	nextID   int
+	tokens   int
+	ranges   []string
+	// expireToken rejects the next API request with 401
+	expireToken bool
+}
+
+type fakeFile struct {
+	File
+	parent string
+	data   []byte
+}
+
+func newFakeDrive() *fakeDrive {
+	return &fakeDrive{files: make(map[string]*fakeFile), sessions: make(map[string]*fakeFile)}
+}
+
+func (d *fakeDrive) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if r.URL.Path == "/oauth2/v4/token" {
+		_ = r.ParseForm()
+		if r.Form.Get("refresh_token") != "refresh" || r.Form.Get("client_id") != "client" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": "invalid_grant"}`))
+			return
+		}
+		d.tokens++
+		fmt.Fprintf(w, `{"access_token": "token-%d", "expires_in": 3599, "token_type": "Bearer"}`, d.tokens)
+		return
+	}
+	if d.expireToken || r.Header.Get("Authorization") != fmt.Sprintf("Bearer token-%d", d.tokens) {
+		d.expireToken = false
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error": {"code": 401, "errors": [{"reason": "authError"}], "status": "UNAUTHENTICATED"}}`))
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/drive/v3/files/")
+	switch {
+	case r.URL.Path == "/drive/v3/files" && r.Method == "GET":
+		d.list(w, r.URL.Query().Get("q"))
+	case r.URL.Path == "/drive/v3/files" && r.Method == "POST":
+		file := d.create(r.Body)
+		_ = json.NewEncoder(w).Encode(file.File)
+	case r.URL.Path == "/upload/drive/v3/files" && r.URL.Query().Get("uploadType") == "resumable":
+		file := &fakeFile{}
+		var meta struct {
+			Name    string   `json:"name"`
+			Parents []string `json:"parents"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&meta)
+		file.Name, file.parent = meta.Name, meta.Parents[0]
+		session := fmt.Sprintf("session-%d", len(d.sessions)+1)
+		d.sessions[session] = file
+		w.Header().Set("Location", "http://"+r.Host+"/upload/sessions/"+session)
+	case strings.HasPrefix(r.URL.Path, "/upload/sessions/"):
+		d.uploadChunk(w, r, strings.TrimPrefix(r.URL.Path, "/upload/sessions/"))
+	case d.files[id] == nil:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error": {"code": 404, "errors": [{"reason": "notFound"}]}}`))
+	case r.Method == "GET" && r.URL.Query().Get("alt") == "media":
+		_, _ = w.Write(d.files[id].data)
+	case r.Method == "GET":
+		fmt.Fprintf(w, `{"size": "%d"}`, d.files[id].Size)
+	case r.Method == "DELETE":
+		delete(d.files, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (d *fakeDrive) add(file *fakeFile) *fakeFile {
+	d.nextID++
+	file.ID = fmt.Sprintf("id-%d", d.nextID)
+	d.files[file.ID] = file
+	return file
+}
+
+func (d *fakeDrive) create(body io.Reader) *fakeFile {
+	var meta struct {
+		Name     string   `json:"name"`
+		MimeType string   `json:"mimeType"`
+		Parents  []string `json:"parents"`
+	}
+	_ = json.NewDecoder(body).Decode(&meta)
+	return d.add(&fakeFile{File: File{Name: meta.Name, MimeType: meta.MimeType}, parent: meta.Parents[0]})
+}
+
+func (d *fakeDrive) list(w http.ResponseWriter, q string) {
+	var name, parent string
+	if m := childQuery.FindStringSubmatch(q); m != nil {
+		name, parent = m[1], m[2]
+	} else if m := parentQuery.FindStringSubmatch(q); m != nil {
+		parent = m[1]
+	}
+	folderOnly := strings.Contains(q, "mimeType = '"+folderMimeType+"'")
+
+	var list FileList
+	for _, f := range d.files {
+		if f.parent == parent && (name == "" || f.Name == name) && (!folderOnly || f.MimeType == folderMimeType) {
+			list.Files = append(list.Files, f.File)
+		}
+	}
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+func (d *fakeDrive) uploadChunk(w http.ResponseWriter, r *http.Request, session string) {
+	file := d.sessions[session]
+	body, _ := io.ReadAll(r.Body)
+	contentRange := r.Header.Get("Content-Range")
+	d.ranges = append(d.ranges, contentRange)
+
+	var start, end, total int64
+	if _, err := fmt.Sscanf(contentRange, "bytes %d-%d/%d", &start, &end, &total); err != nil {
+		if _, err := fmt.Sscanf(contentRange, "bytes */%d", &total); err != nil || total != 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	} else if start != int64(len(file.data)) || end-start+1 != int64(len(body)) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	file.data = append(file.data, body...)
+	if int64(len(file.data)) < total {
+		w.Header().Set("Range", fmt.Sprintf("bytes=0-%d", len(file.data)-1))
+		w.WriteHeader(statusResumeIncomplete)
+		return
+	}
+	file.Size = total
+	d.add(file)
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(file.File)
+}
+
+func newTestClient(t *testing.T, drive *fakeDrive) *Client {
+	server := httptest.NewServer(drive)
+	t.Cleanup(server.Close)
+	return NewClient(server.URL, "refresh", "client", "secret")
+}
+
+func TestUploadFileResumable(t *testing.T) {
+	drive := newFakeDrive()
+	client := newTestClient(t, drive)
+	content := []byte("0123456789")
+
+	if err := client.EnsureDirectory(context.Background(), "/perf/run"); err != nil {
+		t.Fatalf("EnsureDirectory failed: %v", err)
+	}
+	if err := client.UploadFile(context.Background(), "/perf/run/file.bin", bytes.NewReader(content), int64(len(content)), 4); err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+	expected := []string{"bytes 0-3/10", "bytes 4-7/10", "bytes 8-9/10"}
+	if strings.Join(drive.ranges, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected chunks %v, got %v", expected, drive.ranges)
+	}
+
+	size, err := client.StatFile(context.Background(), "/perf/run/file.bin")
+	if err != nil || size != int64(len(content)) {
+		t.Errorf("expected size %d, got %d (%v)", len(content), size, err)
+	}
+	body, err := client.DownloadFile(context.Background(), "/perf/run/file.bin")
+	if err != nil {
+		t.Fatalf("DownloadFile failed: %v", err)
+	}
+	defer body.Close()
+	if data, _ := io.ReadAll(body); !bytes.Equal(data, content) {
+		t.Errorf("unexpected content: %q", data)
+	}
+	if drive.tokens != 1 {
+		t.Errorf("expected the access token to be generated once, got %d", drive.tokens)
+	}
+}
+
+func TestEnsureDirectoryReusesFolders(t *testing.T) {
+	drive := newFakeDrive()
+	client := newTestClient(t, drive)
+
+	for i := 0; i < 2; i++ {
+		if err := client.EnsureDirectory(context.Background(), "/perf/run"); err != nil {
+			t.Fatalf("EnsureDirectory failed: %v", err)
+		}
+	}
+	if len(drive.files) != 2 {
+		t.Errorf("expected 2 folders, got %d", len(drive.files))
+	}
+}
+
+func TestListDirectoryAndDelete(t *testing.T) {
+	drive := newFakeDrive()
+	client := newTestClient(t, drive)
+	if err := client.EnsureDirectory(context.Background(), "/perf"); err != nil {
+		t.Fatalf("EnsureDirectory failed: %v", err)
+	}
+	for _, name := range []string{"a.tmp", "b.tmp"} {
+		if err := client.UploadFile(context.Background(), "/perf/"+name, strings.NewReader(name), int64(len(name)), 4); err != nil {
+			t.Fatalf("UploadFile failed: %v", err)
+		}
+	}
+
+	names, err := client.ListDirectory(context.Background(), "/perf")
+	if err != nil {
+		t.Fatalf("ListDirectory failed: %v", err)
+	}
+	if len(names) != 2 {
+		t.Errorf("unexpected entries: %v", names)
+	}
+
+	if err := client.DeleteFile(context.Background(), "/perf/a.tmp"); err != nil {
+		t.Fatalf("DeleteFile failed: %v", err)
+	}
+	if len(drive.files) != 2 {
+		t.Errorf("expected the file to be deleted permanently, %d files left", len(drive.files))
+	}
+	_, err = client.StatFile(context.Background(), "/perf/a.tmp")
+	var providerErr *utils.ProviderError
+	if !errors.As(err, &providerErr) || providerErr.StatusCode != http.StatusNotFound {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+func TestRefreshOnUnauthorized(t *testing.T) {
+	drive := newFakeDrive()
+	client := newTestClient(t, drive)
+	if err := client.EnsureDirectory(context.Background(), "/perf"); err != nil {
+		t.Fatalf("EnsureDirectory failed: %v", err)
+	}
+
+	drive.expireToken = true
+	if _, err := client.ListDirectory(context.Background(), "/perf"); err != nil {
+		t.Fatalf("ListDirectory failed: %v", err)
+	}
+	if drive.tokens != 2 || client.Token().AccessToken != "token-2" {
+		t.Errorf("expected the rejected access token to be refreshed, got %d tokens", drive.tokens)
+	}
+}
+
+// readingObserver reads the client's token when notified, like the token store does
+type readingObserver struct {
+	client *Client
+	tokens []utils.OAuthToken
+}
+
+func (o *readingObserver) TokenRefreshed(token utils.OAuthToken) {
+	o.tokens = append(o.tokens, o.client.Token())
+}
+
+func (o *readingObserver) TokenRefreshFailed(err error) {}
+
+func TestRefreshAccessTokenNotifiesWithoutLock(t *testing.T) {
+	client := newTestClient(t, newFakeDrive())
+	observer := &readingObserver{client: client}
+	client.TokenObserver = observer
+
+	done := make(chan error, 1)
+	go func() { done <- client.RefreshAccessToken(context.Background()) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("RefreshAccessToken failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RefreshAccessToken did not return, the observer is called with the token lock held")
+	}
+	if len(observer.tokens) != 1 || observer.tokens[0].AccessToken != "token-1" {
+		t.Errorf("expected the observer to see the new token, got %+v", observer.tokens)
+	}
+}
+
+func TestRefreshAccessTokenInvalidGrant(t *testing.T) {
+	client := newTestClient(t, newFakeDrive())
+	client.RefreshToken = "revoked"
+
+	err := client.RefreshAccessToken(context.Background())
+	var providerErr *utils.ProviderError
+	if !errors.As(err, &providerErr) || providerErr.Tag != "invalid_grant" {
+		t.Errorf("expected an invalid_grant error, got %v", err)
+	}
+}
+
+func TestParseErrorTag(t *testing.T) {
+	tests := map[string]string{
+		`{"error": {"code": 403, "errors": [{"reason": "userRateLimitExceeded"}]}}`: "userRateLimitExceeded",
+		`{"error": {"code": 401, "status": "UNAUTHENTICATED"}}`:                     "UNAUTHENTICATED",
+		`{"error": "invalid_grant", "error_description": "Bad Request"}`:            "invalid_grant",
+		`not json`: "",
+	}
+	for body, expected := range tests {
+		if tag := parseErrorTag(body); tag != expected {
+			t.Errorf("parseErrorTag(%s) = %q, expected %q", body, tag, expected)
+		}
+	}
+}
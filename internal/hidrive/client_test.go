@@ -1,6 +1,7 @@
 package hidrive
 
-import (
+import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -19,7 +20,7 @@ func TestEnsureDirectory(t *testing.T) {
 
 	client := NewClient(server.URL, "testuser", "testpass")
 
-	err := client.EnsureDirectory("testdir")
+	err := client.EnsureDirectory(context.Background(), "testdir")
 	if err != nil {
 		t.Errorf("EnsureDirectory failed: %v", err)
 	}
@@ -37,7 +38,7 @@ func TestDownloadFile(t *testing.T) {
 
 	client := NewClient(server.URL, "testuser", "testpass")
 
-	body, err := client.DownloadFile("testfile.txt")
+	body, err := client.DownloadFile(context.Background(), "testfile.txt")
 	if err != nil {
 		t.Errorf("DownloadFile failed: %v", err)
 	}
@@ -62,7 +63,7 @@ func TestDeleteFile(t *testing.T) {
 
 	client := NewClient(server.URL, "testuser", "testpass")
 
-	err := client.DeleteFile("testfile.txt")
+	err := client.DeleteFile(context.Background(), "testfile.txt")
 	if err != nil {
 		t.Errorf("DeleteFile failed: %v", err)
 	}
@@ -12,6 +12,7 @@ import (
 	"path"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/xXRoxXeRXx/cloud-performance-monitor/internal/utils"
@@ -24,7 +25,12 @@ type Client struct {
 	ClientID     string
 	ClientSecret string
 	HTTPClient   *http.Client
-	logger       utils.ClientLogger
+	// TokenExpiry is when AccessToken expires, zero if unknown
+	TokenExpiry time.Time
+	// TokenObserver is notified about token refreshes, nil if not needed
+	TokenObserver utils.TokenObserver
+	tokenMutex    sync.RWMutex
+	logger        utils.ClientLogger
 }
 
 const (
@@ -83,6 +89,15 @@ type ErrorResponse struct {
 	Message string `json:"msg"`
 }
 
+// parseErrorTag extracts the HiDrive error code from an error response body
+func parseErrorTag(body string) string {
+	var errResp ErrorResponse
+	if err := json.Unmarshal([]byte(body), &errResp); err != nil || errResp.Code == 0 {
+		return ""
+	}
+	return strconv.Itoa(errResp.Code)
+}
+
 // NewClient creates a new HiDrive Legacy API client
 func NewClient(accessToken string) *Client {
 	t := http.DefaultTransport.(*http.Transport).Clone()
@@ -101,7 +116,7 @@ func NewClient(accessToken string) *Client {
 }
 
 // NewClientWithOAuth2 creates a new HiDrive Legacy API client with OAuth2 refresh capability
-func NewClientWithOAuth2(refreshToken, clientID, clientSecret string) (*Client, error) {
+func NewClientWithOAuth2(ctx context.Context, refreshToken, clientID, clientSecret string) (*Client, error) {
 	t := http.DefaultTransport.(*http.Transport).Clone()
 	t.MaxIdleConns = 100
 	t.MaxConnsPerHost = 100
@@ -119,22 +134,20 @@ func NewClientWithOAuth2(refreshToken, clientID, clientSecret string) (*Client,
 	}
 
 	// Generate initial access token
-	if err := client.RefreshAccessToken(); err != nil {
-		return nil, fmt.Errorf("failed to generate initial access token: %v", err)
+	if err := client.RefreshAccessToken(ctx); err != nil {
+		return nil, fmt.Errorf("failed to generate initial access token: %w", err)
 	}
 
 	return client, nil
 }
 
 // GetAccessTokenFromCredentials exchanges client credentials for access token using OAuth2 with retry logic
-func GetAccessTokenFromCredentials(clientID, clientSecret, authCode string) (*OAuth2TokenResponse, error) {
+func GetAccessTokenFromCredentials(ctx context.Context, clientID, clientSecret, authCode string) (*OAuth2TokenResponse, error) {
 	retryConfig := utils.DefaultRetryConfig()
 	retryConfig.MaxRetries = 2
-	retryConfig.RetryableErrors = append(retryConfig.RetryableErrors, 
-		"connection refused", "timeout", "temporary failure", "502", "503", "504")
 
 	var tokenResp OAuth2TokenResponse
-	err := retryConfig.WithRetry(context.Background(), "hidrive_legacy_oauth_initial", func(ctx context.Context) error {
+	err := retryConfig.WithRetry(ctx, "hidrive_legacy_oauth_initial", func(ctx context.Context) error {
 		data := url.Values{}
 		data.Set("client_id", clientID)
 		data.Set("client_secret", clientSecret)
@@ -143,24 +156,23 @@ func GetAccessTokenFromCredentials(clientID, clientSecret, authCode string) (*OA
 
 		req, err := http.NewRequestWithContext(ctx, "POST", HiDriveOAuthURL, bytes.NewBufferString(data.Encode()))
 		if err != nil {
-			return fmt.Errorf("failed to create OAuth2 request: %v", err)
+			return fmt.Errorf("failed to create OAuth2 request: %w", err)
 		}
 		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
 		client := &http.Client{Timeout: DefaultTimeout}
 		resp, err := client.Do(req)
 		if err != nil {
-			return fmt.Errorf("OAuth2 request failed: %v", err)
+			return fmt.Errorf("OAuth2 request failed: %w", err)
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			return fmt.Errorf("OAuth2 failed with status %d: %s", resp.StatusCode, string(body))
+			return utils.NewStatusError("hidrive_legacy", "token", "", resp, parseErrorTag)
 		}
 
 		if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
-			return fmt.Errorf("failed to decode OAuth2 response: %v", err)
+			return fmt.Errorf("failed to decode OAuth2 response: %w", err)
 		}
 
 		return nil
@@ -174,17 +186,17 @@ func GetAccessTokenFromCredentials(clientID, clientSecret, authCode string) (*OA
 }
 
 // RefreshAccessToken refreshes the access token using the refresh token with retry logic
-func (c *Client) RefreshAccessToken() error {
+func (c *Client) RefreshAccessToken(ctx context.Context) error {
+	c.tokenMutex.Lock()
 	if c.RefreshToken == "" || c.ClientID == "" || c.ClientSecret == "" {
+		c.tokenMutex.Unlock()
 		return fmt.Errorf("refresh token, client ID, and client secret are required for token refresh")
 	}
 
 	retryConfig := utils.DefaultRetryConfig()
 	retryConfig.MaxRetries = 2 // Fewer retries for OAuth2 operations
-	retryConfig.RetryableErrors = append(retryConfig.RetryableErrors, 
-		"connection refused", "timeout", "temporary failure", "502", "503", "504")
 
-	return retryConfig.WithRetry(context.Background(), "hidrive_legacy_oauth_refresh", func(ctx context.Context) error {
+	err := retryConfig.WithRetry(ctx, "hidrive_legacy_oauth_refresh", func(ctx context.Context) error {
 		data := url.Values{}
 		data.Set("grant_type", "refresh_token")
 		data.Set("refresh_token", c.RefreshToken)
@@ -193,28 +205,28 @@ func (c *Client) RefreshAccessToken() error {
 
 		req, err := http.NewRequestWithContext(ctx, "POST", HiDriveOAuthURL, bytes.NewBufferString(data.Encode()))
 		if err != nil {
-			return fmt.Errorf("failed to create refresh request: %v", err)
+			return fmt.Errorf("failed to create refresh request: %w", err)
 		}
 		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
 		resp, err := c.HTTPClient.Do(req)
 		if err != nil {
-			return fmt.Errorf("refresh token request failed: %v", err)
+			return fmt.Errorf("refresh token request failed: %w", err)
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			return fmt.Errorf("refresh token failed with status %d: %s", resp.StatusCode, string(body))
+			return utils.NewStatusError("hidrive_legacy", "token_refresh", "", resp, parseErrorTag)
 		}
 
 		var tokenResp OAuth2TokenResponse
 		if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
-			return fmt.Errorf("failed to decode refresh token response: %v", err)
+			return fmt.Errorf("failed to decode refresh token response: %w", err)
 		}
 
 		// Update access token
 		c.AccessToken = tokenResp.AccessToken
+		c.TokenExpiry = utils.TokenExpiry(time.Now(), tokenResp.ExpiresIn)
 		
 		// Update refresh token if a new one was provided
 		if tokenResp.RefreshToken != "" {
@@ -226,19 +238,81 @@ func (c *Client) RefreshAccessToken() error {
 			map[string]interface{}{"expires_in": tokenResp.ExpiresIn})
 		return nil
 	})
+	token := c.token()
+	c.tokenMutex.Unlock()
+
+	// The observer persists the token, requests must not wait for that
+	if c.TokenObserver != nil {
+		if err != nil {
+			c.TokenObserver.TokenRefreshFailed(err)
+		} else {
+			c.TokenObserver.TokenRefreshed(token)
+		}
+	}
+	return err
+}
+
+// Token returns the current OAuth token
+func (c *Client) Token() utils.OAuthToken {
+	c.tokenMutex.RLock()
+	defer c.tokenMutex.RUnlock()
+	return c.token()
+}
+
+// token returns the current OAuth token; the caller must hold tokenMutex
+func (c *Client) token() utils.OAuthToken {
+	return utils.OAuthToken{AccessToken: c.AccessToken, RefreshToken: c.RefreshToken, Expiry: c.TokenExpiry}
+}
+
+// SetToken replaces the tokens of the client, e.g. with a token restored from a token store
+func (c *Client) SetToken(token utils.OAuthToken) {
+	c.tokenMutex.Lock()
+	defer c.tokenMutex.Unlock()
+	c.AccessToken = token.AccessToken
+	c.RefreshToken = token.RefreshToken
+	c.TokenExpiry = token.Expiry
+}
+
+// EnsureToken refreshes the access token ahead of time if it is missing or expires within
+// utils.TokenRefreshMargin. Clients without a refresh token are left unchanged.
+func (c *Client) EnsureToken(ctx context.Context) error {
+	token := c.Token()
+	if token.RefreshToken == "" || !token.NeedsRefresh(time.Now(), utils.TokenRefreshMargin) {
+		return nil
+	}
+	return c.RefreshAccessToken(ctx)
 }
 
 // newAPIRequest creates a new authenticated API request
-func (c *Client) newAPIRequest(method, endpoint string, body io.Reader) (*http.Request, error) {
+func (c *Client) newAPIRequest(ctx context.Context, method, endpoint string, body io.Reader) (*http.Request, error) {
+	if err := c.EnsureToken(ctx); err != nil {
+		return nil, err
+	}
 	fullURL := HiDriveAPIBaseURL + endpoint
-	req, err := http.NewRequest(method, fullURL, body)
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, body)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Authorization", "Bearer "+c.AccessToken)
+	req.Header.Set("Authorization", "Bearer "+c.Token().AccessToken)
 	return req, nil
 }
 
+// statusError creates a typed error for a response with an unexpected status
+func (c *Client) statusError(operation, phase string, resp *http.Response) error {
+	return utils.NewStatusError("hidrive_legacy", operation, phase, resp, parseErrorTag)
+}
+
+// wrapError annotates a request error with the operation it occurred in
+func (c *Client) wrapError(operation, phase string, err error) error {
+	return utils.WrapProviderError("hidrive_legacy", operation, phase, err)
+}
+
+// CloseIdleConnections closes the kept-alive connections of the client, so that the next
+// request has to connect again
+func (c *Client) CloseIdleConnections() {
+	c.HTTPClient.CloseIdleConnections()
+}
+
 // doRequestWithRetry performs an HTTP request with automatic token refresh on 401 errors
 func (c *Client) doRequestWithRetry(req *http.Request) (*http.Response, error) {
 	// Clone the request to retry if needed
@@ -247,7 +321,7 @@ func (c *Client) doRequestWithRetry(req *http.Request) (*http.Response, error) {
 		var err error
 		originalBody, err = io.ReadAll(req.Body)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read request body: %v", err)
+			return nil, fmt.Errorf("failed to read request body: %w", err)
 		}
 		req.Body = io.NopCloser(bytes.NewReader(originalBody))
 	}
@@ -269,14 +343,14 @@ func (c *Client) doRequestWithRetry(req *http.Request) (*http.Response, error) {
 	// Try to refresh the token
 	c.logger.LogOperation(utils.INFO, "hidrive_legacy", "auth", "token_refresh", "start", 
 		"Received 401, attempting token refresh", nil)
-	if err := c.RefreshAccessToken(); err != nil {
-		return nil, fmt.Errorf("token refresh failed: %v", err)
+	if err := c.RefreshAccessToken(req.Context()); err != nil {
+		return nil, fmt.Errorf("token refresh failed: %w", err)
 	}
 
-	// Recreate the request with new token
-	newReq, err := http.NewRequest(req.Method, req.URL.String(), bytes.NewReader(originalBody))
+	// Recreate the request with new token, keeping its context
+	newReq, err := http.NewRequestWithContext(req.Context(), req.Method, req.URL.String(), bytes.NewReader(originalBody))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create retry request: %v", err)
+		return nil, fmt.Errorf("failed to create retry request: %w", err)
 	}
 
 	// Copy headers from original request
@@ -287,33 +361,32 @@ func (c *Client) doRequestWithRetry(req *http.Request) (*http.Response, error) {
 	}
 
 	// Update authorization header with new token
-	newReq.Header.Set("Authorization", "Bearer "+c.AccessToken)
+	newReq.Header.Set("Authorization", "Bearer "+c.Token().AccessToken)
 
 	// Retry the request
 	return c.HTTPClient.Do(newReq)
 }
 
 // GetUserHome retrieves the user's home directory path
-func (c *Client) GetUserHome() (string, error) {
-	req, err := c.newAPIRequest("GET", "/user/me?fields=home", nil)
+func (c *Client) GetUserHome(ctx context.Context) (string, error) {
+	req, err := c.newAPIRequest(ctx, "GET", "/user/me?fields=home", nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to create user info request: %v", err)
+		return "", fmt.Errorf("failed to create user info request: %w", err)
 	}
 
 	resp, err := c.doRequestWithRetry(req)
 	if err != nil {
-		return "", fmt.Errorf("user info request failed: %v", err)
+		return "", c.wrapError("user_info", "", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("user info request failed with status %d: %s", resp.StatusCode, string(body))
+		return "", c.statusError("user_info", "", resp)
 	}
 
 	var userInfo UserInfo
 	if err := json.NewDecoder(resp.Body).Decode(&userInfo); err != nil {
-		return "", fmt.Errorf("failed to decode user info response: %v", err)
+		return "", fmt.Errorf("failed to decode user info response: %w", err)
 	}
 
 	c.logger.LogOperation(utils.DEBUG, "hidrive_legacy", "api", "user_info", "success", 
@@ -323,14 +396,14 @@ func (c *Client) GetUserHome() (string, error) {
 }
 
 // EnsureDirectory ensures the test directory exists
-func (c *Client) EnsureDirectory(dirPath string) error {
+func (c *Client) EnsureDirectory(ctx context.Context, dirPath string) error {
 	// Get the user's home directory from API
-	homePath, err := c.GetUserHome()
+	homePath, err := c.GetUserHome(ctx)
 	if err != nil {
 		c.logger.LogOperation(utils.ERROR, "hidrive_legacy", "api", "directory", "home_error", 
 			fmt.Sprintf("Failed to get user home directory: %v", err), 
 			map[string]interface{}{"error": err.Error()})
-		return fmt.Errorf("failed to get user home directory: %v", err)
+		return fmt.Errorf("failed to get user home directory: %w", err)
 	}
 	
 	// Remove root prefix from home path - API returns "root/users/myserver" but we need "/users/myserver"
@@ -338,19 +411,19 @@ func (c *Client) EnsureDirectory(dirPath string) error {
 	if !strings.HasPrefix(cleanHomePath, "/") {
 		cleanHomePath = "/" + cleanHomePath
 	}
-	fullPath := cleanHomePath + "/" + dirPath
+	fullPath := path.Join(cleanHomePath, dirPath)
 	
 	c.logger.LogOperation(utils.DEBUG, "hidrive_legacy", "api", "directory", "create", 
 		fmt.Sprintf("Creating directory %s (home: %s, cleanHome: %s, dirPath: %s)", fullPath, homePath, cleanHomePath, dirPath), 
 		map[string]interface{}{"full_path": fullPath, "home_path": homePath, "clean_home": cleanHomePath, "dir_path": dirPath})
 	
 	// Check if directory exists
-	req, err := c.newAPIRequest("GET", "/dir?path="+url.QueryEscape(fullPath), nil)
+	req, err := c.newAPIRequest(ctx, "GET", "/dir?path="+url.QueryEscape(fullPath), nil)
 	if err != nil {
 		c.logger.LogOperation(utils.ERROR, "hidrive_legacy", "api", "directory", "check_error", 
 			fmt.Sprintf("Failed to create directory check request: %v", err), 
 			map[string]interface{}{"error": err.Error()})
-		return fmt.Errorf("failed to create directory check request: %v", err)
+		return fmt.Errorf("failed to create directory check request: %w", err)
 	}
 
 	resp, err := c.doRequestWithRetry(req)
@@ -358,7 +431,7 @@ func (c *Client) EnsureDirectory(dirPath string) error {
 		c.logger.LogOperation(utils.ERROR, "hidrive_legacy", "api", "directory", "check_failed", 
 			fmt.Sprintf("Directory check request failed: %v", err), 
 			map[string]interface{}{"error": err.Error()})
-		return fmt.Errorf("directory check request failed: %v", err)
+		return c.wrapError("mkdir", "check", err)
 	}
 	defer resp.Body.Close()
 
@@ -377,12 +450,12 @@ func (c *Client) EnsureDirectory(dirPath string) error {
 	data := url.Values{}
 	data.Set("path", fullPath)
 
-	req, err = c.newAPIRequest("POST", "/dir", strings.NewReader(data.Encode()))
+	req, err = c.newAPIRequest(ctx, "POST", "/dir", strings.NewReader(data.Encode()))
 	if err != nil {
 		c.logger.LogOperation(utils.ERROR, "hidrive_legacy", "api", "directory", "request_error", 
 			fmt.Sprintf("Failed to create directory request: %v", err), 
 			map[string]interface{}{"error": err.Error()})
-		return fmt.Errorf("failed to create directory request: %v", err)
+		return fmt.Errorf("failed to create directory request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
@@ -391,16 +464,16 @@ func (c *Client) EnsureDirectory(dirPath string) error {
 		c.logger.LogOperation(utils.ERROR, "hidrive_legacy", "api", "directory", "creation_failed", 
 			fmt.Sprintf("Directory creation request failed: %v", err), 
 			map[string]interface{}{"error": err.Error()})
-		return fmt.Errorf("directory creation request failed: %v", err)
+		return c.wrapError("mkdir", "", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
+		err := c.statusError("mkdir", "", resp)
 		c.logger.LogOperation(utils.ERROR, "hidrive_legacy", "api", "directory", "creation_status_error", 
-			fmt.Sprintf("Directory creation failed with status %d: %s", resp.StatusCode, string(body)), 
-			map[string]interface{}{"status_code": resp.StatusCode, "response_body": string(body)})
-		return fmt.Errorf("directory creation failed with status %d: %s", resp.StatusCode, string(body))
+			fmt.Sprintf("Directory creation failed: %v", err), 
+			map[string]interface{}{"status_code": resp.StatusCode, "error": err.Error()})
+		return err
 	}
 
 	c.logger.LogOperation(utils.DEBUG, "hidrive_legacy", "api", "directory", "created", 
@@ -410,11 +483,11 @@ func (c *Client) EnsureDirectory(dirPath string) error {
 }
 
 // UploadFile uploads a file using multipart upload or chunked upload for large files
-func (c *Client) UploadFile(filePath string, reader io.Reader, size int64, chunkSize int64) error {
+func (c *Client) UploadFile(ctx context.Context, filePath string, reader io.Reader, size int64, chunkSize int64) error {
 	// Get user home directory and build full path
-	homePath, err := c.GetUserHome()
+	homePath, err := c.GetUserHome(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get user home directory: %v", err)
+		return fmt.Errorf("failed to get user home directory: %w", err)
 	}
 	
 	// Remove root prefix from home path and build full path
@@ -422,19 +495,19 @@ func (c *Client) UploadFile(filePath string, reader io.Reader, size int64, chunk
 	if !strings.HasPrefix(cleanHomePath, "/") {
 		cleanHomePath = "/" + cleanHomePath
 	}
-	fullPath := cleanHomePath + "/" + filePath
+	fullPath := path.Join(cleanHomePath, filePath)
 	c.logger.LogOperation(utils.INFO, "hidrive_legacy", "api", "upload", "start", 
 		fmt.Sprintf("Uploading file to %s (home: %s, cleanHome: %s)", fullPath, homePath, cleanHomePath), 
 		map[string]interface{}{"full_path": fullPath, "home_path": homePath, "clean_home": cleanHomePath})
 	
 	if size <= chunkSize {
-		return c.uploadSimple(fullPath, reader, size)
+		return c.uploadSimple(ctx, fullPath, reader, size)
 	}
-	return c.uploadChunked(fullPath, reader, size, chunkSize)
+	return c.uploadChunked(ctx, fullPath, reader, size, chunkSize)
 }
 
 // uploadSimple uploads a file in a single request using multipart/form-data
-func (c *Client) uploadSimple(filePath string, reader io.Reader, size int64) error {
+func (c *Client) uploadSimple(ctx context.Context, filePath string, reader io.Reader, size int64) error {
 	// Extract directory and filename from the full path
 	dirPath := path.Dir(filePath)
 	fileName := path.Base(filePath)
@@ -450,39 +523,39 @@ func (c *Client) uploadSimple(filePath string, reader io.Reader, size int64) err
 	// Add the file field with the correct filename (needed for HiDrive API)
 	fileWriter, err := writer.CreateFormFile("file", fileName)
 	if err != nil {
-		return fmt.Errorf("failed to create form file: %v", err)
+		return fmt.Errorf("failed to create form file: %w", err)
 	}
 
 	// Copy file data
 	_, err = io.Copy(fileWriter, reader)
 	if err != nil {
-		return fmt.Errorf("failed to copy file data: %v", err)
+		return fmt.Errorf("failed to copy file data: %w", err)
 	}
 
 	// Close the writer (no additional form fields needed for multipart upload)
 	err = writer.Close()
 	if err != nil {
-		return fmt.Errorf("failed to close multipart writer: %v", err)
+		return fmt.Errorf("failed to close multipart writer: %w", err)
 	}
 
 	// Create request with only dir query parameter (filename is in the multipart form)
 	endpoint := fmt.Sprintf("/file?dir=%s", url.QueryEscape(dirPath))
-	req, err := c.newAPIRequest("POST", endpoint, &requestBody)
+	req, err := c.newAPIRequest(ctx, "POST", endpoint, &requestBody)
 	if err != nil {
-		return fmt.Errorf("failed to create upload request: %v", err)
+		return fmt.Errorf("failed to create upload request: %w", err)
 	}
+	req = utils.WithHTTPOperation(req, utils.HTTPOperationUpload)
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 
 	// Execute request
 	resp, err := c.doRequestWithRetry(req)
 	if err != nil {
-		return fmt.Errorf("upload request failed: %v", err)
+		return c.wrapError("upload", "", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("upload failed with status %d: %s", resp.StatusCode, string(body))
+		return c.statusError("upload", "", resp)
 	}
 
 	c.logger.LogOperation(utils.INFO, "hidrive_legacy", "api", "upload", "completed", 
@@ -492,15 +565,15 @@ func (c *Client) uploadSimple(filePath string, reader io.Reader, size int64) err
 }
 
 // uploadChunked uploads a file using HiDrive's chunked upload (POST + PATCH)
-func (c *Client) uploadChunked(filePath string, reader io.Reader, size int64, chunkSize int64) error {
+func (c *Client) uploadChunked(ctx context.Context, filePath string, reader io.Reader, size int64, chunkSize int64) error {
 	c.logger.LogOperation(utils.INFO, "hidrive_legacy", "api", "upload", "chunked_start", 
 		fmt.Sprintf("Starting chunked upload for %s (size: %d bytes, chunk size: %d)", filePath, size, chunkSize), 
 		map[string]interface{}{"file_path": filePath, "size": size, "chunk_size": chunkSize})
 
 	// Step 1: Create empty file with POST /file
-	err := c.createEmptyFile(filePath)
+	err := c.createEmptyFile(ctx, filePath)
 	if err != nil {
-		return fmt.Errorf("failed to create empty file: %v", err)
+		return fmt.Errorf("failed to create empty file: %w", err)
 	}
 
 	// Step 2: Upload chunks sequentially using PATCH /file?offset=X
@@ -519,7 +592,7 @@ func (c *Client) uploadChunked(filePath string, reader io.Reader, size int64, ch
 		chunkData := make([]byte, currentChunkSize)
 		bytesRead, err := io.ReadFull(reader, chunkData)
 		if err != nil && err != io.ErrUnexpectedEOF {
-			return fmt.Errorf("failed to read chunk %d: %v", chunkNum, err)
+			return fmt.Errorf("failed to read chunk %d: %w", chunkNum, err)
 		}
 		
 		// Adjust slice if we read less than expected (end of file)
@@ -529,9 +602,9 @@ func (c *Client) uploadChunked(filePath string, reader io.Reader, size int64, ch
 		}
 
 		// Upload this chunk
-		err = c.uploadChunkPatch(filePath, chunkData, offset)
+		err = c.uploadChunkPatch(ctx, filePath, chunkData, offset)
 		if err != nil {
-			return fmt.Errorf("failed to upload chunk %d at offset %d: %v", chunkNum, offset, err)
+			return fmt.Errorf("failed to upload chunk %d at offset %d: %w", chunkNum, offset, err)
 		}
 
 		c.logger.LogOperation(utils.DEBUG, "hidrive_legacy", "api", "upload", "chunk_progress", 
@@ -549,7 +622,7 @@ func (c *Client) uploadChunked(filePath string, reader io.Reader, size int64, ch
 }
 
 // createEmptyFile creates an empty file on HiDrive using POST /file
-func (c *Client) createEmptyFile(filePath string) error {
+func (c *Client) createEmptyFile(ctx context.Context, filePath string) error {
 	// Extract directory and filename from the full path
 	dirPath := path.Dir(filePath)
 	fileName := path.Base(filePath)
@@ -564,38 +637,37 @@ func (c *Client) createEmptyFile(filePath string) error {
 	// Add empty file field with correct filename
 	fileWriter, err := writer.CreateFormFile("file", fileName)
 	if err != nil {
-		return fmt.Errorf("failed to create form file: %v", err)
+		return fmt.Errorf("failed to create form file: %w", err)
 	}
 	
 	// Write empty content
 	_, err = fileWriter.Write([]byte{})
 	if err != nil {
-		return fmt.Errorf("failed to write empty content: %v", err)
+		return fmt.Errorf("failed to write empty content: %w", err)
 	}
 
 	err = writer.Close()
 	if err != nil {
-		return fmt.Errorf("failed to close multipart writer: %v", err)
+		return fmt.Errorf("failed to close multipart writer: %w", err)
 	}
 
 	// Create POST request with only dir query parameter (filename is in multipart form)
 	endpoint := fmt.Sprintf("/file?dir=%s", url.QueryEscape(dirPath))
-	req, err := c.newAPIRequest("POST", endpoint, &requestBody)
+	req, err := c.newAPIRequest(ctx, "POST", endpoint, &requestBody)
 	if err != nil {
-		return fmt.Errorf("failed to create empty file request: %v", err)
+		return fmt.Errorf("failed to create empty file request: %w", err)
 	}
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 
 	// Execute request
 	resp, err := c.doRequestWithRetry(req)
 	if err != nil {
-		return fmt.Errorf("empty file creation request failed: %v", err)
+		return c.wrapError("upload", "create_file", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("empty file creation failed with status %d: %s", resp.StatusCode, string(body))
+		return c.statusError("upload", "create_file", resp)
 	}
 
 	c.logger.LogOperation(utils.INFO, "hidrive_legacy", "api", "file", "create_empty_success", 
@@ -605,17 +677,18 @@ func (c *Client) createEmptyFile(filePath string) error {
 }
 
 // uploadChunkPatch uploads a chunk using PATCH /file?offset=X
-func (c *Client) uploadChunkPatch(filePath string, chunkData []byte, offset int64) error {
+func (c *Client) uploadChunkPatch(ctx context.Context, filePath string, chunkData []byte, offset int64) error {
 	// Create PATCH request with binary data
 	requestBody := bytes.NewReader(chunkData)
 	
 	// Build URL with offset parameter
 	endpoint := fmt.Sprintf("/file?path=%s&offset=%d", url.QueryEscape(filePath), offset)
 	
-	req, err := c.newAPIRequest("PATCH", endpoint, requestBody)
+	req, err := c.newAPIRequest(ctx, "PATCH", endpoint, requestBody)
 	if err != nil {
-		return fmt.Errorf("failed to create PATCH request: %v", err)
+		return fmt.Errorf("failed to create PATCH request: %w", err)
 	}
+	req = utils.WithHTTPOperation(req, utils.HTTPOperationChunk)
 	
 	// Set content type to application/octet-stream (required for PATCH)
 	req.Header.Set("Content-Type", "application/octet-stream")
@@ -624,24 +697,23 @@ func (c *Client) uploadChunkPatch(filePath string, chunkData []byte, offset int6
 	// Execute request
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("PATCH request failed: %v", err)
+		return c.wrapError("upload", "chunk", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("PATCH upload failed with status %d: %s", resp.StatusCode, string(body))
+		return c.statusError("upload", "chunk", resp)
 	}
 
 	return nil
 }
 
 // DownloadFile downloads a file from HiDrive
-func (c *Client) DownloadFile(filePath string) (io.ReadCloser, error) {
+func (c *Client) DownloadFile(ctx context.Context, filePath string) (io.ReadCloser, error) {
 	// Get user home directory and build full path
-	homePath, err := c.GetUserHome()
+	homePath, err := c.GetUserHome(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user home directory: %v", err)
+		return nil, fmt.Errorf("failed to get user home directory: %w", err)
 	}
 	
 	// Remove root prefix from home path and build full path
@@ -649,25 +721,25 @@ func (c *Client) DownloadFile(filePath string) (io.ReadCloser, error) {
 	if !strings.HasPrefix(cleanHomePath, "/") {
 		cleanHomePath = "/" + cleanHomePath
 	}
-	fullPath := cleanHomePath + "/" + filePath
+	fullPath := path.Join(cleanHomePath, filePath)
 	c.logger.LogOperation(utils.DEBUG, "hidrive_legacy", "api", "download", "path_constructed", 
 		fmt.Sprintf("Downloading file from %s (home: %s, cleanHome: %s)", fullPath, homePath, cleanHomePath), 
 		map[string]interface{}{"full_path": fullPath, "home_path": homePath, "clean_home": cleanHomePath, "file_path": filePath})
 	
-	req, err := c.newAPIRequest("GET", "/file?path="+url.QueryEscape(fullPath), nil)
+	req, err := c.newAPIRequest(ctx, "GET", "/file?path="+url.QueryEscape(fullPath), nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create download request: %v", err)
+		return nil, fmt.Errorf("failed to create download request: %w", err)
 	}
+	req = utils.WithHTTPOperation(req, utils.HTTPOperationDownload)
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("download request failed: %v", err)
+		return nil, c.wrapError("download", "", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		defer resp.Body.Close()
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("download failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, c.statusError("download", "", resp)
 	}
 
 	c.logger.LogOperation(utils.INFO, "hidrive_legacy", "api", "download", "started", 
@@ -677,11 +749,11 @@ func (c *Client) DownloadFile(filePath string) (io.ReadCloser, error) {
 }
 
 // DeleteFile deletes a file from HiDrive
-func (c *Client) DeleteFile(filePath string) error {
+func (c *Client) DeleteFile(ctx context.Context, filePath string) error {
 	// Get user home directory and build full path
-	homePath, err := c.GetUserHome()
+	homePath, err := c.GetUserHome(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get user home directory: %v", err)
+		return fmt.Errorf("failed to get user home directory: %w", err)
 	}
 	
 	// Remove root prefix from home path and build full path
@@ -689,7 +761,7 @@ func (c *Client) DeleteFile(filePath string) error {
 	if !strings.HasPrefix(cleanHomePath, "/") {
 		cleanHomePath = "/" + cleanHomePath
 	}
-	fullPath := cleanHomePath + "/" + filePath
+	fullPath := path.Join(cleanHomePath, filePath)
 	c.logger.LogOperation(utils.DEBUG, "hidrive_legacy", "api", "delete", "path_constructed", 
 		fmt.Sprintf("Deleting file %s (home: %s, cleanHome: %s)", fullPath, homePath, cleanHomePath), 
 		map[string]interface{}{"full_path": fullPath, "home_path": homePath, "clean_home": cleanHomePath, "file_path": filePath})
@@ -697,21 +769,20 @@ func (c *Client) DeleteFile(filePath string) error {
 	data := url.Values{}
 	data.Set("path", fullPath)
 
-	req, err := c.newAPIRequest("DELETE", "/file", strings.NewReader(data.Encode()))
+	req, err := c.newAPIRequest(ctx, "DELETE", "/file", strings.NewReader(data.Encode()))
 	if err != nil {
-		return fmt.Errorf("failed to create delete request: %v", err)
+		return fmt.Errorf("failed to create delete request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("delete request failed: %v", err)
+		return c.wrapError("delete", "", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("delete failed with status %d: %s", resp.StatusCode, string(body))
+		return c.statusError("delete", "", resp)
 	}
 
 	c.logger.LogOperation(utils.INFO, "hidrive_legacy", "api", "delete", "success", 
@@ -720,50 +791,131 @@ func (c *Client) DeleteFile(filePath string) error {
 	return nil
 }
 
+// ListDirectory returns the names of the members of a directory
+func (c *Client) ListDirectory(ctx context.Context, dirPath string) ([]string, error) {
+	// Get user home directory and build full path
+	homePath, err := c.GetUserHome(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user home directory: %w", err)
+	}
+	
+	// Remove root prefix from home path and build full path
+	cleanHomePath := strings.TrimPrefix(homePath, "root")
+	if !strings.HasPrefix(cleanHomePath, "/") {
+		cleanHomePath = "/" + cleanHomePath
+	}
+	fullPath := path.Join(cleanHomePath, dirPath)
+
+	req, err := c.newAPIRequest(ctx, "GET", "/dir?path="+url.QueryEscape(fullPath)+"&members=all&fields=members.name", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create directory list request: %w", err)
+	}
+
+	resp, err := c.doRequestWithRetry(req)
+	if err != nil {
+		return nil, c.wrapError("list", "", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.statusError("list", "", resp)
+	}
+
+	var dirInfo DirectoryInfo
+	if err := json.NewDecoder(resp.Body).Decode(&dirInfo); err != nil {
+		return nil, fmt.Errorf("failed to decode directory list response: %w", err)
+	}
+
+	names := make([]string, 0, len(dirInfo.Files))
+	for _, member := range dirInfo.Files {
+		names = append(names, member.Name)
+	}
+
+	c.logger.LogOperation(utils.DEBUG, "hidrive_legacy", "api", "list", "success", 
+		fmt.Sprintf("Listed %d entries in %s", len(names), fullPath), 
+		map[string]interface{}{"full_path": fullPath, "entries": len(names)})
+	return names, nil
+}
+
+// StatFile returns the size of a remote file using the /meta endpoint
+func (c *Client) StatFile(ctx context.Context, filePath string) (int64, error) {
+	// Get user home directory and build full path
+	homePath, err := c.GetUserHome(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get user home directory: %w", err)
+	}
+	
+	// Remove root prefix from home path and build full path
+	cleanHomePath := strings.TrimPrefix(homePath, "root")
+	if !strings.HasPrefix(cleanHomePath, "/") {
+		cleanHomePath = "/" + cleanHomePath
+	}
+	fullPath := path.Join(cleanHomePath, filePath)
+
+	req, err := c.newAPIRequest(ctx, "GET", "/meta?path="+url.QueryEscape(fullPath)+"&fields=name,type,size", nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create meta request: %w", err)
+	}
+
+	resp, err := c.doRequestWithRetry(req)
+	if err != nil {
+		return 0, c.wrapError("stat", "", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, c.statusError("stat", "", resp)
+	}
+
+	var fileInfo FileInfo
+	if err := json.NewDecoder(resp.Body).Decode(&fileInfo); err != nil {
+		return 0, fmt.Errorf("failed to decode meta response: %w", err)
+	}
+	return fileInfo.Size, nil
+}
+
 // GetFileInfo gets metadata for a file
-func (c *Client) GetFileInfo(filePath string) (*FileInfo, error) {
+func (c *Client) GetFileInfo(ctx context.Context, filePath string) (*FileInfo, error) {
 	cleanPath := strings.TrimPrefix(filePath, "/")
 	
-	req, err := c.newAPIRequest("GET", "/file?path="+url.QueryEscape(cleanPath)+"&fields=id,name,type,size,modified,path", nil)
+	req, err := c.newAPIRequest(ctx, "GET", "/file?path="+url.QueryEscape(cleanPath)+"&fields=id,name,type,size,modified,path", nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create file info request: %v", err)
+		return nil, fmt.Errorf("failed to create file info request: %w", err)
 	}
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("file info request failed: %v", err)
+		return nil, c.wrapError("stat", "file_info", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("file info failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, c.statusError("stat", "file_info", resp)
 	}
 
 	var fileInfo FileInfo
 	if err := json.NewDecoder(resp.Body).Decode(&fileInfo); err != nil {
-		return nil, fmt.Errorf("failed to decode file info response: %v", err)
+		return nil, fmt.Errorf("failed to decode file info response: %w", err)
 	}
 
 	return &fileInfo, nil
 }
 
 // TestConnection tests the connection to HiDrive API
-func (c *Client) TestConnection() error {
-	req, err := c.newAPIRequest("GET", "/app/me?fields=id,name", nil)
+func (c *Client) TestConnection(ctx context.Context) error {
+	req, err := c.newAPIRequest(ctx, "GET", "/app/me?fields=id,name", nil)
 	if err != nil {
-		return fmt.Errorf("failed to create test request: %v", err)
+		return fmt.Errorf("failed to create test request: %w", err)
 	}
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("test request failed: %v", err)
+		return c.wrapError("connection_test", "", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("connection test failed with status %d: %s", resp.StatusCode, string(body))
+		return c.statusError("connection_test", "", resp)
 	}
 
 	c.logger.LogOperation(utils.INFO, "hidrive_legacy", "api", "connection", "test_success", 
@@ -1,6 +1,7 @@
 package hidrive_legacy
 
 import (
+	"context"
 	"testing"
 )
 
@@ -26,7 +27,7 @@ func TestNewClientWithOAuth2(t *testing.T) {
 	}()
 
 	// This will fail due to invalid credentials, but should not panic
-	_, err := NewClientWithOAuth2("invalid_refresh_token", "invalid_client_id", "invalid_client_secret")
+	_, err := NewClientWithOAuth2(context.Background(), "invalid_refresh_token", "invalid_client_id", "invalid_client_secret")
 	if err == nil {
 		t.Error("Expected NewClientWithOAuth2 to fail with invalid credentials")
 	}
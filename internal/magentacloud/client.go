@@ -2,6 +2,7 @@ package magentacloud
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -43,9 +44,9 @@ func NewClient(baseURL, username, password, anid string) *Client {
 }
 
 // newRequest is a helper to create authenticated WebDAV requests
-func (c *Client) newRequest(method, urlPath string, body io.Reader) (*http.Request, error) {
+func (c *Client) newRequest(ctx context.Context, method, urlPath string, body io.Reader) (*http.Request, error) {
 	fullURL := c.BaseURL + urlPath
-	req, err := http.NewRequest(method, fullURL, body)
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, body)
 	if err != nil {
 		return nil, err
 	}
@@ -59,30 +60,46 @@ func (c *Client) newRequest(method, urlPath string, body io.Reader) (*http.Reque
 	return req, nil
 }
 
+// statusError returns the typed error of a response with an unexpected status
+func (c *Client) statusError(operation, phase string, resp *http.Response) error {
+	return utils.NewStatusError("magentacloud", operation, phase, resp, utils.ParseWebDAVErrorTag)
+}
+
+// wrapError annotates a request error with the operation it occurred in
+func (c *Client) wrapError(operation, phase string, err error) error {
+	return utils.WrapProviderError("magentacloud", operation, phase, err)
+}
+
+// CloseIdleConnections closes the kept-alive connections of the client, so that the next
+// request has to connect again
+func (c *Client) CloseIdleConnections() {
+	c.HTTPClient.CloseIdleConnections()
+}
+
 // EnsureDirectory ensures the test directory exists
 // Uses ANID in path: /remote.php/dav/files/{ANID}/path
-func (c *Client) EnsureDirectory(dirPath string) error {
+func (c *Client) EnsureDirectory(ctx context.Context, dirPath string) error {
 	fullPath := path.Join("/remote.php/dav/files/", c.ANID, dirPath)
-	req, err := c.newRequest("MKCOL", fullPath, nil)
+	req, err := c.newRequest(ctx, "MKCOL", fullPath, nil)
 	if err != nil {
 		return err
 	}
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return err
+		return c.wrapError("mkdir", "", err)
 	}
 	defer resp.Body.Close()
 
 	// 405 is returned if the directory already exists, which is not an error for us.
 	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusMethodNotAllowed {
-		return fmt.Errorf("failed to create directory %s, status: %s", dirPath, resp.Status)
+		return c.statusError("mkdir", "", resp)
 	}
 	return nil
 }
 
 // UploadFile uploads a file using the chunking API
 // Uses ANID in both upload and destination paths
-func (c *Client) UploadFile(filePath string, reader io.Reader, size int64, chunkSize int64) error {
+func (c *Client) UploadFile(ctx context.Context, filePath string, reader io.Reader, size int64, chunkSize int64) error {
 	transferID := uuid.New().String()
 	chunkDir := path.Join("/remote.php/dav/uploads/", c.ANID, transferID)
 	destinationURL := c.BaseURL + path.Join("/remote.php/dav/files/", c.ANID, filePath)
@@ -92,14 +109,14 @@ func (c *Client) UploadFile(filePath string, reader io.Reader, size int64, chunk
 		map[string]interface{}{"file_path": filePath, "size": size, "chunk_size": chunkSize, "transfer_id": transferID})
 
 	// 1. Create temporary directory for chunks on the server
-	if err := c.createChunkDirectory(chunkDir, destinationURL); err != nil {
+	if err := c.createChunkDirectory(ctx, chunkDir, destinationURL); err != nil {
 		return fmt.Errorf("failed to create chunk directory: %w", err)
 	}
 
 	// 2. Upload file in chunks
 	c.logger.LogOperation(utils.INFO, "magentacloud", c.BaseURL, "chunk_upload", "start", 
 		"Starting chunk upload phase", nil)
-	if err := c.uploadChunks(chunkDir, reader, chunkSize, destinationURL, size); err != nil {
+	if err := c.uploadChunks(ctx, chunkDir, reader, chunkSize, destinationURL, size); err != nil {
 		c.logger.LogOperation(utils.ERROR, "magentacloud", c.BaseURL, "chunk_upload", "failed", 
 			fmt.Sprintf("Chunk upload failed: %v", err), 
 			map[string]interface{}{"error": err.Error()})
@@ -114,7 +131,7 @@ func (c *Client) UploadFile(filePath string, reader io.Reader, size int64, chunk
 		map[string]interface{}{"source": c.BaseURL + chunkDir + "/.file", "destination": destinationURL})
 	moveSource := c.BaseURL + chunkDir + "/.file"
 	
-	req, err := http.NewRequest("MOVE", moveSource, nil)
+	req, err := http.NewRequestWithContext(ctx, "MOVE", moveSource, nil)
 	if err != nil {
 		return fmt.Errorf("could not create MOVE request: %w", err)
 	}
@@ -144,17 +161,17 @@ func (c *Client) UploadFile(filePath string, reader io.Reader, size int64, chunk
 		c.logger.LogOperation(utils.ERROR, "magentacloud", c.BaseURL, "move", "failed", 
 			fmt.Sprintf("MOVE request failed after %v: %v", moveDuration, err), 
 			map[string]interface{}{"duration": moveDuration, "error": err.Error()})
-		return fmt.Errorf("MOVE request failed after %v: %w", moveDuration, err)
+		return c.wrapError("upload", "assemble", fmt.Errorf("MOVE request failed after %v: %w", moveDuration, err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent {
 		// Read response body for more detailed error information
-		body, _ := io.ReadAll(resp.Body)
+		err := c.statusError("upload", "assemble", resp)
 		c.logger.LogOperation(utils.ERROR, "magentacloud", c.BaseURL, "move", "status_error", 
-			fmt.Sprintf("MOVE operation failed with status %s, response: %s", resp.Status, string(body)), 
-			map[string]interface{}{"status_code": resp.StatusCode, "response_body": string(body)})
-		return fmt.Errorf("final MOVE to assemble chunks failed with status %s, response: %s", resp.Status, string(body))
+			fmt.Sprintf("MOVE operation failed: %v", err), 
+			map[string]interface{}{"status_code": resp.StatusCode, "error": err.Error()})
+		return err
 	}
 
 	c.logger.LogOperation(utils.INFO, "magentacloud", c.BaseURL, "move", "completed", 
@@ -167,7 +184,7 @@ func (c *Client) UploadFile(filePath string, reader io.Reader, size int64, chunk
 }
 
 // uploadChunks uploads the file in chunks to the server
-func (c *Client) uploadChunks(chunkDir string, reader io.Reader, chunkSize int64, destinationURL string, totalSize int64) error {
+func (c *Client) uploadChunks(ctx context.Context, chunkDir string, reader io.Reader, chunkSize int64, destinationURL string, totalSize int64) error {
 	chunk := make([]byte, chunkSize)
 	totalChunks := 0
 	successfulChunks := 0
@@ -190,107 +207,40 @@ func (c *Client) uploadChunks(chunkDir string, reader io.Reader, chunkSize int64
 				fmt.Sprintf("Uploading chunk %d: %d bytes to %s", chunkNumber, bytesRead, chunkPath), 
 				map[string]interface{}{"chunk_number": chunkNumber, "bytes": bytesRead, "chunk_path": chunkPath})
 
-			// Retry logic for individual chunks
-			var resp *http.Response
-			var chunkErr error
-			maxRetries := 3
+			// Transient failures are retried by the client's transport (shared retry policy);
+			// only a 409 Conflict caused by a leftover chunk is resolved here
 			chunkStart := time.Now()
-			for attempt := 1; attempt <= maxRetries; attempt++ {
-				req, err := http.NewRequest("PUT", chunkURL, bytes.NewReader(chunk[:bytesRead]))
+			resp, err := c.putChunk(ctx, chunkURL, destinationURL, chunk[:bytesRead], totalSize, false)
+			if err != nil {
+				return c.wrapError("upload", "chunk", fmt.Errorf("PUT request for chunk %d failed: %w", chunkNumber, err))
+			}
+			if resp.StatusCode == http.StatusConflict {
+				resp.Body.Close()
+				c.logger.LogOperation(utils.WARN, "magentacloud", c.BaseURL, "chunk_upload", "conflict", 
+					fmt.Sprintf("PUT request for chunk %d failed with 409 Conflict, trying to overwrite existing chunk: %s", chunkNumber, chunkPath), 
+					map[string]interface{}{"chunk_number": chunkNumber, "chunk_path": chunkPath})
+				c.deleteConflictingChunk(ctx, chunkPath)
+
+				resp, err = c.putChunk(ctx, chunkURL, destinationURL, chunk[:bytesRead], totalSize, true)
 				if err != nil {
-					if attempt == maxRetries {
-						return fmt.Errorf("could not create PUT request for chunk %d after %d attempts: %w", chunkNumber, maxRetries, err)
-					}
-					continue
-				}
-				req.SetBasicAuth(c.Username, c.Password)
-				req.Header.Set("User-Agent", MagentaCloudUserAgent)
-				req.Header.Set("Accept", "*/*")
-				req.Header.Set("Accept-Language", "en-US,en;q=0.9")
-				req.Header.Set("Connection", "keep-alive")
-				
-				// On retry attempts after 409 Conflict, force overwrite with If-Match header
-				if attempt > 1 {
-					req.Header.Set("If-Match", "*")
-					c.logger.LogOperation(utils.INFO, "magentacloud", c.BaseURL, "chunk_upload", "retry_overwrite", 
-						fmt.Sprintf("Retry attempt %d for chunk %d with If-Match header to force overwrite", attempt, chunkNumber), 
-						map[string]interface{}{"chunk_number": chunkNumber, "attempt": attempt})
-				}
-				req.Header.Set("Content-Type", "application/octet-stream")
-				// CRITICAL: Add Destination header like bash script does for each chunk!
-				req.Header.Set("Destination", destinationURL)
-				// CRITICAL: Add OC-Total-Length header as required by Nextcloud Chunking v2
-				req.Header.Set("OC-Total-Length", fmt.Sprintf("%d", totalSize))
-				req.ContentLength = int64(bytesRead)
-
-				resp, chunkErr = c.HTTPClient.Do(req)
-				if chunkErr != nil {
-					if attempt < maxRetries {
-						time.Sleep(time.Duration(attempt) * time.Second) // Progressive backoff
-						continue
-					}
-					return fmt.Errorf("PUT request for chunk %d failed after %d attempts: %w", chunkNumber, maxRetries, chunkErr)
+					return c.wrapError("upload", "chunk", fmt.Errorf("PUT request for chunk %d failed: %w", chunkNumber, err))
 				}
+			}
 
-				// Check response status - Accept both 201 Created, 200 OK and 204 No Content
-				if resp.StatusCode == http.StatusCreated || resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusOK {
-					chunkDuration := time.Since(chunkStart)
-					c.logger.LogOperation(utils.DEBUG, "magentacloud", c.BaseURL, "chunk_upload", "success", 
-						fmt.Sprintf("Chunk %d uploaded successfully in %v (status: %s)", chunkNumber, chunkDuration, resp.Status), 
-						map[string]interface{}{"chunk_number": chunkNumber, "duration": chunkDuration, "status_code": resp.StatusCode})
-					break // Success
-				} else {
-					// Read response body for detailed error information
-					body, _ := io.ReadAll(resp.Body)
-					resp.Body.Close()
-					
-					// Handle 409 Conflict - try to overwrite the existing chunk
-					if resp.StatusCode == http.StatusConflict && attempt < maxRetries {
-						c.logger.LogOperation(utils.WARN, "magentacloud", c.BaseURL, "chunk_upload", "conflict", 
-							fmt.Sprintf("PUT request for chunk %d failed with 409 Conflict (attempt %d/%d), trying to overwrite existing chunk: %s", chunkNumber, attempt, maxRetries, chunkPath), 
-							map[string]interface{}{"chunk_number": chunkNumber, "attempt": attempt, "chunk_path": chunkPath})
-						
-						// Try different approaches to resolve the conflict
-						resolved := false
-						
-						// Approach 1: Try DELETE with different headers
-						deleteReq, err := c.newRequest("DELETE", chunkPath, nil)
-						if err == nil {
-							deleteReq.Header.Set("If-Match", "*") // Force delete regardless of etag
-							deleteResp, err := c.HTTPClient.Do(deleteReq)
-							if err == nil {
-								deleteResp.Body.Close()
-								if deleteResp.StatusCode == http.StatusNoContent || deleteResp.StatusCode == http.StatusNotFound {
-									resolved = true
-									c.logger.LogOperation(utils.INFO, "magentacloud", c.BaseURL, "chunk_upload", "delete_success", 
-										"Successfully deleted conflicting chunk with If-Match header", 
-										map[string]interface{}{"chunk_path": chunkPath, "status_code": deleteResp.StatusCode})
-								}
-							}
-						}
-						
-						// Approach 2: If DELETE didn't work, try PUT with If-Match header to overwrite
-						if !resolved {
-							c.logger.LogOperation(utils.INFO, "magentacloud", c.BaseURL, "chunk_upload", "retry_overwrite", 
-								"Attempting to overwrite conflicting chunk with If-Match header", 
-								map[string]interface{}{"chunk_path": chunkPath})
-							// The next iteration will try PUT with If-Match header (see below)
-						}
-						
-						time.Sleep(time.Duration(attempt) * time.Second) // Progressive backoff
-						continue
-					}
-					
-					if attempt < maxRetries {
-						c.logger.LogOperation(utils.ERROR, "magentacloud", c.BaseURL, "chunk_upload", "status_error", 
-							fmt.Sprintf("PUT request for chunk %d failed (attempt %d/%d) with status %s: %s", chunkNumber, attempt, maxRetries, resp.Status, string(body)), 
-							map[string]interface{}{"chunk_number": chunkNumber, "attempt": attempt, "max_retries": maxRetries, "status_code": resp.StatusCode, "response_body": string(body)})
-						time.Sleep(time.Duration(attempt) * time.Second) // Progressive backoff
-						continue
-					}
-					return fmt.Errorf("upload of chunk %d failed with status %s after %d attempts, response: %s", chunkNumber, resp.Status, maxRetries, string(body))
-				}
+			// Check response status - Accept both 201 Created, 200 OK and 204 No Content
+			if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+				// Read response body for detailed error information
+				err := c.statusError("upload", "chunk", resp)
+				resp.Body.Close()
+				c.logger.LogOperation(utils.ERROR, "magentacloud", c.BaseURL, "chunk_upload", "status_error", 
+					fmt.Sprintf("PUT request for chunk %d failed: %v", chunkNumber, err), 
+					map[string]interface{}{"chunk_number": chunkNumber, "status_code": resp.StatusCode, "error": err.Error()})
+				return err
 			}
+			chunkDuration := time.Since(chunkStart)
+			c.logger.LogOperation(utils.DEBUG, "magentacloud", c.BaseURL, "chunk_upload", "success", 
+				fmt.Sprintf("Chunk %d uploaded successfully in %v (status: %s)", chunkNumber, chunkDuration, resp.Status), 
+				map[string]interface{}{"chunk_number": chunkNumber, "duration": chunkDuration, "status_code": resp.StatusCode})
 			
 			// Immediately close response body to avoid resource leaks
 			resp.Body.Close()
@@ -313,43 +263,99 @@ func (c *Client) uploadChunks(chunkDir string, reader io.Reader, chunkSize int64
 
 // DownloadFile downloads a file
 // Uses ANID in path: /remote.php/dav/files/{ANID}/path
-func (c *Client) DownloadFile(filePath string) (io.ReadCloser, error) {
+func (c *Client) DownloadFile(ctx context.Context, filePath string) (io.ReadCloser, error) {
 	c.logger.LogOperation(utils.INFO, "magentacloud", c.BaseURL, "download", "started", 
 		fmt.Sprintf("Download started for %s", filePath), 
 		map[string]interface{}{"file_path": filePath})
 
 	fullPath := path.Join("/remote.php/dav/files/", c.ANID, filePath)
-	req, err := c.newRequest("GET", fullPath, nil)
+	req, err := c.newRequest(ctx, "GET", fullPath, nil)
 	if err != nil {
 		return nil, err
 	}
+	req = utils.WithHTTPOperation(req, utils.HTTPOperationDownload)
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, c.wrapError("download", "", err)
 	}
 	if resp.StatusCode != http.StatusOK {
-		resp.Body.Close()
-		return nil, fmt.Errorf("download failed, status: %s", resp.Status)
+		defer resp.Body.Close()
+		return nil, c.statusError("download", "", resp)
 	}
 	return resp.Body, nil
 }
 
+// ListDirectory returns the names of the entries in a directory (PROPFIND with Depth: 1)
+// Uses ANID in path: /remote.php/dav/files/{ANID}/path
+func (c *Client) ListDirectory(ctx context.Context, dirPath string) ([]string, error) {
+	fullPath := path.Join("/remote.php/dav/files/", c.ANID, dirPath)
+	req, err := c.newRequest(ctx, "PROPFIND", fullPath, strings.NewReader(utils.PropfindBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Depth", "1")
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, c.wrapError("list", "", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, c.statusError("list", "", resp)
+	}
+	resources, err := utils.ParseMultistatus(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PROPFIND response: %w", err)
+	}
+	return utils.DAVChildNames(resources, fullPath), nil
+}
+
+// StatFile returns the size of a remote file (PROPFIND with Depth: 0)
+// Uses ANID in path: /remote.php/dav/files/{ANID}/path
+func (c *Client) StatFile(ctx context.Context, filePath string) (int64, error) {
+	fullPath := path.Join("/remote.php/dav/files/", c.ANID, filePath)
+	req, err := c.newRequest(ctx, "PROPFIND", fullPath, strings.NewReader(utils.PropfindBody))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Depth", "0")
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return 0, c.wrapError("stat", "", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMultiStatus {
+		return 0, c.statusError("stat", "", resp)
+	}
+	resources, err := utils.ParseMultistatus(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse PROPFIND response: %w", err)
+	}
+	if len(resources) == 0 {
+		return 0, fmt.Errorf("PROPFIND returned no properties for %s", filePath)
+	}
+	return resources[0].ContentLength, nil
+}
+
 // DeleteFile deletes a file or directory
 // Uses ANID in path: /remote.php/dav/files/{ANID}/path
-func (c *Client) DeleteFile(filePath string) error {
+func (c *Client) DeleteFile(ctx context.Context, filePath string) error {
 	fullPath := path.Join("/remote.php/dav/files/", c.ANID, filePath)
-	req, err := c.newRequest("DELETE", fullPath, nil)
+	req, err := c.newRequest(ctx, "DELETE", fullPath, nil)
 	if err != nil {
 		return err
 	}
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return err
+		return c.wrapError("delete", "", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusNoContent {
-		return fmt.Errorf("delete failed, status: %s", resp.Status)
+		return c.statusError("delete", "", resp)
 	}
 
 	c.logger.LogOperation(utils.INFO, "magentacloud", c.BaseURL, "delete", "success", 
@@ -360,14 +366,14 @@ func (c *Client) DeleteFile(filePath string) error {
 
 // DeleteChunkFile deletes a chunk file during upload conflict resolution
 // More tolerant of various status codes since it's used for cleanup
-func (c *Client) DeleteChunkFile(chunkPath string) error {
-	req, err := c.newRequest("DELETE", chunkPath, nil)
+func (c *Client) DeleteChunkFile(ctx context.Context, chunkPath string) error {
+	req, err := c.newRequest(ctx, "DELETE", chunkPath, nil)
 	if err != nil {
 		return err
 	}
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return err
+		return c.wrapError("delete", "chunk", err)
 	}
 	defer resp.Body.Close()
 
@@ -375,8 +381,7 @@ func (c *Client) DeleteChunkFile(chunkPath string) error {
 	// 409 Conflict is acceptable for chunk cleanup - it means the chunk couldn't be deleted but that's ok
 	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound && 
 	   resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusConflict {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("chunk file delete failed, status: %s, response: %s", resp.Status, string(body))
+		return c.statusError("delete", "chunk", resp)
 	}
 
 	c.logger.LogOperation(utils.INFO, "magentacloud", c.BaseURL, "delete_chunk", "success", 
@@ -387,7 +392,7 @@ func (c *Client) DeleteChunkFile(chunkPath string) error {
 
 // DeleteDirectory deletes a directory (including chunk upload directories)
 // Accepts both file paths and upload paths
-func (c *Client) DeleteDirectory(dirPath string) error {
+func (c *Client) DeleteDirectory(ctx context.Context, dirPath string) error {
 	// If it's not an absolute WebDAV path, assume it's a file path
 	var fullPath string
 	if !strings.HasPrefix(dirPath, "/remote.php/dav/") {
@@ -396,20 +401,19 @@ func (c *Client) DeleteDirectory(dirPath string) error {
 		fullPath = dirPath
 	}
 	
-	req, err := c.newRequest("DELETE", fullPath, nil)
+	req, err := c.newRequest(ctx, "DELETE", fullPath, nil)
 	if err != nil {
 		return err
 	}
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return err
+		return c.wrapError("delete", "directory", err)
 	}
 	defer resp.Body.Close()
 
 	// Accept both 204 No Content and 404 Not Found (directory already gone)
 	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("directory delete failed, status: %s, response: %s", resp.Status, string(body))
+		return c.statusError("delete", "directory", resp)
 	}
 
 	c.logger.LogOperation(utils.INFO, "magentacloud", c.BaseURL, "delete_directory", "success", 
@@ -418,77 +422,113 @@ func (c *Client) DeleteDirectory(dirPath string) error {
 	return nil
 }
 
+// putChunk uploads a single chunk. With overwrite set, an existing chunk is replaced
+// (If-Match: *), which resolves a 409 Conflict left over from an earlier attempt.
+func (c *Client) putChunk(ctx context.Context, chunkURL, destinationURL string, data []byte, totalSize int64, overwrite bool) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "PUT", chunkURL, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("could not create PUT request: %w", err)
+	}
+	req.SetBasicAuth(c.Username, c.Password)
+	req.Header.Set("User-Agent", MagentaCloudUserAgent)
+	req.Header.Set("Accept", "*/*")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	req.Header.Set("Connection", "keep-alive")
+	if overwrite {
+		req.Header.Set("If-Match", "*")
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	// CRITICAL: Add Destination header like bash script does for each chunk!
+	req.Header.Set("Destination", destinationURL)
+	// CRITICAL: Add OC-Total-Length header as required by Nextcloud Chunking v2
+	req.Header.Set("OC-Total-Length", fmt.Sprintf("%d", totalSize))
+	req.ContentLength = int64(len(data))
+	req = utils.WithHTTPOperation(req, utils.HTTPOperationChunk)
+
+	return c.HTTPClient.Do(req)
+}
+
+// deleteConflictingChunk removes a chunk that blocks a PUT with 409 Conflict. Failures are
+// only logged; the following overwrite attempt reports the actual error.
+func (c *Client) deleteConflictingChunk(ctx context.Context, chunkPath string) {
+	deleteReq, err := c.newRequest(ctx, "DELETE", chunkPath, nil)
+	if err != nil {
+		return
+	}
+	deleteReq.Header.Set("If-Match", "*") // Force delete regardless of etag
+	deleteResp, err := c.HTTPClient.Do(deleteReq)
+	if err != nil {
+		return
+	}
+	deleteResp.Body.Close()
+	if deleteResp.StatusCode == http.StatusNoContent || deleteResp.StatusCode == http.StatusNotFound {
+		c.logger.LogOperation(utils.INFO, "magentacloud", c.BaseURL, "chunk_upload", "delete_success", 
+			"Successfully deleted conflicting chunk with If-Match header", 
+			map[string]interface{}{"chunk_path": chunkPath, "status_code": deleteResp.StatusCode})
+		return
+	}
+	c.logger.LogOperation(utils.INFO, "magentacloud", c.BaseURL, "chunk_upload", "retry_overwrite", 
+		"Attempting to overwrite conflicting chunk with If-Match header", 
+		map[string]interface{}{"chunk_path": chunkPath})
+}
+
 // createChunkDirectory creates a chunk upload directory on the server
 // Used for initial setup and recreation after cleanup
-func (c *Client) createChunkDirectory(chunkDir, destinationURL string) error {
+func (c *Client) createChunkDirectory(ctx context.Context, chunkDir, destinationURL string) error {
 	chunkDirURL := c.BaseURL + chunkDir
 	
 	c.logger.LogOperation(utils.DEBUG, "magentacloud", c.BaseURL, "mkcol", "start", 
 		fmt.Sprintf("Creating chunk directory: %s", chunkDir), 
 		map[string]interface{}{"chunk_dir": chunkDir})
 	
-	maxMkcolRetries := 2
-	var mkcolDuration time.Duration
-	for mkcolAttempt := 1; mkcolAttempt <= maxMkcolRetries; mkcolAttempt++ {
-		mkcolStart := time.Now()
-		
-		req, err := http.NewRequest("MKCOL", chunkDirURL, nil)
-		if err != nil {
-			c.logger.LogOperation(utils.ERROR, "magentacloud", c.BaseURL, "mkcol", "request_error", 
-				fmt.Sprintf("Could not create MKCOL request: %v", err), 
-				map[string]interface{}{"error": err.Error()})
-			return fmt.Errorf("could not create MKCOL request: %w", err)
-		}
-		req.SetBasicAuth(c.Username, c.Password)
-		req.Header.Set("User-Agent", MagentaCloudUserAgent)
-		req.Header.Set("Accept", "*/*")
-		req.Header.Set("Accept-Language", "en-US,en;q=0.9")
-		req.Header.Set("Connection", "keep-alive")
-		req.Header.Set("Destination", destinationURL)
-		
-		resp, err := c.HTTPClient.Do(req)
-		mkcolDuration = time.Since(mkcolStart)
-		
-		if err != nil {
-			if mkcolAttempt == maxMkcolRetries {
-				c.logger.LogOperation(utils.ERROR, "magentacloud", c.BaseURL, "mkcol", "failed", 
-					fmt.Sprintf("MKCOL request failed after %v (attempt %d/%d): %v", mkcolDuration, mkcolAttempt, maxMkcolRetries, err), 
-					map[string]interface{}{"duration": mkcolDuration, "attempt": mkcolAttempt, "error": err.Error()})
-				return fmt.Errorf("MKCOL request failed after %v: %w", mkcolDuration, err)
-			}
-			time.Sleep(time.Duration(mkcolAttempt) * time.Second)
-			continue
-		}
-		defer resp.Body.Close()
+	// Transient failures are retried by the client's transport (shared retry policy)
+	mkcolStart := time.Now()
+	req, err := http.NewRequestWithContext(ctx, "MKCOL", chunkDirURL, nil)
+	if err != nil {
+		c.logger.LogOperation(utils.ERROR, "magentacloud", c.BaseURL, "mkcol", "request_error", 
+			fmt.Sprintf("Could not create MKCOL request: %v", err), 
+			map[string]interface{}{"error": err.Error()})
+		return fmt.Errorf("could not create MKCOL request: %w", err)
+	}
+	req.SetBasicAuth(c.Username, c.Password)
+	req.Header.Set("User-Agent", MagentaCloudUserAgent)
+	req.Header.Set("Accept", "*/*")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	req.Header.Set("Connection", "keep-alive")
+	req.Header.Set("Destination", destinationURL)
+	
+	resp, err := c.HTTPClient.Do(req)
+	mkcolDuration := time.Since(mkcolStart)
+	if err != nil {
+		c.logger.LogOperation(utils.ERROR, "magentacloud", c.BaseURL, "mkcol", "failed", 
+			fmt.Sprintf("MKCOL request failed after %v: %v", mkcolDuration, err), 
+			map[string]interface{}{"duration": mkcolDuration, "error": err.Error()})
+		return c.wrapError("upload", "mkcol", fmt.Errorf("MKCOL request failed after %v: %w", mkcolDuration, err))
+	}
+	defer resp.Body.Close()
+	
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		c.logger.LogOperation(utils.DEBUG, "magentacloud", c.BaseURL, "mkcol", "success", 
+			fmt.Sprintf("Chunk directory created in %v (status: %s)", mkcolDuration, resp.Status), 
+			map[string]interface{}{"duration": mkcolDuration, "status_code": resp.StatusCode})
 		
-		if resp.StatusCode == http.StatusCreated {
-			c.logger.LogOperation(utils.DEBUG, "magentacloud", c.BaseURL, "mkcol", "success", 
-				fmt.Sprintf("Chunk directory created in %v (status: %s, attempt: %d/%d)", mkcolDuration, resp.Status, mkcolAttempt, maxMkcolRetries), 
-				map[string]interface{}{"duration": mkcolDuration, "status_code": resp.StatusCode, "attempt": mkcolAttempt})
-			
-			// MagentaCLOUD should have the destination from the MKCOL Destination header
-			// Let's try without creating a separate .target file
-			c.logger.LogOperation(utils.DEBUG, "magentacloud", c.BaseURL, "mkcol", "destination_set", 
-				fmt.Sprintf("Chunk directory created with destination: %s", destinationURL), 
-				map[string]interface{}{"chunk_dir": chunkDir, "destination_url": destinationURL})
-			
-			return nil // Success
-		} else if resp.StatusCode == http.StatusMethodNotAllowed {
-			// Directory might already exist - this is ok for chunking v2
-			c.logger.LogOperation(utils.WARN, "magentacloud", c.BaseURL, "mkcol", "already_exists", 
-				fmt.Sprintf("Directory might already exist (status: %s, attempt: %d/%d)", resp.Status, mkcolAttempt, maxMkcolRetries), 
-				map[string]interface{}{"status_code": resp.StatusCode, "attempt": mkcolAttempt})
-			return nil // Treat as success
-		} else {
-			body, _ := io.ReadAll(resp.Body)
-			if mkcolAttempt == maxMkcolRetries {
-				c.logger.LogOperation(utils.ERROR, "magentacloud", c.BaseURL, "mkcol", "failed", 
-					fmt.Sprintf("MKCOL request failed with status %s after %d attempts, response: %s", resp.Status, maxMkcolRetries, string(body)), 
-					map[string]interface{}{"status_code": resp.StatusCode, "response_body": string(body), "attempt": mkcolAttempt})
-				return fmt.Errorf("MKCOL request failed with status %s after %d attempts", resp.Status, maxMkcolRetries)
-			}
-			time.Sleep(time.Duration(mkcolAttempt) * time.Second)
-		}
+		// MagentaCLOUD should have the destination from the MKCOL Destination header
+		// Let's try without creating a separate .target file
+		c.logger.LogOperation(utils.DEBUG, "magentacloud", c.BaseURL, "mkcol", "destination_set", 
+			fmt.Sprintf("Chunk directory created with destination: %s", destinationURL), 
+			map[string]interface{}{"chunk_dir": chunkDir, "destination_url": destinationURL})
+		return nil
+	case http.StatusMethodNotAllowed:
+		// Directory might already exist - this is ok for chunking v2
+		c.logger.LogOperation(utils.WARN, "magentacloud", c.BaseURL, "mkcol", "already_exists", 
+			fmt.Sprintf("Directory might already exist (status: %s)", resp.Status), 
+			map[string]interface{}{"status_code": resp.StatusCode})
+		return nil
 	}
-	return fmt.Errorf("MKCOL request failed after %d attempts", maxMkcolRetries)
+	err = c.statusError("upload", "mkcol", resp)
+	c.logger.LogOperation(utils.ERROR, "magentacloud", c.BaseURL, "mkcol", "failed", 
+		fmt.Sprintf("MKCOL request failed: %v", err), 
+		map[string]interface{}{"status_code": resp.StatusCode, "error": err.Error()})
+	return err
 }
@@ -2,10 +2,12 @@ package nextcloud
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"path"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -39,9 +41,9 @@ func NewClient(baseURL, username, password string) *Client {
 }
 
 // newRequest is a helper to create authenticated WebDAV requests
-func (c *Client) newRequest(method, urlPath string, body io.Reader) (*http.Request, error) {
+func (c *Client) newRequest(ctx context.Context, method, urlPath string, body io.Reader) (*http.Request, error) {
 	fullURL := c.BaseURL + urlPath
-	req, err := http.NewRequest(method, fullURL, body)
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, body)
 	if err != nil {
 		return nil, err
 	}
@@ -55,28 +57,44 @@ func (c *Client) newRequest(method, urlPath string, body io.Reader) (*http.Reque
 	return req, nil
 }
 
+// statusError returns the typed error of a response with an unexpected status
+func (c *Client) statusError(operation, phase string, resp *http.Response) error {
+	return utils.NewStatusError("nextcloud", operation, phase, resp, utils.ParseWebDAVErrorTag)
+}
+
+// wrapError annotates a request error with the operation it occurred in
+func (c *Client) wrapError(operation, phase string, err error) error {
+	return utils.WrapProviderError("nextcloud", operation, phase, err)
+}
+
+// CloseIdleConnections closes the kept-alive connections of the client, so that the next
+// request has to connect again
+func (c *Client) CloseIdleConnections() {
+	c.HTTPClient.CloseIdleConnections()
+}
+
 // EnsureDirectory ensures the test directory exists
-func (c *Client) EnsureDirectory(dirPath string) error {
+func (c *Client) EnsureDirectory(ctx context.Context, dirPath string) error {
 	fullPath := path.Join("/remote.php/dav/files/", c.Username, dirPath)
-	req, err := c.newRequest("MKCOL", fullPath, nil)
+	req, err := c.newRequest(ctx, "MKCOL", fullPath, nil)
 	if err != nil {
 		return err
 	}
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return err
+		return c.wrapError("mkdir", "", err)
 	}
 	defer resp.Body.Close()
 
 	// 405 is returned if the directory already exists, which is not an error for us.
 	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusMethodNotAllowed {
-		return fmt.Errorf("failed to create directory %s, status: %s", dirPath, resp.Status)
+		return c.statusError("mkdir", "", resp)
 	}
 	return nil
 }
 
 // UploadFile uploads a file using the chunking API
-func (c *Client) UploadFile(filePath string, reader io.Reader, size int64, chunkSize int64) error {
+func (c *Client) UploadFile(ctx context.Context, filePath string, reader io.Reader, size int64, chunkSize int64) error {
 	c.logger.LogOperation(utils.INFO, "nextcloud", c.BaseURL, "upload", "start", 
 		fmt.Sprintf("Starting upload for %s (size: %d bytes, chunk size: %d bytes)", filePath, size, chunkSize), 
 		map[string]interface{}{"file_path": filePath, "file_size": size, "chunk_size": chunkSize})
@@ -89,7 +107,7 @@ func (c *Client) UploadFile(filePath string, reader io.Reader, size int64, chunk
 	// 1. Create temporary directory for chunks on the server
 	mkcolStart := time.Now()
 	
-	req, err := http.NewRequest("MKCOL", chunkDirURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "MKCOL", chunkDirURL, nil)
 	if err != nil {
 		return fmt.Errorf("could not create MKCOL request: %w", err)
 	}
@@ -105,16 +123,16 @@ func (c *Client) UploadFile(filePath string, reader io.Reader, size int64, chunk
 	mkcolDuration := time.Since(mkcolStart)
 	
 	if err != nil {
-		return fmt.Errorf("MKCOL request failed after %v: %w", mkcolDuration, err)
+		return c.wrapError("upload", "mkcol", fmt.Errorf("MKCOL request failed after %v: %w", mkcolDuration, err))
 	}
 	defer resp.Body.Close()
 	
 	if resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("MKCOL for chunks failed with status %s after %v", resp.Status, mkcolDuration)
+		return c.statusError("upload", "mkcol", resp)
 	}
 
 	// 2. Upload file in chunks
-	if err := c.uploadChunks(chunkDir, reader, chunkSize, destinationURL); err != nil {
+	if err := c.uploadChunks(ctx, chunkDir, reader, chunkSize, destinationURL); err != nil {
 		return err
 	}
 
@@ -122,7 +140,7 @@ func (c *Client) UploadFile(filePath string, reader io.Reader, size int64, chunk
 	moveSource := c.BaseURL + chunkDir + "/.file"
 	
 	fmt.Printf("[Nextcloud] Starting MOVE operation from %s to %s\n", moveSource, destinationURL)
-	req, err = http.NewRequest("MOVE", moveSource, nil)
+	req, err = http.NewRequestWithContext(ctx, "MOVE", moveSource, nil)
 	if err != nil {
 		return fmt.Errorf("could not create MOVE request: %w", err)
 	}
@@ -151,7 +169,7 @@ func (c *Client) UploadFile(filePath string, reader io.Reader, size int64, chunk
 		c.logger.LogOperation(utils.ERROR, "nextcloud", c.BaseURL, "move", "failed", 
 			fmt.Sprintf("MOVE operation failed after %v: %v", moveDuration, err), 
 			map[string]interface{}{"duration": moveDuration.String(), "error": err.Error()})
-		return fmt.Errorf("MOVE request failed after %v: %w", moveDuration, err)
+		return c.wrapError("upload", "assemble", fmt.Errorf("MOVE request failed after %v: %w", moveDuration, err))
 	}
 	defer resp.Body.Close()
 
@@ -159,11 +177,11 @@ func (c *Client) UploadFile(filePath string, reader io.Reader, size int64, chunk
 
 	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent {
 		// Read response body for more detailed error information
-		body, _ := io.ReadAll(resp.Body)
+		err := c.statusError("upload", "assemble", resp)
 		c.logger.LogOperation(utils.ERROR, "nextcloud", c.BaseURL, "move", "failed", 
-			fmt.Sprintf("MOVE failed with status %s, response: %s", resp.Status, string(body)), 
-			map[string]interface{}{"status": resp.Status, "response_body": string(body)})
-		return fmt.Errorf("final MOVE to assemble chunks failed with status %s", resp.Status)
+			fmt.Sprintf("MOVE failed: %v", err), 
+			map[string]interface{}{"status": resp.Status, "error": err.Error()})
+		return err
 	}
 
 	c.logger.LogOperation(utils.INFO, "nextcloud", c.BaseURL, "upload", "completed", 
@@ -173,7 +191,7 @@ func (c *Client) UploadFile(filePath string, reader io.Reader, size int64, chunk
 }
 
 // uploadChunks uploads the file in chunks to the server
-func (c *Client) uploadChunks(chunkDir string, reader io.Reader, chunkSize int64, destinationURL string) error {
+func (c *Client) uploadChunks(ctx context.Context, chunkDir string, reader io.Reader, chunkSize int64, destinationURL string) error {
 	chunk := make([]byte, chunkSize)
 	totalChunks := 0
 	successfulChunks := 0
@@ -198,59 +216,39 @@ func (c *Client) uploadChunks(chunkDir string, reader io.Reader, chunkSize int64
 
 			chunkStart := time.Now()
 
-			// Retry logic for individual chunks
-			var resp *http.Response
-			var chunkErr error
-			maxRetries := 3
-			for attempt := 1; attempt <= maxRetries; attempt++ {
-				req, err := http.NewRequest("PUT", chunkURL, bytes.NewReader(chunk[:bytesRead]))
-				if err != nil {
-					c.logger.LogOperation(utils.ERROR, "nextcloud", c.BaseURL, "chunk_upload", "request_error", 
-						fmt.Sprintf("Could not create PUT request for chunk %d (attempt %d): %v", chunkNumber, attempt, err), 
-						map[string]interface{}{"chunk_number": chunkNumber, "attempt": attempt, "error": err.Error()})
-					if attempt == maxRetries {
-						return fmt.Errorf("could not create PUT request for chunk %d after %d attempts: %w", chunkNumber, maxRetries, err)
-					}
-					continue
-				}
-				req.SetBasicAuth(c.Username, c.Password)
-				req.Header.Set("User-Agent", NextcloudUserAgent)
-				req.Header.Set("Accept", "*/*")
-				req.Header.Set("Accept-Language", "en-US,en;q=0.9")
-				req.Header.Set("Connection", "keep-alive")
-				req.Header.Set("Content-Type", "application/octet-stream")
-				// CRITICAL: Add Destination header like bash script does for each chunk!
-				req.Header.Set("Destination", destinationURL)
-				req.ContentLength = int64(bytesRead)
-
-				resp, chunkErr = c.HTTPClient.Do(req)
-				if chunkErr != nil {
-					c.logger.LogOperation(utils.ERROR, "nextcloud", c.BaseURL, "chunk_upload", "http_error", 
-						fmt.Sprintf("PUT request for chunk %d failed (attempt %d/%d) after %v: %v", chunkNumber, attempt, maxRetries, time.Since(chunkStart), chunkErr), 
-						map[string]interface{}{"chunk_number": chunkNumber, "attempt": attempt, "max_retries": maxRetries, "error": chunkErr.Error()})
-					if attempt < maxRetries {
-						time.Sleep(time.Duration(attempt) * time.Second) // Progressive backoff
-						continue
-					}
-					return fmt.Errorf("PUT request for chunk %d failed after %d attempts: %w", chunkNumber, maxRetries, chunkErr)
-				}
-
-				// Check response status - Accept both 201 Created, 200 OK and 204 No Content
-				if resp.StatusCode == http.StatusCreated || resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusOK {
-					break // Success
-				} else {
-					// Read response body for detailed error information
-					body, _ := io.ReadAll(resp.Body)
-					resp.Body.Close()
-					c.logger.LogOperation(utils.ERROR, "nextcloud", c.BaseURL, "chunk_upload", "status_error", 
-						fmt.Sprintf("Chunk %d upload failed (attempt %d/%d) with status %s after %v, response: %s", chunkNumber, attempt, maxRetries, resp.Status, time.Since(chunkStart), string(body)), 
-						map[string]interface{}{"chunk_number": chunkNumber, "attempt": attempt, "max_retries": maxRetries, "status": resp.Status, "response_body": string(body)})
-					if attempt < maxRetries {
-						time.Sleep(time.Duration(attempt) * time.Second) // Progressive backoff
-						continue
-					}
-					return fmt.Errorf("upload of chunk %d failed with status %s after %d attempts", chunkNumber, resp.Status, maxRetries)
-				}
+			// Transient failures are retried by the client's transport (shared retry policy)
+			req, err := http.NewRequestWithContext(ctx, "PUT", chunkURL, bytes.NewReader(chunk[:bytesRead]))
+			if err != nil {
+				return fmt.Errorf("could not create PUT request for chunk %d: %w", chunkNumber, err)
+			}
+			req.SetBasicAuth(c.Username, c.Password)
+			req.Header.Set("User-Agent", NextcloudUserAgent)
+			req.Header.Set("Accept", "*/*")
+			req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+			req.Header.Set("Connection", "keep-alive")
+			req.Header.Set("Content-Type", "application/octet-stream")
+			// CRITICAL: Add Destination header like bash script does for each chunk!
+			req.Header.Set("Destination", destinationURL)
+			req.ContentLength = int64(bytesRead)
+			req = utils.WithHTTPOperation(req, utils.HTTPOperationChunk)
+
+			resp, err := c.HTTPClient.Do(req)
+			if err != nil {
+				c.logger.LogOperation(utils.ERROR, "nextcloud", c.BaseURL, "chunk_upload", "http_error", 
+					fmt.Sprintf("PUT request for chunk %d failed after %v: %v", chunkNumber, time.Since(chunkStart), err), 
+					map[string]interface{}{"chunk_number": chunkNumber, "error": err.Error()})
+				return c.wrapError("upload", "chunk", fmt.Errorf("PUT request for chunk %d failed: %w", chunkNumber, err))
+			}
+
+			// Check response status - Accept both 201 Created, 200 OK and 204 No Content
+			if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+				// Read response body for detailed error information
+				err := c.statusError("upload", "chunk", resp)
+				resp.Body.Close()
+				c.logger.LogOperation(utils.ERROR, "nextcloud", c.BaseURL, "chunk_upload", "status_error", 
+					fmt.Sprintf("Chunk %d upload failed after %v: %v", chunkNumber, time.Since(chunkStart), err), 
+					map[string]interface{}{"chunk_number": chunkNumber, "status": resp.Status, "error": err.Error()})
+				return err
 			}
 			
 			chunkDuration := time.Since(chunkStart)
@@ -282,42 +280,96 @@ func (c *Client) uploadChunks(chunkDir string, reader io.Reader, chunkSize int64
 }
 
 // DownloadFile downloads a file
-func (c *Client) DownloadFile(filePath string) (io.ReadCloser, error) {
+func (c *Client) DownloadFile(ctx context.Context, filePath string) (io.ReadCloser, error) {
 	c.logger.LogOperation(utils.INFO, "nextcloud", c.BaseURL, "download", "started", 
 		fmt.Sprintf("Download started for %s", filePath), 
 		map[string]interface{}{"file_path": filePath})
 
 	fullPath := path.Join("/remote.php/dav/files/", c.Username, filePath)
-	req, err := c.newRequest("GET", fullPath, nil)
+	req, err := c.newRequest(ctx, "GET", fullPath, nil)
 	if err != nil {
 		return nil, err
 	}
+	req = utils.WithHTTPOperation(req, utils.HTTPOperationDownload)
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, c.wrapError("download", "", err)
 	}
 	if resp.StatusCode != http.StatusOK {
-		resp.Body.Close()
-		return nil, fmt.Errorf("download failed, status: %s", resp.Status)
+		defer resp.Body.Close()
+		return nil, c.statusError("download", "", resp)
 	}
 	return resp.Body, nil
 }
 
+// ListDirectory returns the names of the entries in a directory (PROPFIND with Depth: 1)
+func (c *Client) ListDirectory(ctx context.Context, dirPath string) ([]string, error) {
+	fullPath := path.Join("/remote.php/dav/files/", c.Username, dirPath)
+	req, err := c.newRequest(ctx, "PROPFIND", fullPath, strings.NewReader(utils.PropfindBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Depth", "1")
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, c.wrapError("list", "", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, c.statusError("list", "", resp)
+	}
+	resources, err := utils.ParseMultistatus(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PROPFIND response: %w", err)
+	}
+	return utils.DAVChildNames(resources, fullPath), nil
+}
+
+// StatFile returns the size of a remote file (PROPFIND with Depth: 0)
+func (c *Client) StatFile(ctx context.Context, filePath string) (int64, error) {
+	fullPath := path.Join("/remote.php/dav/files/", c.Username, filePath)
+	req, err := c.newRequest(ctx, "PROPFIND", fullPath, strings.NewReader(utils.PropfindBody))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Depth", "0")
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return 0, c.wrapError("stat", "", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMultiStatus {
+		return 0, c.statusError("stat", "", resp)
+	}
+	resources, err := utils.ParseMultistatus(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse PROPFIND response: %w", err)
+	}
+	if len(resources) == 0 {
+		return 0, fmt.Errorf("PROPFIND returned no properties for %s", filePath)
+	}
+	return resources[0].ContentLength, nil
+}
+
 // DeleteFile deletes a file or directory
-func (c *Client) DeleteFile(filePath string) error {
+func (c *Client) DeleteFile(ctx context.Context, filePath string) error {
 	fullPath := path.Join("/remote.php/dav/files/", c.Username, filePath)
-	req, err := c.newRequest("DELETE", fullPath, nil)
+	req, err := c.newRequest(ctx, "DELETE", fullPath, nil)
 	if err != nil {
 		return err
 	}
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return err
+		return c.wrapError("delete", "", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusNoContent {
-		return fmt.Errorf("delete failed, status: %s", resp.Status)
+		return c.statusError("delete", "", resp)
 	}
 
 	c.logger.LogOperation(utils.INFO, "nextcloud", c.BaseURL, "delete", "success", 
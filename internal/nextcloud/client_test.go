@@ -1,9 +1,12 @@
 package nextcloud
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestEnsureDirectory(t *testing.T) {
@@ -19,7 +22,7 @@ func TestEnsureDirectory(t *testing.T) {
 
 	client := NewClient(server.URL, "testuser", "testpass")
 
-	err := client.EnsureDirectory("testdir")
+	err := client.EnsureDirectory(context.Background(), "testdir")
 	if err != nil {
 		t.Errorf("EnsureDirectory failed: %v", err)
 	}
@@ -37,7 +40,7 @@ func TestDownloadFile(t *testing.T) {
 
 	client := NewClient(server.URL, "testuser", "testpass")
 
-	body, err := client.DownloadFile("testfile.txt")
+	body, err := client.DownloadFile(context.Background(), "testfile.txt")
 	if err != nil {
 		t.Errorf("DownloadFile failed: %v", err)
 	}
@@ -51,6 +54,25 @@ func TestDownloadFile(t *testing.T) {
 	}
 }
 
+func TestDownloadFileDeadline(t *testing.T) {
+	// The server answers only after the client gave up
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := NewClient(server.URL, "testuser", "testpass")
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.DownloadFile(ctx, "testfile.txt")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the request to be aborted by the deadline, got %v", err)
+	}
+}
+
 func TestDeleteFile(t *testing.T) {
 	// Mock HTTP server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -62,8 +84,65 @@ func TestDeleteFile(t *testing.T) {
 
 	client := NewClient(server.URL, "testuser", "testpass")
 
-	err := client.DeleteFile("testfile.txt")
+	err := client.DeleteFile(context.Background(), "testfile.txt")
 	if err != nil {
 		t.Errorf("DeleteFile failed: %v", err)
 	}
 }
+
+func TestListDirectory(t *testing.T) {
+	// Mock HTTP server answering a Depth: 1 PROPFIND
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PROPFIND" || r.Header.Get("Depth") != "1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = w.Write([]byte(`<?xml version="1.0"?>
+<d:multistatus xmlns:d="DAV:">
+  <d:response><d:href>/remote.php/dav/files/testuser/testdir/</d:href></d:response>
+  <d:response><d:href>/remote.php/dav/files/testuser/testdir/a.tmp</d:href></d:response>
+  <d:response><d:href>/remote.php/dav/files/testuser/testdir/b.tmp</d:href></d:response>
+</d:multistatus>`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "testuser", "testpass")
+
+	names, err := client.ListDirectory(context.Background(), "/testdir")
+	if err != nil {
+		t.Fatalf("ListDirectory failed: %v", err)
+	}
+	if len(names) != 2 || names[0] != "a.tmp" || names[1] != "b.tmp" {
+		t.Errorf("Unexpected entries: %v", names)
+	}
+}
+
+func TestStatFile(t *testing.T) {
+	// Mock HTTP server answering a Depth: 0 PROPFIND
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PROPFIND" || r.Header.Get("Depth") != "0" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = w.Write([]byte(`<?xml version="1.0"?>
+<d:multistatus xmlns:d="DAV:">
+  <d:response>
+    <d:href>/remote.php/dav/files/testuser/testdir/a.tmp</d:href>
+    <d:propstat><d:prop><d:getcontentlength>1024</d:getcontentlength></d:prop></d:propstat>
+  </d:response>
+</d:multistatus>`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "testuser", "testpass")
+
+	size, err := client.StatFile(context.Background(), "/testdir/a.tmp")
+	if err != nil {
+		t.Fatalf("StatFile failed: %v", err)
+	}
+	if size != 1024 {
+		t.Errorf("Expected size 1024, got %d", size)
+	}
+}
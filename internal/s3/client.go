@@ -0,0 +1,461 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xXRoxXeRXx/cloud-performance-monitor/internal/utils"
+)
+
+const (
+	// DefaultRegion is used for signing if no region is configured; MinIO and Ceph RGW
+	// accept it unless a different region is set on the server
+	DefaultRegion = "us-east-1"
+	// DefaultTimeout is the timeout of a single request
+	DefaultTimeout = 300 * time.Second
+	// MinPartSize is the smallest part S3 accepts in a multipart upload, except for the last one
+	MinPartSize = 5 * 1024 * 1024
+	// abortTimeout bounds the abort of a failed multipart upload, which runs even if the
+	// upload was cancelled
+	abortTimeout = 30 * time.Second
+)
+
+// Client for S3-compatible object storage (Amazon S3, MinIO, Ceph RGW, Wasabi). Requests
+// use path-style addressing (<endpoint>/<bucket>/<key>) and are signed with AWS Signature
+// Version 4. Directories are key prefixes.
+type Client struct {
+	Endpoint   string
+	Bucket     string
+	Region     string
+	AccessKey  string
+	SecretKey  string
+	HTTPClient *http.Client
+	logger     utils.ClientLogger
+	// now returns the signing time
+	now func() time.Time
+}
+
+// NewClient creates a new S3 client; an empty region signs for DefaultRegion
+func NewClient(endpoint, bucket, region, accessKey, secretKey string) *Client {
+	if region == "" {
+		region = DefaultRegion
+	}
+	return &Client{
+		Endpoint:   strings.TrimSuffix(endpoint, "/"),
+		Bucket:     bucket,
+		Region:     region,
+		AccessKey:  accessKey,
+		SecretKey:  secretKey,
+		HTTPClient: &http.Client{Timeout: DefaultTimeout},
+		logger:     &utils.DefaultClientLogger{},
+		now:        time.Now,
+	}
+}
+
+// objectKey converts a file path of the runner into an object key
+func objectKey(filePath string) string {
+	return strings.TrimPrefix(path.Clean("/"+filePath), "/")
+}
+
+// newRequest creates a signed request for an object key of the bucket; an empty key
+// addresses the bucket itself
+func (c *Client) newRequest(ctx context.Context, method, key string, query url.Values, body []byte) (*http.Request, error) {
+	u := c.Endpoint + "/" + escape(c.Bucket, true)
+	if key != "" {
+		u += "/" + escape(key, false)
+	}
+	if len(query) > 0 {
+		u += "?" + canonicalQuery(query)
+	}
+
+	var reader io.Reader
+	bodyHash := emptyPayloadHash
+	if body != nil {
+		reader = bytes.NewReader(body)
+		bodyHash = payloadHash(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	sign(req, c.AccessKey, c.SecretKey, c.Region, bodyHash, c.now())
+	return req, nil
+}
+
+// s3Error is the error body of an S3 response
+type s3Error struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message"`
+}
+
+// parseErrorTag returns the S3 error code of an error body, e.g. "NoSuchKey"
+func parseErrorTag(body string) string {
+	var e s3Error
+	if err := xml.Unmarshal([]byte(body), &e); err != nil {
+		return ""
+	}
+	return e.Code
+}
+
+// statusError returns the typed error of a response with an unexpected status
+func (c *Client) statusError(operation, phase string, resp *http.Response) error {
+	return utils.NewStatusError("s3", operation, phase, resp, parseErrorTag)
+}
+
+// wrapError annotates a request error with the operation it occurred in
+func (c *Client) wrapError(operation, phase string, err error) error {
+	return utils.WrapProviderError("s3", operation, phase, err)
+}
+
+// CloseIdleConnections closes the kept-alive connections of the client, so that the next
+// request has to connect again
+func (c *Client) CloseIdleConnections() {
+	c.HTTPClient.CloseIdleConnections()
+}
+
+// EnsureDirectory checks that the bucket is accessible. Object storage has no
+// directories, objects are created with the directory as key prefix.
+func (c *Client) EnsureDirectory(ctx context.Context, dirPath string) error {
+	req, err := c.newRequest(ctx, "HEAD", "", nil, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return c.wrapError("mkdir", "", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return c.statusError("mkdir", "", resp)
+	}
+	return nil
+}
+
+// UploadFile uploads a file with a single PUT if it fits into one part, otherwise as a
+// multipart upload with parts of chunkSize bytes
+func (c *Client) UploadFile(ctx context.Context, filePath string, reader io.Reader, size int64, chunkSize int64) error {
+	key := objectKey(filePath)
+	c.logger.LogOperation(utils.INFO, "s3", c.Endpoint, "upload", "start",
+		fmt.Sprintf("Starting upload for %s (size: %d bytes, part size: %d bytes)", key, size, chunkSize),
+		map[string]interface{}{"key": key, "file_size": size, "part_size": chunkSize})
+
+	// Files smaller than a part only need a buffer of their size; the extra byte detects
+	// a file that is larger than announced
+	bufSize := chunkSize
+	if size >= 0 && size < chunkSize {
+		bufSize = size + 1
+	}
+	first := make([]byte, bufSize)
+	n, err := io.ReadFull(reader, first)
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		return c.putObject(ctx, key, first[:n])
+	case err != nil:
+		return fmt.Errorf("failed to read part 1: %w", err)
+	}
+
+	// The file has more than one part; the first one is already read
+	return c.uploadMultipart(ctx, key, io.MultiReader(bytes.NewReader(first), reader), chunkSize)
+}
+
+// putObject uploads a whole object with one PUT
+func (c *Client) putObject(ctx context.Context, key string, data []byte) error {
+	req, err := c.newRequest(ctx, "PUT", key, nil, data)
+	if err != nil {
+		return fmt.Errorf("could not create PUT request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req = utils.WithHTTPOperation(req, utils.HTTPOperationUpload)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return c.wrapError("upload", "", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return c.statusError("upload", "", resp)
+	}
+	return nil
+}
+
+// completedPart is a part of a multipart upload as listed in CompleteMultipartUpload
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// uploadMultipart uploads an object in parts of partSize bytes. A failed upload is
+// aborted so that its parts do not keep using storage.
+func (c *Client) uploadMultipart(ctx context.Context, key string, reader io.Reader, partSize int64) (err error) {
+	uploadID, err := c.initiateMultipartUpload(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			c.abortMultipartUpload(ctx, key, uploadID)
+		}
+	}()
+
+	var parts []completedPart
+	part := make([]byte, partSize)
+	for number := 1; ; number++ {
+		n, readErr := io.ReadFull(reader, part)
+		if n > 0 {
+			etag, err := c.uploadPart(ctx, key, uploadID, number, part[:n])
+			if err != nil {
+				return err
+			}
+			parts = append(parts, completedPart{PartNumber: number, ETag: etag})
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return fmt.Errorf("failed to read part %d: %w", number, readErr)
+		}
+	}
+	return c.completeMultipartUpload(ctx, key, uploadID, parts)
+}
+
+// initiateMultipartUpload starts a multipart upload and returns its upload ID
+func (c *Client) initiateMultipartUpload(ctx context.Context, key string) (string, error) {
+	req, err := c.newRequest(ctx, "POST", key, url.Values{"uploads": {""}}, nil)
+	if err != nil {
+		return "", fmt.Errorf("could not create multipart upload request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return "", c.wrapError("upload", "initiate", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", c.statusError("upload", "initiate", resp)
+	}
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to parse multipart upload response: %w", err)
+	}
+	if result.UploadID == "" {
+		return "", errors.New("multipart upload response contains no upload ID")
+	}
+	return result.UploadID, nil
+}
+
+// uploadPart uploads one part of a multipart upload and returns its ETag
+func (c *Client) uploadPart(ctx context.Context, key, uploadID string, number int, data []byte) (string, error) {
+	// Transient failures are retried by the client's transport (shared retry policy)
+	query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadID}}
+	req, err := c.newRequest(ctx, "PUT", key, query, data)
+	if err != nil {
+		return "", fmt.Errorf("could not create PUT request for part %d: %w", number, err)
+	}
+	req = utils.WithHTTPOperation(req, utils.HTTPOperationChunk)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return "", c.wrapError("upload", "part", fmt.Errorf("PUT request for part %d failed: %w", number, err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", c.statusError("upload", "part", resp)
+	}
+	c.logger.LogOperation(utils.DEBUG, "s3", c.Endpoint, "chunk_upload", "chunk_success",
+		fmt.Sprintf("Part %d of %s uploaded (%d bytes)", number, key, len(data)),
+		map[string]interface{}{"part_number": number, "bytes": len(data)})
+	return resp.Header.Get("ETag"), nil
+}
+
+// completeMultipartUpload assembles the uploaded parts into the object
+func (c *Client) completeMultipartUpload(ctx context.Context, key, uploadID string, parts []completedPart) error {
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return fmt.Errorf("could not encode part list: %w", err)
+	}
+	req, err := c.newRequest(ctx, "POST", key, url.Values{"uploadId": {uploadID}}, body)
+	if err != nil {
+		return fmt.Errorf("could not create complete request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/xml")
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return c.wrapError("upload", "assemble", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return c.statusError("upload", "assemble", resp)
+	}
+	// S3 may report a failed assembly with status 200 and an error body
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return c.wrapError("upload", "assemble", err)
+	}
+	if tag := parseErrorTag(string(data)); tag != "" {
+		return &utils.ProviderError{
+			Provider:   "s3",
+			Operation:  "upload",
+			Phase:      "assemble",
+			StatusCode: resp.StatusCode,
+			Tag:        tag,
+			Err:        fmt.Errorf("multipart upload could not be completed: %s", tag),
+		}
+	}
+	return nil
+}
+
+// abortMultipartUpload discards the parts of a failed multipart upload; it runs even if
+// ctx is already cancelled
+func (c *Client) abortMultipartUpload(ctx context.Context, key, uploadID string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), abortTimeout)
+	defer cancel()
+
+	req, err := c.newRequest(ctx, "DELETE", key, url.Values{"uploadId": {uploadID}}, nil)
+	if err != nil {
+		return
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		c.logger.LogOperation(utils.WARN, "s3", c.Endpoint, "upload", "abort_failed",
+			fmt.Sprintf("Could not abort multipart upload of %s: %v", key, err),
+			map[string]interface{}{"key": key, "upload_id": uploadID})
+		return
+	}
+	resp.Body.Close()
+}
+
+// DownloadFile downloads an object
+func (c *Client) DownloadFile(ctx context.Context, filePath string) (io.ReadCloser, error) {
+	req, err := c.newRequest(ctx, "GET", objectKey(filePath), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = utils.WithHTTPOperation(req, utils.HTTPOperationDownload)
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, c.wrapError("download", "", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, c.statusError("download", "", resp)
+	}
+	return resp.Body, nil
+}
+
+// listBucketResult is the part of a ListObjectsV2 response we need
+type listBucketResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// ListDirectory returns the names of the objects and sub-prefixes below a directory
+// (ListObjectsV2 with delimiter "/")
+func (c *Client) ListDirectory(ctx context.Context, dirPath string) ([]string, error) {
+	prefix := objectKey(dirPath)
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	var names []string
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}, "delimiter": {"/"}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		req, err := c.newRequest(ctx, "GET", "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.HTTPClient.Do(req)
+		if err != nil {
+			return nil, c.wrapError("list", "", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			defer resp.Body.Close()
+			return nil, c.statusError("list", "", resp)
+		}
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse list response: %w", err)
+		}
+
+		for _, obj := range result.Contents {
+			if name := strings.TrimPrefix(obj.Key, prefix); name != "" {
+				names = append(names, name)
+			}
+		}
+		for _, p := range result.CommonPrefixes {
+			names = append(names, strings.TrimSuffix(strings.TrimPrefix(p.Prefix, prefix), "/"))
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return names, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// StatFile returns the size of an object (HEAD)
+func (c *Client) StatFile(ctx context.Context, filePath string) (int64, error) {
+	req, err := c.newRequest(ctx, "HEAD", objectKey(filePath), nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return 0, c.wrapError("stat", "", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, c.statusError("stat", "", resp)
+	}
+	return resp.ContentLength, nil
+}
+
+// DeleteFile deletes an object; deleting a missing object is not an error in S3
+func (c *Client) DeleteFile(ctx context.Context, filePath string) error {
+	req, err := c.newRequest(ctx, "DELETE", objectKey(filePath), nil, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return c.wrapError("delete", "", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return c.statusError("delete", "", resp)
+	}
+	return nil
+}
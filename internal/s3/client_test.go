@@ -0,0 +1,223 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/xXRoxXeRXx/cloud-performance-monitor/internal/utils"
+)
+
+// fakeS3 is a minimal in-memory S3 server for the path-style requests of the client
+type fakeS3 struct {
+	mu       sync.Mutex
+	bucket   string
+	objects  map[string][]byte
+	parts    map[string]map[int][]byte
+	uploads  int
+	aborted  int
+	failPart int
+}
+
+func newFakeS3(bucket string) *fakeS3 {
+	return &fakeS3{bucket: bucket, objects: make(map[string][]byte), parts: make(map[string]map[int][]byte)}
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=access/") ||
+		r.Header.Get("X-Amz-Content-Sha256") == "" {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`<Error><Code>SignatureDoesNotMatch</Code></Error>`))
+		return
+	}
+
+	p := strings.TrimPrefix(r.URL.Path, "/")
+	if p != s.bucket && !strings.HasPrefix(p, s.bucket+"/") {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`<Error><Code>NoSuchBucket</Code></Error>`))
+		return
+	}
+	key := strings.TrimPrefix(strings.TrimPrefix(p, s.bucket), "/")
+	query := r.URL.Query()
+	body, _ := io.ReadAll(r.Body)
+
+	switch {
+	case key == "" && r.Method == "HEAD":
+		w.WriteHeader(http.StatusOK)
+	case key == "" && r.Method == "GET":
+		s.list(w, query.Get("prefix"))
+	case r.Method == "POST" && query.Has("uploads"):
+		s.uploads++
+		id := fmt.Sprintf("upload-%d", s.uploads)
+		s.parts[id] = make(map[int][]byte)
+		fmt.Fprintf(w, `<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>`, id)
+	case r.Method == "PUT" && query.Has("uploadId"):
+		var number int
+		fmt.Sscanf(query.Get("partNumber"), "%d", &number)
+		if number == s.failPart {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`<Error><Code>AccessDenied</Code></Error>`))
+			return
+		}
+		s.parts[query.Get("uploadId")][number] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"etag-%d"`, number))
+	case r.Method == "POST" && query.Has("uploadId"):
+		var complete struct {
+			Parts []completedPart `xml:"Part"`
+		}
+		_ = xml.Unmarshal(body, &complete)
+		var data []byte
+		for _, part := range complete.Parts {
+			data = append(data, s.parts[query.Get("uploadId")][part.PartNumber]...)
+		}
+		s.objects[key] = data
+		delete(s.parts, query.Get("uploadId"))
+		_, _ = w.Write([]byte(`<CompleteMultipartUploadResult></CompleteMultipartUploadResult>`))
+	case r.Method == "DELETE" && query.Has("uploadId"):
+		s.aborted++
+		delete(s.parts, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == "PUT":
+		s.objects[key] = body
+	case r.Method == "GET" || r.Method == "HEAD":
+		data, ok := s.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`<Error><Code>NoSuchKey</Code></Error>`))
+			return
+		}
+		w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+		_, _ = w.Write(data)
+	case r.Method == "DELETE":
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *fakeS3) list(w http.ResponseWriter, prefix string) {
+	var keys []string
+	for key := range s.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	fmt.Fprint(w, `<ListBucketResult>`)
+	for _, key := range keys {
+		fmt.Fprintf(w, `<Contents><Key>%s</Key></Contents>`, key)
+	}
+	fmt.Fprint(w, `<IsTruncated>false</IsTruncated></ListBucketResult>`)
+}
+
+func newTestClient(t *testing.T, fake *fakeS3) *Client {
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return NewClient(server.URL, "monitor", "", "access", "secret")
+}
+
+func TestUploadFileSinglePut(t *testing.T) {
+	fake := newFakeS3("monitor")
+	client := newTestClient(t, fake)
+	content := []byte("0123456789")
+
+	if err := client.EnsureDirectory(context.Background(), "/perf"); err != nil {
+		t.Fatalf("EnsureDirectory failed: %v", err)
+	}
+	if err := client.UploadFile(context.Background(), "/perf/file.bin", bytes.NewReader(content), int64(len(content)), 16); err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+	if fake.uploads != 0 || !bytes.Equal(fake.objects["perf/file.bin"], content) {
+		t.Errorf("expected a single PUT, got %d multipart uploads", fake.uploads)
+	}
+
+	size, err := client.StatFile(context.Background(), "/perf/file.bin")
+	if err != nil || size != int64(len(content)) {
+		t.Errorf("expected size %d, got %d (%v)", len(content), size, err)
+	}
+	body, err := client.DownloadFile(context.Background(), "/perf/file.bin")
+	if err != nil {
+		t.Fatalf("DownloadFile failed: %v", err)
+	}
+	defer body.Close()
+	if data, _ := io.ReadAll(body); !bytes.Equal(data, content) {
+		t.Errorf("unexpected content: %q", data)
+	}
+}
+
+func TestUploadFileMultipart(t *testing.T) {
+	fake := newFakeS3("monitor")
+	client := newTestClient(t, fake)
+	content := []byte("0123456789")
+
+	if err := client.UploadFile(context.Background(), "/perf/file.bin", bytes.NewReader(content), int64(len(content)), 4); err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+	if fake.uploads != 1 {
+		t.Errorf("expected one multipart upload, got %d", fake.uploads)
+	}
+	if !bytes.Equal(fake.objects["perf/file.bin"], content) {
+		t.Errorf("expected the parts to be assembled in order, got %q", fake.objects["perf/file.bin"])
+	}
+}
+
+func TestUploadFileMultipartAbortsOnFailure(t *testing.T) {
+	fake := newFakeS3("monitor")
+	fake.failPart = 2
+	client := newTestClient(t, fake)
+
+	err := client.UploadFile(context.Background(), "/perf/file.bin", strings.NewReader("0123456789"), 10, 4)
+	var providerErr *utils.ProviderError
+	if !errors.As(err, &providerErr) || providerErr.Phase != "part" || providerErr.Tag != "AccessDenied" {
+		t.Fatalf("expected a typed part error, got %v", err)
+	}
+	if fake.aborted != 1 || len(fake.parts) != 0 {
+		t.Errorf("expected the multipart upload to be aborted, %d aborts, %d open uploads", fake.aborted, len(fake.parts))
+	}
+}
+
+func TestListDirectoryAndDelete(t *testing.T) {
+	fake := newFakeS3("monitor")
+	fake.objects["perf/a.tmp"] = []byte("a")
+	fake.objects["perf/b.tmp"] = []byte("b")
+	fake.objects["other/c.tmp"] = []byte("c")
+	client := newTestClient(t, fake)
+
+	names, err := client.ListDirectory(context.Background(), "/perf")
+	if err != nil {
+		t.Fatalf("ListDirectory failed: %v", err)
+	}
+	if len(names) != 2 || names[0] != "a.tmp" || names[1] != "b.tmp" {
+		t.Errorf("unexpected entries: %v", names)
+	}
+
+	if err := client.DeleteFile(context.Background(), "/perf/a.tmp"); err != nil {
+		t.Fatalf("DeleteFile failed: %v", err)
+	}
+	if _, ok := fake.objects["perf/a.tmp"]; ok {
+		t.Error("expected the object to be deleted")
+	}
+}
+
+func TestDownloadFileMissingKey(t *testing.T) {
+	client := newTestClient(t, newFakeS3("monitor"))
+
+	_, err := client.DownloadFile(context.Background(), "/perf/missing.bin")
+	var providerErr *utils.ProviderError
+	if !errors.As(err, &providerErr) || providerErr.StatusCode != http.StatusNotFound || providerErr.Tag != "NoSuchKey" {
+		t.Errorf("expected a NoSuchKey error, got %v", err)
+	}
+}
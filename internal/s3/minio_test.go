@@ -0,0 +1,67 @@
+//go:build integration
+
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"os"
+	"testing"
+)
+
+// TestMinIO runs the client against a real S3-compatible server, e.g. a local MinIO:
+//
+//	docker run -d -p 9000:9000 -e MINIO_ROOT_USER=minioadmin -e MINIO_ROOT_PASSWORD=minioadmin \
+//	  minio/minio server /data
+//	S3_TEST_ENDPOINT=http://localhost:9000 S3_TEST_BUCKET=monitor \
+//	  S3_TEST_ACCESS_KEY=minioadmin S3_TEST_SECRET_KEY=minioadmin go test -tags integration ./internal/s3
+//
+// The bucket must exist.
+func TestMinIO(t *testing.T) {
+	endpoint := os.Getenv("S3_TEST_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("S3_TEST_ENDPOINT not set")
+	}
+	client := NewClient(endpoint, os.Getenv("S3_TEST_BUCKET"), os.Getenv("S3_TEST_REGION"),
+		os.Getenv("S3_TEST_ACCESS_KEY"), os.Getenv("S3_TEST_SECRET_KEY"))
+	ctx := context.Background()
+
+	// Two full parts and a short last part
+	content := make([]byte, 2*MinPartSize+1024)
+	_, _ = rand.Read(content)
+	const file = "/performance_tests/minio_integration.bin"
+
+	if err := client.EnsureDirectory(ctx, "/performance_tests"); err != nil {
+		t.Fatalf("EnsureDirectory failed: %v", err)
+	}
+	if err := client.UploadFile(ctx, file, bytes.NewReader(content), int64(len(content)), MinPartSize); err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+	defer client.DeleteFile(ctx, file)
+
+	if size, err := client.StatFile(ctx, file); err != nil || size != int64(len(content)) {
+		t.Errorf("expected size %d, got %d (%v)", len(content), size, err)
+	}
+	names, err := client.ListDirectory(ctx, "/performance_tests")
+	if err != nil {
+		t.Fatalf("ListDirectory failed: %v", err)
+	}
+	found := false
+	for _, name := range names {
+		found = found || name == "minio_integration.bin"
+	}
+	if !found {
+		t.Errorf("uploaded object not listed: %v", names)
+	}
+
+	body, err := client.DownloadFile(ctx, file)
+	if err != nil {
+		t.Fatalf("DownloadFile failed: %v", err)
+	}
+	defer body.Close()
+	if data, _ := io.ReadAll(body); !bytes.Equal(data, content) {
+		t.Error("downloaded content differs from the upload")
+	}
+}
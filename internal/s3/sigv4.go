@@ -0,0 +1,113 @@
+package s3
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// signingAlgorithm is the AWS Signature Version 4 algorithm name
+	signingAlgorithm = "AWS4-HMAC-SHA256"
+	// amzDateFormat is the format of X-Amz-Date
+	amzDateFormat = "20060102T150405Z"
+	// emptyPayloadHash is the SHA-256 of an empty body
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// payloadHash returns the hex SHA-256 of a request body, as sent in X-Amz-Content-Sha256
+func payloadHash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// sign adds the X-Amz-Date, X-Amz-Content-Sha256 and Authorization headers of AWS
+// Signature Version 4 to req. Host, X-Amz-Content-Sha256 and X-Amz-Date are signed.
+func sign(req *http.Request, accessKey, secretKey, region, bodyHash string, now time.Time) {
+	amzDate := now.UTC().Format(amzDateFormat)
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", bodyHash)
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		"host:" + host + "\n" +
+			"x-amz-content-sha256:" + bodyHash + "\n" +
+			"x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		bodyHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := signingAlgorithm + "\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", signingAlgorithm+" Credential="+accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery returns the query parameters sorted by name with names and values
+// escaped as required by Signature Version 4
+func canonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		vs := append([]string(nil), values[k]...)
+		sort.Strings(vs)
+		for _, v := range vs {
+			parts = append(parts, escape(k, true)+"="+escape(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// escape percent-encodes everything but the unreserved characters of RFC 3986. Slashes
+// are kept unless encodeSlash is set, as object keys are sent as paths.
+func escape(s string, encodeSlash bool) string {
+	const hexDigits = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hexDigits[c>>4])
+			b.WriteByte(hexDigits[c&15])
+		}
+	}
+	return b.String()
+}
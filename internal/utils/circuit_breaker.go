@@ -2,6 +2,7 @@ package utils
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -32,6 +33,13 @@ func (s CircuitState) String() string {
 	}
 }
 
+// ErrCircuitOpen is returned by Execute while the circuit is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// ErrAborted is returned by a guarded function that was aborted before its outcome said
+// anything about the service, e.g. on shutdown. It is recorded neither as success nor as failure.
+var ErrAborted = errors.New("aborted")
+
 // CircuitBreakerConfig configures circuit breaker behavior
 type CircuitBreakerConfig struct {
 	MaxFailures     int           // Number of failures before opening
@@ -58,6 +66,7 @@ type CircuitBreaker struct {
 	mutex           sync.RWMutex
 	name            string
 	logger          ClientLogger
+	onStateChange   func(from, to CircuitState)
 }
 
 // NewCircuitBreaker creates a new circuit breaker
@@ -74,27 +83,54 @@ func NewCircuitBreaker(name string, config *CircuitBreakerConfig) *CircuitBreake
 	}
 }
 
+// SetStateChangeHandler sets a function that is called on every state transition
+// instead of logging it. It is called with the breaker locked and must not call back into it.
+func (cb *CircuitBreaker) SetStateChangeHandler(fn func(from, to CircuitState)) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	cb.onStateChange = fn
+}
+
 // Execute executes a function with circuit breaker protection
 func (cb *CircuitBreaker) Execute(ctx context.Context, fn func(ctx context.Context) error) error {
 	if !cb.canExecute() {
-		return fmt.Errorf("circuit breaker '%s' is OPEN", cb.name)
+		return fmt.Errorf("circuit breaker '%s': %w", cb.name, ErrCircuitOpen)
+	}
+	
+	err := fn(ctx)
+	cb.recordResult(err)
+	return err
+}
+
+// Probe executes a function regardless of the state. An open circuit is switched to
+// half-open first, so the result decides whether it closes again.
+func (cb *CircuitBreaker) Probe(ctx context.Context, fn func(ctx context.Context) error) error {
+	cb.mutex.Lock()
+	if cb.state == CircuitOpen {
+		cb.setState(CircuitHalfOpen)
 	}
+	cb.mutex.Unlock()
 	
 	err := fn(ctx)
 	cb.recordResult(err)
 	return err
 }
 
-// canExecute checks if the circuit breaker allows execution
+// canExecute checks if the circuit breaker allows execution. Once the reset timeout
+// has passed an open circuit becomes half-open and lets the next call through as a probe.
 func (cb *CircuitBreaker) canExecute() bool {
-	cb.mutex.RLock()
-	defer cb.mutex.RUnlock()
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
 	
 	switch cb.state {
 	case CircuitClosed:
 		return true
 	case CircuitOpen:
-		return time.Since(cb.lastFailureTime) >= cb.config.ResetTimeout
+		if time.Since(cb.lastFailureTime) >= cb.config.ResetTimeout {
+			cb.setState(CircuitHalfOpen)
+			return true
+		}
+		return false
 	case CircuitHalfOpen:
 		return true
 	default:
@@ -104,6 +140,9 @@ func (cb *CircuitBreaker) canExecute() bool {
 
 // recordResult records the result of an execution
 func (cb *CircuitBreaker) recordResult(err error) {
+	if errors.Is(err, ErrAborted) {
+		return
+	}
 	cb.mutex.Lock()
 	defer cb.mutex.Unlock()
 	
@@ -161,6 +200,10 @@ func (cb *CircuitBreaker) setState(newState CircuitState) {
 			cb.successes = 0
 		}
 		
+		if cb.onStateChange != nil {
+			cb.onStateChange(oldState, newState)
+			return
+		}
 		cb.logger.LogOperation(INFO, "utils", "circuit_breaker", "state", "change", 
 			fmt.Sprintf("Circuit breaker '%s' state changed: %s -> %s", cb.name, oldState, newState), 
 			map[string]interface{}{"circuit_breaker": cb.name, "old_state": oldState.String(), "new_state": newState.String()})
@@ -174,6 +217,16 @@ func (cb *CircuitBreaker) GetState() CircuitState {
 	return cb.state
 }
 
+// OpenUntil returns when an open circuit lets the next probe through, zero if it is not open
+func (cb *CircuitBreaker) OpenUntil() time.Time {
+	cb.mutex.RLock()
+	defer cb.mutex.RUnlock()
+	if cb.state != CircuitOpen {
+		return time.Time{}
+	}
+	return cb.lastFailureTime.Add(cb.config.ResetTimeout)
+}
+
 // GetStats returns current statistics
 func (cb *CircuitBreaker) GetStats() (CircuitState, int, int) {
 	cb.mutex.RLock()
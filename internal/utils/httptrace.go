@@ -0,0 +1,220 @@
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// HTTP operations reported by TracingTransport
+const (
+	HTTPOperationUpload   = "upload"
+	HTTPOperationDownload = "download"
+	HTTPOperationChunk    = "chunk"
+	// HTTPOperationOther is reported to a ConnectionObserver for requests without operation
+	HTTPOperationOther = "other"
+)
+
+// HTTPTimings breaks the duration of a single HTTP request into its phases.
+// Phases that did not happen (e.g. DNS and TLS on a reused connection) are zero.
+type HTTPTimings struct {
+	DNS     time.Duration // DNS lookup
+	Connect time.Duration // TCP connect
+	TLS     time.Duration // TLS handshake
+	// TTFB is the time from the request being fully written to the first response byte,
+	// i.e. the processing time of the server
+	TTFB time.Duration
+	// Transfer is the time spent sending the request body plus reading the response body
+	Transfer time.Duration
+	// ReusedConn is set if the request was sent over an idle keep-alive connection
+	ReusedConn bool
+}
+
+// HTTPPhaseObserver receives the timings of traced requests. It is implemented by the
+// agent so that clients can report metrics without importing it.
+type HTTPPhaseObserver interface {
+	ObserveHTTPPhases(operation string, timings HTTPTimings)
+}
+
+// ConnectionObserver is optionally implemented by an HTTPPhaseObserver to learn for every
+// request, marked or not, whether it was sent over a reused keep-alive connection
+type ConnectionObserver interface {
+	ObserveConnection(operation string, reused bool)
+}
+
+type httpOperationKey struct{}
+
+// WithHTTPOperation marks a request so that TracingTransport reports its phase timings
+// under the given operation
+func WithHTTPOperation(req *http.Request, operation string) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), httpOperationKey{}, operation))
+}
+
+// HTTPOperation returns the operation a request was marked with, "" if none
+func HTTPOperation(req *http.Request) string {
+	operation, _ := req.Context().Value(httpOperationKey{}).(string)
+	return operation
+}
+
+// TracingTransport is an http.RoundTripper that measures the phases of requests marked
+// with WithHTTPOperation using net/http/httptrace. Of unmarked requests only the
+// connection reuse is reported, if the observer is a ConnectionObserver.
+type TracingTransport struct {
+	Base     http.RoundTripper
+	Observer HTTPPhaseObserver
+}
+
+// NewTracingTransport wraps base (http.DefaultTransport if nil) with phase tracing
+func NewTracingTransport(base http.RoundTripper, observer HTTPPhaseObserver) *TracingTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &TracingTransport{Base: base, Observer: observer}
+}
+
+// CloseIdleConnections closes the idle connections of the wrapped transport
+func (t *TracingTransport) CloseIdleConnections() {
+	closeIdleConnections(t.Base)
+}
+
+// closeIdleConnections closes the idle connections of rt if it keeps any
+func closeIdleConnections(rt http.RoundTripper) {
+	if closer, ok := rt.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *TracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	operation := HTTPOperation(req)
+	connObserver, _ := t.Observer.(ConnectionObserver)
+	if operation == "" {
+		if connObserver == nil {
+			return t.Base.RoundTrip(req)
+		}
+		trace := &httptrace.ClientTrace{
+			GotConn: func(info httptrace.GotConnInfo) {
+				connObserver.ObserveConnection(HTTPOperationOther, info.Reused)
+			},
+		}
+		return t.Base.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+	}
+	if t.Observer == nil {
+		return t.Base.RoundTrip(req)
+	}
+
+	rt := &requestTrace{operation: operation, connObserver: connObserver}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), rt.clientTrace()))
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	// The transfer phase ends when the caller has consumed the body
+	resp.Body = &tracedBody{
+		ReadCloser: resp.Body,
+		done: func() {
+			t.Observer.ObserveHTTPPhases(operation, rt.timings(time.Now()))
+		},
+	}
+	return resp, nil
+}
+
+// requestTrace collects the httptrace events of one request. The callbacks may be
+// invoked from transport goroutines, hence the mutex.
+type requestTrace struct {
+	mu sync.Mutex
+
+	dnsStart, dnsDone         time.Time
+	connectStart, connectDone time.Time
+	tlsStart, tlsDone         time.Time
+	gotConn, wroteRequest     time.Time
+	firstByte                 time.Time
+	reused                    bool
+
+	operation    string
+	connObserver ConnectionObserver // nil if connection reuse is not reported
+}
+
+func (rt *requestTrace) set(field *time.Time) {
+	rt.mu.Lock()
+	*field = time.Now()
+	rt.mu.Unlock()
+}
+
+func (rt *requestTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { rt.set(&rt.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { rt.set(&rt.dnsDone) },
+		ConnectStart: func(string, string) {
+			rt.mu.Lock()
+			// Only the first dial attempt counts (happy eyeballs may start several)
+			if rt.connectStart.IsZero() {
+				rt.connectStart = time.Now()
+			}
+			rt.mu.Unlock()
+		},
+		ConnectDone: func(string, string, error) { rt.set(&rt.connectDone) },
+		TLSHandshakeStart: func() { rt.set(&rt.tlsStart) },
+		TLSHandshakeDone: func(tls.ConnectionState, error) { rt.set(&rt.tlsDone) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			rt.mu.Lock()
+			rt.gotConn = time.Now()
+			rt.reused = info.Reused
+			rt.mu.Unlock()
+			if rt.connObserver != nil {
+				rt.connObserver.ObserveConnection(rt.operation, info.Reused)
+			}
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { rt.set(&rt.wroteRequest) },
+		GotFirstResponseByte: func() { rt.set(&rt.firstByte) },
+	}
+}
+
+// timings computes the phase durations for a request whose body was consumed at end
+func (rt *requestTrace) timings(end time.Time) HTTPTimings {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	t := HTTPTimings{
+		DNS:        between(rt.dnsStart, rt.dnsDone),
+		Connect:    between(rt.connectStart, rt.connectDone),
+		TLS:        between(rt.tlsStart, rt.tlsDone),
+		TTFB:       between(rt.wroteRequest, rt.firstByte),
+		ReusedConn: rt.reused,
+	}
+	t.Transfer = between(rt.gotConn, rt.wroteRequest) + between(rt.firstByte, end)
+	return t
+}
+
+// between returns end-start, or zero if either event did not happen
+func between(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// tracedBody reports the request timings once the body is read to EOF or closed
+type tracedBody struct {
+	io.ReadCloser
+	once sync.Once
+	done func()
+}
+
+func (b *tracedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.once.Do(b.done)
+	}
+	return n, err
+}
+
+func (b *tracedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.done)
+	return err
+}
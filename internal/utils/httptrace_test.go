@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingObserver struct {
+	mu      sync.Mutex
+	reports map[string][]HTTPTimings
+}
+
+func (o *recordingObserver) ObserveHTTPPhases(operation string, timings HTTPTimings) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.reports == nil {
+		o.reports = make(map[string][]HTTPTimings)
+	}
+	o.reports[operation] = append(o.reports[operation], timings)
+}
+
+func TestTracingTransportReportsMarkedRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte("payload"))
+	}))
+	defer server.Close()
+
+	observer := &recordingObserver{}
+	client := &http.Client{Transport: NewTracingTransport(nil, observer)}
+
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		resp, err := client.Do(WithHTTPOperation(req, HTTPOperationDownload))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		_, _ = io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+
+	reports := observer.reports[HTTPOperationDownload]
+	if len(reports) != 2 {
+		t.Fatalf("expected 2 download reports, got %d", len(reports))
+	}
+	if reports[0].TTFB < 20*time.Millisecond {
+		t.Errorf("expected TTFB to include server processing time, got %v", reports[0].TTFB)
+	}
+	if reports[0].ReusedConn || reports[0].Connect == 0 {
+		t.Errorf("expected first request to dial a new connection, got %+v", reports[0])
+	}
+	if !reports[1].ReusedConn || reports[1].Connect != 0 {
+		t.Errorf("expected second request to reuse the connection, got %+v", reports[1])
+	}
+}
+
+func TestTracingTransportIgnoresUnmarkedRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	observer := &recordingObserver{}
+	client := &http.Client{Transport: NewTracingTransport(nil, observer)}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if len(observer.reports) != 0 {
+		t.Errorf("expected no reports for unmarked request, got %v", observer.reports)
+	}
+}
+
+type connectionObserver struct {
+	recordingObserver
+	reused []string
+}
+
+func (o *connectionObserver) ObserveConnection(operation string, reused bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	state := "new"
+	if reused {
+		state = "reused"
+	}
+	o.reused = append(o.reused, operation+":"+state)
+}
+
+func TestTracingTransportReportsConnectionReuse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("payload"))
+	}))
+	defer server.Close()
+
+	observer := &connectionObserver{}
+	transport := NewRetryTransport(NewTracingTransport(&http.Transport{}, observer), DefaultRetryConfig(), nil)
+	client := &http.Client{Transport: transport}
+
+	get := func(operation string) {
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		if operation != "" {
+			req = WithHTTPOperation(req, operation)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		_, _ = io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+
+	get(HTTPOperationDownload)
+	get("")
+	// Closing through the wrapping transports forces a new connection
+	client.CloseIdleConnections()
+	get("")
+
+	want := []string{"download:new", "other:reused", "other:new"}
+	if len(observer.reused) != len(want) {
+		t.Fatalf("expected %v, got %v", want, observer.reused)
+	}
+	for i := range want {
+		if observer.reused[i] != want[i] {
+			t.Errorf("expected %v, got %v", want, observer.reused)
+			break
+		}
+	}
+}
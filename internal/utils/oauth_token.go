@@ -0,0 +1,42 @@
+package utils
+
+import "time"
+
+// TokenRefreshMargin is how long before its expiry an access token is refreshed, so
+// that it does not expire in the middle of a test run
+const TokenRefreshMargin = 5 * time.Minute
+
+// OAuthToken is an OAuth2 access token together with the refresh token of its grant
+type OAuthToken struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	// Expiry is when the access token expires, zero if the provider did not say
+	Expiry time.Time `json:"expiry,omitempty"`
+}
+
+// NeedsRefresh reports whether the access token is missing or expires within margin.
+// A token without a known expiry is used until the provider rejects it.
+func (t OAuthToken) NeedsRefresh(now time.Time, margin time.Duration) bool {
+	if t.AccessToken == "" {
+		return true
+	}
+	return !t.Expiry.IsZero() && !now.Add(margin).Before(t.Expiry)
+}
+
+// TokenExpiry returns the expiry of a token issued at now with the given expires_in
+// seconds, zero if expires_in is not set
+func TokenExpiry(now time.Time, expiresIn int) time.Time {
+	if expiresIn <= 0 {
+		return time.Time{}
+	}
+	return now.Add(time.Duration(expiresIn) * time.Second)
+}
+
+// TokenObserver is notified by the OAuth2 clients about token refreshes, e.g. to persist
+// rotated refresh tokens and to export token metrics
+type TokenObserver interface {
+	// TokenRefreshed is called with the new token after a successful refresh
+	TokenRefreshed(token OAuthToken)
+	// TokenRefreshFailed is called when a refresh failed after all retries
+	TokenRefreshFailed(err error)
+}
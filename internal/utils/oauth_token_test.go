@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOAuthTokenNeedsRefresh(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		token    OAuthToken
+		expected bool
+	}{
+		{name: "missing access token", token: OAuthToken{}, expected: true},
+		{name: "unknown expiry", token: OAuthToken{AccessToken: "a"}, expected: false},
+		{name: "valid", token: OAuthToken{AccessToken: "a", Expiry: now.Add(time.Hour)}, expected: false},
+		{name: "expires within margin", token: OAuthToken{AccessToken: "a", Expiry: now.Add(time.Minute)}, expected: true},
+		{name: "expired", token: OAuthToken{AccessToken: "a", Expiry: now.Add(-time.Minute)}, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.token.NeedsRefresh(now, TokenRefreshMargin); got != tt.expected {
+				t.Errorf("NeedsRefresh = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTokenExpiry(t *testing.T) {
+	now := time.Now()
+	if expiry := TokenExpiry(now, 14400); !expiry.Equal(now.Add(4 * time.Hour)) {
+		t.Errorf("unexpected expiry %v", expiry)
+	}
+	if expiry := TokenExpiry(now, 0); !expiry.IsZero() {
+		t.Errorf("expected no expiry without expires_in, got %v", expiry)
+	}
+}
@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ProviderError is returned by the provider clients for a failed operation. It carries the
+// HTTP status and the provider's own error tag, so that callers can classify the failure
+// with errors.As instead of searching the error message.
+type ProviderError struct {
+	Provider  string
+	Operation string // client operation, e.g. "upload", "delete", "token_refresh"
+	Phase     string // step of the operation, e.g. "chunk", "assemble"; empty for single-step operations
+	// StatusCode is the HTTP status of the failed request, 0 if no response was received
+	StatusCode int
+	// Tag is the provider's error tag, e.g. the Dropbox error summary "path/not_found"
+	// or the HiDrive error code
+	Tag string
+	Err error
+}
+
+func (e *ProviderError) Error() string {
+	step := e.Operation
+	if e.Phase != "" {
+		step += " " + e.Phase
+	}
+	return fmt.Sprintf("%s failed: %v", step, e.Err)
+}
+
+func (e *ProviderError) Unwrap() error { return e.Err }
+
+// NewStatusError creates a ProviderError for a response with an unexpected status. parseTag
+// extracts the provider's error tag from the response body and may be nil. The body is
+// read but not closed.
+func NewStatusError(provider, operation, phase string, resp *http.Response, parseTag func(body string) string) *ProviderError {
+	statusErr := NewHTTPStatusError(resp)
+	err := &ProviderError{
+		Provider:   provider,
+		Operation:  operation,
+		Phase:      phase,
+		StatusCode: resp.StatusCode,
+		Err:        statusErr,
+	}
+	if parseTag != nil {
+		err.Tag = parseTag(statusErr.Body)
+	}
+	return err
+}
+
+// WrapProviderError annotates err (e.g. a network error) with the provider, operation and
+// phase it occurred in. nil stays nil and a ProviderError is returned unchanged.
+func WrapProviderError(provider, operation, phase string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) {
+		return err
+	}
+	return &ProviderError{Provider: provider, Operation: operation, Phase: phase, Err: err}
+}
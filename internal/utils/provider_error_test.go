@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNewStatusError(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: http.StatusConflict,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(`path/not_found`)),
+	}
+	err := NewStatusError("dropbox", "download", "", resp, func(body string) string { return body })
+
+	if err.StatusCode != http.StatusConflict || err.Tag != "path/not_found" {
+		t.Errorf("unexpected status or tag: %+v", err)
+	}
+	var statusErr *HTTPStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusConflict {
+		t.Errorf("expected the HTTP status error to be wrapped, got %v", err)
+	}
+	if !strings.HasPrefix(err.Error(), "download failed: ") {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+}
+
+func TestWrapProviderError(t *testing.T) {
+	if WrapProviderError("nextcloud", "upload", "chunk", nil) != nil {
+		t.Error("expected nil for a nil error")
+	}
+
+	cause := errors.New("connection reset")
+	err := WrapProviderError("nextcloud", "upload", "chunk", cause)
+	if !errors.Is(err, cause) || err.Error() != "upload chunk failed: connection reset" {
+		t.Errorf("unexpected wrapped error %v", err)
+	}
+	if again := WrapProviderError("nextcloud", "upload", "", err); again != err {
+		t.Errorf("expected a provider error to be returned unchanged, got %v", again)
+	}
+}
@@ -2,17 +2,27 @@ package utils
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
+	"math/rand"
 	"time"
 )
 
 // RetryConfig configures retry behavior
 type RetryConfig struct {
-	MaxRetries      int
-	InitialDelay    time.Duration
-	MaxDelay        time.Duration
-	BackoffFactor   float64
+	MaxRetries    int
+	InitialDelay  time.Duration
+	MaxDelay      time.Duration
+	BackoffFactor float64
+	// Jitter randomly shortens each delay by up to this fraction (0-1) so that
+	// clients failing at the same time do not retry in lockstep
+	Jitter float64
+	// MaxRetryAfter is the longest delay requested by a provider (Retry-After or
+	// rate-limit headers) that is waited for; a longer one fails the operation
+	MaxRetryAfter time.Duration
+	// RetryableErrors are message substrings that mark untyped errors as retryable.
+	// Typed errors (HTTPStatusError, net.Error, ...) are classified by IsRetryableError.
 	RetryableErrors []string
 	logger          ClientLogger
 }
@@ -24,6 +34,8 @@ func DefaultRetryConfig() *RetryConfig {
 		InitialDelay:  1 * time.Second,
 		MaxDelay:      30 * time.Second,
 		BackoffFactor: 2.0,
+		Jitter:        0.5,
+		MaxRetryAfter: 60 * time.Second,
 		RetryableErrors: []string{
 			"connection refused",
 			"timeout",
@@ -38,10 +50,11 @@ func DefaultRetryConfig() *RetryConfig {
 // RetryableFunc is a function that can be retried
 type RetryableFunc func(ctx context.Context) error
 
-// IsRetryableError checks if an error is retryable
+// IsRetryableError checks if an error is retryable. Typed errors are classified by
+// their type; only errors without type information are matched against RetryableErrors.
 func (rc *RetryConfig) IsRetryableError(err error) bool {
-	if err == nil {
-		return false
+	if retryable, known := classifyError(err); known {
+		return retryable
 	}
 	
 	errStr := err.Error()
@@ -59,7 +72,12 @@ func (rc *RetryConfig) WithRetry(ctx context.Context, operation string, fn Retry
 	
 	for attempt := 0; attempt <= rc.MaxRetries; attempt++ {
 		if attempt > 0 {
-			delay := rc.calculateDelay(attempt)
+			var retryAfter time.Duration
+			var statusErr *HTTPStatusError
+			if errors.As(lastErr, &statusErr) {
+				retryAfter = statusErr.RetryAfter
+			}
+			delay := rc.RetryDelay(attempt, retryAfter)
 			rc.logger.LogOperation(DEBUG, "utils", "retry", "retry", "attempt", 
 				fmt.Sprintf("Retrying %s (attempt %d/%d) after %v delay", operation, attempt, rc.MaxRetries, delay), 
 				map[string]interface{}{"operation": operation, "attempt": attempt, "max_retries": rc.MaxRetries, "delay": delay})
@@ -82,6 +100,13 @@ func (rc *RetryConfig) WithRetry(ctx context.Context, operation string, fn Retry
 		}
 		
 		// Check if error is retryable
+		var statusErr *HTTPStatusError
+		if errors.As(lastErr, &statusErr) && statusErr.RetryAfter > rc.MaxRetryAfter {
+			rc.logger.LogOperation(ERROR, "utils", "retry", "retry", "retry_after_exceeded", 
+				fmt.Sprintf("%s asked to wait %v, longer than %v: %v", operation, statusErr.RetryAfter, rc.MaxRetryAfter, lastErr), 
+				map[string]interface{}{"operation": operation, "retry_after": statusErr.RetryAfter, "error": lastErr.Error()})
+			return lastErr
+		}
 		if !rc.IsRetryableError(lastErr) {
 			rc.logger.LogOperation(ERROR, "utils", "retry", "retry", "non_retryable", 
 				fmt.Sprintf("Non-retryable error in %s: %v", operation, lastErr), 
@@ -106,6 +131,22 @@ func (rc *RetryConfig) calculateDelay(attempt int) time.Duration {
 	return time.Duration(delay)
 }
 
+// RetryDelay returns the jittered backoff before retry number attempt (starting at 1).
+// A delay requested by the server (Retry-After) is honoured up to MaxRetryAfter.
+func (rc *RetryConfig) RetryDelay(attempt int, retryAfter time.Duration) time.Duration {
+	delay := rc.calculateDelay(attempt)
+	if rc.Jitter > 0 {
+		delay -= time.Duration(rand.Float64() * rc.Jitter * float64(delay))
+	}
+	if retryAfter > delay {
+		delay = retryAfter
+		if delay > rc.MaxRetryAfter {
+			delay = rc.MaxRetryAfter
+		}
+	}
+	return delay
+}
+
 // contains checks if a string contains a substring (case-insensitive)
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && 
@@ -123,3 +123,85 @@ func TestCircuitBreakerOpening(t *testing.T) {
 		t.Error("Expected error when circuit is open")
 	}
 }
+
+func TestCircuitBreakerHalfOpenAfterReset(t *testing.T) {
+	config := &CircuitBreakerConfig{
+		MaxFailures:      1,
+		ResetTimeout:     20 * time.Millisecond,
+		SuccessThreshold: 1,
+	}
+	cb := NewCircuitBreaker("test", config)
+	
+	var transitions []CircuitState
+	cb.SetStateChangeHandler(func(from, to CircuitState) {
+		transitions = append(transitions, to)
+	})
+	
+	_ = cb.Execute(context.Background(), func(ctx context.Context) error {
+		return errors.New("failure")
+	})
+	err := cb.Execute(context.Background(), func(ctx context.Context) error {
+		return nil
+	})
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen, got: %v", err)
+	}
+	
+	// After the reset timeout the next call is a probe in half-open state
+	time.Sleep(30 * time.Millisecond)
+	var probeState CircuitState
+	err = cb.Execute(context.Background(), func(ctx context.Context) error {
+		probeState = cb.state
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Expected probe to run, got error: %v", err)
+	}
+	if probeState != CircuitHalfOpen {
+		t.Errorf("Expected probe to run half-open, got: %v", probeState)
+	}
+	if cb.GetState() != CircuitClosed {
+		t.Errorf("Expected circuit to close after successful probe, got: %v", cb.GetState())
+	}
+	
+	want := []CircuitState{CircuitOpen, CircuitHalfOpen, CircuitClosed}
+	if len(transitions) != len(want) {
+		t.Fatalf("Expected transitions %v, got %v", want, transitions)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Errorf("Expected transitions %v, got %v", want, transitions)
+			break
+		}
+	}
+}
+
+func TestCircuitBreakerProbeWhileOpen(t *testing.T) {
+	cb := NewCircuitBreaker("test", &CircuitBreakerConfig{
+		MaxFailures:      1,
+		ResetTimeout:     time.Hour,
+		SuccessThreshold: 1,
+	})
+	
+	_ = cb.Execute(context.Background(), func(ctx context.Context) error {
+		return errors.New("failure")
+	})
+	if cb.OpenUntil().IsZero() {
+		t.Error("Expected OpenUntil to be set while open")
+	}
+	
+	// A failed probe keeps the circuit open
+	_ = cb.Probe(context.Background(), func(ctx context.Context) error {
+		return errors.New("still failing")
+	})
+	if cb.GetState() != CircuitOpen {
+		t.Errorf("Expected circuit to stay open after failed probe, got: %v", cb.GetState())
+	}
+	
+	if err := cb.Probe(context.Background(), func(ctx context.Context) error { return nil }); err != nil {
+		t.Errorf("Expected probe to run while open, got error: %v", err)
+	}
+	if cb.GetState() != CircuitClosed {
+		t.Errorf("Expected circuit to close after successful probe, got: %v", cb.GetState())
+	}
+}
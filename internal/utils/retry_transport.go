@@ -0,0 +1,304 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// HTTPStatusError reports an unexpected HTTP response status
+type HTTPStatusError struct {
+	StatusCode int
+	Status     string
+	// RetryAfter is the delay requested by the provider (see RateLimitDelay), zero if absent
+	RetryAfter time.Duration
+	// RateLimited is set if the provider rejected the request because of rate limiting
+	RateLimited bool
+	Body        string
+}
+
+// NewHTTPStatusError creates an HTTPStatusError from a response, reading up to 4 KiB of its body
+func NewHTTPStatusError(resp *http.Response) *HTTPStatusError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	retryAfter, _ := RateLimitDelay(resp.Header, time.Now())
+	return &HTTPStatusError{
+		StatusCode:  resp.StatusCode,
+		Status:      resp.Status,
+		RetryAfter:  retryAfter,
+		RateLimited: IsRateLimited(resp),
+		Body:        strings.TrimSpace(string(body)),
+	}
+}
+
+func (e *HTTPStatusError) Error() string {
+	status := e.Status
+	if status == "" {
+		status = strconv.Itoa(e.StatusCode)
+	}
+	if e.Body == "" {
+		return fmt.Sprintf("status %s", status)
+	}
+	return fmt.Sprintf("status %s: %s", status, e.Body)
+}
+
+// ParseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// RateLimitDelay returns the delay a provider asks for before the next request. Besides
+// Retry-After it understands the rate-limit headers RateLimit-Reset (seconds until the
+// limit resets) and X-RateLimit-Reset (seconds or a Unix timestamp).
+func RateLimitDelay(header http.Header, now time.Time) (time.Duration, bool) {
+	if delay, ok := ParseRetryAfter(header.Get("Retry-After"), now); ok {
+		return delay, true
+	}
+	for _, name := range []string{"RateLimit-Reset", "X-RateLimit-Reset"} {
+		value, err := strconv.ParseInt(strings.TrimSpace(header.Get(name)), 10, 64)
+		if err != nil || value < 0 {
+			continue
+		}
+		// Values that large are not a delay but the time of the reset
+		if value > 1e9 {
+			if d := time.Unix(value, 0).Sub(now); d > 0 {
+				return d, true
+			}
+			return 0, true
+		}
+		return time.Duration(value) * time.Second, true
+	}
+	return 0, false
+}
+
+// IsRateLimited reports whether a provider rejected a request because of rate limiting:
+// status 429, or an error status while the rate-limit headers report no remaining requests
+func IsRateLimited(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	if resp.StatusCode < 400 {
+		return false
+	}
+	for _, name := range []string{"RateLimit-Remaining", "X-RateLimit-Remaining"} {
+		if strings.TrimSpace(resp.Header.Get(name)) == "0" {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRetryableStatus reports whether a request that failed with the given HTTP status
+// may succeed when sent again
+func IsRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests,
+		http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// classifyError decides whether err is transient. known is false for errors that carry
+// no type information; callers may fall back to message heuristics for those.
+func classifyError(err error) (retryable, known bool) {
+	if err == nil {
+		return false, true
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false, true
+	}
+
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.RateLimited || IsRetryableStatus(statusErr.StatusCode), true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true, true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		// An unknown host is a configuration error, not a transient failure
+		return dnsErr.IsTemporary || dnsErr.IsTimeout, true
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true, true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true, true
+	}
+	return false, false
+}
+
+// IsRetryableError reports whether err is a transient network error or a retryable HTTP status
+func IsRetryableError(err error) bool {
+	retryable, _ := classifyError(err)
+	return retryable
+}
+
+// retryReason returns the label under which a retry is reported
+func retryReason(resp *http.Response, err error) string {
+	if resp != nil {
+		return "http_" + strconv.Itoa(resp.StatusCode)
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return "timeout"
+	}
+	return "network"
+}
+
+// RetryObserver is notified before a request is sent again. It is implemented by the
+// agent so that clients can report retries without importing it.
+type RetryObserver interface {
+	ObserveRetry(operation string, attempt int, reason string, delay time.Duration)
+	// ObserveRateLimit reports the time waited because the provider rate-limited a request
+	ObserveRateLimit(operation string, wait time.Duration)
+}
+
+type idempotentKey struct{}
+
+// WithIdempotent marks a request whose method is not idempotent (e.g. a POST to an API
+// that carries an explicit offset) as safe to send again
+func WithIdempotent(req *http.Request) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), idempotentKey{}, true))
+}
+
+// isIdempotent reports whether a request may be sent more than once
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, "PROPFIND", "MKCOL":
+		return true
+	}
+	marked, _ := req.Context().Value(idempotentKey{}).(bool)
+	return marked
+}
+
+// isReplayable reports whether the body of a request can be sent again
+func isReplayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// RetryTransport is an http.RoundTripper that sends idempotent requests again when they
+// fail with a transient network error or a retryable status (408, 429, 5xx except 501).
+// A rate-limited request was not processed, so it is sent again whatever its method
+// after waiting as long as the provider asks for (up to Config.MaxRetryAfter).
+// Requests with a streamed body that cannot be replayed are sent once.
+type RetryTransport struct {
+	Base     http.RoundTripper
+	Config   *RetryConfig
+	Observer RetryObserver
+}
+
+// NewRetryTransport wraps base (http.DefaultTransport if nil) with retries using config
+// (DefaultRetryConfig if nil)
+func NewRetryTransport(base http.RoundTripper, config *RetryConfig, observer RetryObserver) *RetryTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if config == nil {
+		config = DefaultRetryConfig()
+	}
+	return &RetryTransport{Base: base, Config: config, Observer: observer}
+}
+
+// CloseIdleConnections closes the idle connections of the wrapped transport
+func (t *RetryTransport) CloseIdleConnections() {
+	closeIdleConnections(t.Base)
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.Config.MaxRetries <= 0 || !isReplayable(req) {
+		return t.Base.RoundTrip(req)
+	}
+	idempotent := isIdempotent(req)
+
+	operation := HTTPOperation(req)
+	if operation == "" {
+		operation = strings.ToLower(req.Method)
+	}
+
+	attemptReq := req
+	for attempt := 0; ; attempt++ {
+		resp, err := t.Base.RoundTrip(attemptReq)
+
+		var retryAfter time.Duration
+		rateLimited := false
+		switch {
+		case err != nil:
+			if !idempotent || !IsRetryableError(err) || req.Context().Err() != nil {
+				return nil, err
+			}
+		case IsRateLimited(resp):
+			rateLimited = true
+			retryAfter, _ = RateLimitDelay(resp.Header, time.Now())
+		case idempotent && IsRetryableStatus(resp.StatusCode):
+			retryAfter, _ = RateLimitDelay(resp.Header, time.Now())
+		default:
+			return resp, nil
+		}
+		// Waiting longer than MaxRetryAfter would stall the test; report the failure instead
+		if attempt >= t.Config.MaxRetries || retryAfter > t.Config.MaxRetryAfter {
+			return resp, err
+		}
+
+		delay := t.Config.RetryDelay(attempt+1, retryAfter)
+		reason := retryReason(resp, err)
+		if resp != nil {
+			// Drain a little so the connection can be reused
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+		if t.Observer != nil {
+			t.Observer.ObserveRetry(operation, attempt+1, reason, delay)
+			if rateLimited {
+				t.Observer.ObserveRateLimit(operation, delay)
+			}
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+
+		attemptReq = req.Clone(req.Context())
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq.Body = body
+		}
+	}
+}
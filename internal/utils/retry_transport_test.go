@@ -0,0 +1,319 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type retryReport struct {
+	operation string
+	attempt   int
+	reason    string
+	delay     time.Duration
+}
+
+type recordingRetryObserver struct {
+	mu         sync.Mutex
+	reports    []retryReport
+	rateLimits []time.Duration
+}
+
+func (o *recordingRetryObserver) ObserveRetry(operation string, attempt int, reason string, delay time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.reports = append(o.reports, retryReport{operation, attempt, reason, delay})
+}
+
+func (o *recordingRetryObserver) ObserveRateLimit(operation string, wait time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.rateLimits = append(o.rateLimits, wait)
+}
+
+func fastRetryConfig() *RetryConfig {
+	config := DefaultRetryConfig()
+	config.InitialDelay = time.Millisecond
+	config.MaxDelay = 50 * time.Millisecond
+	return config
+}
+
+func TestRetryTransportRetriesTransientStatus(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if string(body) != "chunk" {
+			t.Errorf("expected replayed body %q, got %q", "chunk", body)
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	observer := &recordingRetryObserver{}
+	client := &http.Client{Transport: NewRetryTransport(nil, fastRetryConfig(), observer)}
+
+	req, _ := http.NewRequest(http.MethodPut, server.URL, bytes.NewReader([]byte("chunk")))
+	resp, err := client.Do(WithHTTPOperation(req, HTTPOperationChunk))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("expected status 201 after retries, got %d", resp.StatusCode)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", calls)
+	}
+	if len(observer.reports) != 2 {
+		t.Fatalf("expected 2 retry reports, got %d", len(observer.reports))
+	}
+	if len(observer.rateLimits) != 0 {
+		t.Errorf("expected a 503 not to be reported as rate limiting, got %v", observer.rateLimits)
+	}
+	for i, report := range observer.reports {
+		if report.operation != HTTPOperationChunk || report.reason != "http_503" || report.attempt != i+1 {
+			t.Errorf("unexpected retry report %+v", report)
+		}
+	}
+}
+
+func TestRetryTransportDoesNotRetry(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		method string
+		body   io.Reader
+	}{
+		{"permanent status", http.StatusNotFound, http.MethodGet, nil},
+		{"non-idempotent method", http.StatusServiceUnavailable, http.MethodPost, nil},
+		{"streamed body", http.StatusServiceUnavailable, http.MethodPut, io.LimitReader(bytes.NewReader([]byte("data")), 4)},
+	}
+	for _, tt := range tests {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(tt.status)
+		}))
+
+		client := &http.Client{Transport: NewRetryTransport(nil, fastRetryConfig(), nil)}
+		req, _ := http.NewRequest(tt.method, server.URL, tt.body)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("%s: request failed: %v", tt.name, err)
+		}
+		resp.Body.Close()
+		server.Close()
+
+		if resp.StatusCode != tt.status || calls != 1 {
+			t.Errorf("%s: expected a single attempt with status %d, got %d attempts and status %d", tt.name, tt.status, calls, resp.StatusCode)
+		}
+	}
+}
+
+func TestRetryTransportMarkedIdempotentPost(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewRetryTransport(nil, fastRetryConfig(), nil)}
+	req, _ := http.NewRequest(http.MethodPost, server.URL, bytes.NewReader([]byte("{}")))
+	resp, err := client.Do(WithIdempotent(req))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || calls != 2 {
+		t.Errorf("expected success on the second attempt, got status %d after %d attempts", resp.StatusCode, calls)
+	}
+}
+
+func TestRetryTransportRateLimited(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	observer := &recordingRetryObserver{}
+	client := &http.Client{Transport: NewRetryTransport(nil, fastRetryConfig(), observer)}
+
+	// A rate-limited request was not processed, so even a POST is sent again
+	start := time.Now()
+	req, _ := http.NewRequest(http.MethodPost, server.URL, bytes.NewReader([]byte("{}")))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || calls != 2 {
+		t.Fatalf("expected success after waiting for the rate limit, got status %d after %d attempts", resp.StatusCode, calls)
+	}
+	if waited := time.Since(start); waited < time.Second {
+		t.Errorf("expected Retry-After to be honoured beyond MaxDelay, waited %v", waited)
+	}
+	if len(observer.rateLimits) != 1 || observer.rateLimits[0] != time.Second {
+		t.Errorf("expected a rate-limit wait of 1s to be reported, got %v", observer.rateLimits)
+	}
+}
+
+func TestRetryTransportRetryAfterTooLong(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewRetryTransport(nil, fastRetryConfig(), nil)}
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	statusErr := NewHTTPStatusError(resp)
+	resp.Body.Close()
+
+	if calls != 1 {
+		t.Errorf("expected no retry for a wait beyond MaxRetryAfter, got %d attempts", calls)
+	}
+	if !statusErr.RateLimited || statusErr.RetryAfter != time.Hour {
+		t.Errorf("expected a rate-limited status error asking for 1h, got %+v", statusErr)
+	}
+}
+
+func TestRetryTransportStopsOnCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	config := fastRetryConfig()
+	config.InitialDelay = time.Hour
+	config.MaxDelay = time.Hour
+	client := &http.Client{Transport: NewRetryTransport(nil, config, nil)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if _, err := client.Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded while waiting to retry, got %v", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"120", 2 * time.Minute, true},
+		{"-1", 0, false},
+		{now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second, true},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseRetryAfter(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRateLimitDelay(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+		ok     bool
+	}{
+		{"none", http.Header{}, 0, false},
+		{"retry-after wins", http.Header{"Retry-After": {"5"}, "Ratelimit-Reset": {"60"}}, 5 * time.Second, true},
+		{"ratelimit-reset seconds", http.Header{"Ratelimit-Reset": {"60"}}, time.Minute, true},
+		{"x-ratelimit-reset timestamp", http.Header{"X-Ratelimit-Reset": {fmt.Sprint(now.Add(90 * time.Second).Unix())}}, 90 * time.Second, true},
+		{"invalid", http.Header{"X-Ratelimit-Reset": {"later"}}, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := RateLimitDelay(tt.header, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%s: RateLimitDelay = %v, %v; want %v, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	exhausted := &http.Response{StatusCode: http.StatusForbidden, Header: http.Header{"X-Ratelimit-Remaining": {"0"}}}
+	if !IsRateLimited(exhausted) {
+		t.Error("expected an error status with no remaining requests to count as rate limited")
+	}
+	if IsRateLimited(&http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{}}) {
+		t.Error("expected a plain 503 not to count as rate limited")
+	}
+}
+
+func TestIsRetryableErrorTyped(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"503", fmt.Errorf("upload failed with %w", &HTTPStatusError{StatusCode: 503}), true},
+		{"429", &HTTPStatusError{StatusCode: 429}, true},
+		{"404", &HTTPStatusError{StatusCode: 404}, false},
+		{"501", &HTTPStatusError{StatusCode: 501}, false},
+		{"unknown host", &net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}, false},
+		{"dns timeout", &net.DNSError{Err: "i/o timeout", Name: "example.com", IsTimeout: true}, true},
+		{"cancelled", context.Canceled, false},
+		{"unexpected EOF", fmt.Errorf("reading body: %w", io.ErrUnexpectedEOF), true},
+	}
+	for _, tt := range tests {
+		if got := IsRetryableError(tt.err); got != tt.want {
+			t.Errorf("%s: IsRetryableError = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRetryDelayJitterAndRetryAfter(t *testing.T) {
+	config := DefaultRetryConfig()
+	for i := 0; i < 100; i++ {
+		delay := config.RetryDelay(2, 0)
+		if delay > 2*time.Second || delay < time.Second {
+			t.Fatalf("expected jittered delay within [1s, 2s], got %v", delay)
+		}
+	}
+	if delay := config.RetryDelay(1, 10*time.Second); delay != 10*time.Second {
+		t.Errorf("expected Retry-After to be honoured, got %v", delay)
+	}
+	if delay := config.RetryDelay(1, time.Hour); delay != config.MaxRetryAfter {
+		t.Errorf("expected Retry-After to be capped at %v, got %v", config.MaxRetryAfter, delay)
+	}
+}
@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"encoding/xml"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// PropfindBody requests the properties needed to list a WebDAV collection
+const PropfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:">
+  <d:prop>
+    <d:resourcetype/>
+    <d:getcontentlength/>
+    <d:getlastmodified/>
+  </d:prop>
+</d:propfind>`
+
+// DAVResource is a single entry of a WebDAV multistatus response
+type DAVResource struct {
+	Href          string
+	IsCollection  bool
+	ContentLength int64
+}
+
+// multistatus mirrors the parts of a PROPFIND response we need
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"href"`
+		Propstat []struct {
+			Prop struct {
+				ResourceType struct {
+					Collection *struct{} `xml:"collection"`
+				} `xml:"resourcetype"`
+				ContentLength int64 `xml:"getcontentlength"`
+			} `xml:"prop"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+// davError mirrors a Sabre/DAV error body (<d:error><s:exception>...</s:exception></d:error>)
+// as returned by Nextcloud, HiDrive and MagentaCLOUD
+type davError struct {
+	Exception string `xml:"exception"`
+}
+
+// ParseWebDAVErrorTag returns the exception class of a WebDAV error body, e.g.
+// "Sabre\DAV\Exception\InsufficientStorage", or "" if body is not a WebDAV error
+func ParseWebDAVErrorTag(body string) string {
+	var e davError
+	if err := xml.Unmarshal([]byte(body), &e); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(e.Exception)
+}
+
+// ParseMultistatus decodes a WebDAV multistatus (207) response body
+func ParseMultistatus(r io.Reader) ([]DAVResource, error) {
+	var ms multistatus
+	if err := xml.NewDecoder(r).Decode(&ms); err != nil {
+		return nil, err
+	}
+
+	resources := make([]DAVResource, 0, len(ms.Responses))
+	for _, resp := range ms.Responses {
+		res := DAVResource{Href: resp.Href}
+		for _, ps := range resp.Propstat {
+			if ps.Prop.ResourceType.Collection != nil {
+				res.IsCollection = true
+			}
+			if ps.Prop.ContentLength > 0 {
+				res.ContentLength = ps.Prop.ContentLength
+			}
+		}
+		resources = append(resources, res)
+	}
+	return resources, nil
+}
+
+// DAVChildNames returns the names of the children of the collection at dirPath.
+// The collection itself, which a Depth: 1 PROPFIND includes, is skipped.
+func DAVChildNames(resources []DAVResource, dirPath string) []string {
+	self := strings.TrimSuffix(path.Clean(dirPath), "/")
+	names := make([]string, 0, len(resources))
+	for _, res := range resources {
+		p := res.Href
+		// The href may be an absolute URL or an escaped path
+		if u, err := url.Parse(res.Href); err == nil {
+			p = u.Path
+		}
+		p = strings.TrimSuffix(p, "/")
+		if p == self || p == "" {
+			continue
+		}
+		names = append(names, path.Base(p))
+	}
+	return names
+}
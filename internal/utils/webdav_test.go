@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testMultistatus = `<?xml version="1.0"?>
+<d:multistatus xmlns:d="DAV:">
+  <d:response>
+    <d:href>/remote.php/dav/files/user/performance_tests/</d:href>
+    <d:propstat><d:prop><d:resourcetype><d:collection/></d:resourcetype></d:prop></d:propstat>
+  </d:response>
+  <d:response>
+    <d:href>/remote.php/dav/files/user/performance_tests/smallfile_1_0000.tmp</d:href>
+    <d:propstat><d:prop><d:resourcetype/><d:getcontentlength>4096</d:getcontentlength></d:prop></d:propstat>
+  </d:response>
+  <d:response>
+    <d:href>https://cloud.example.com/remote.php/dav/files/user/performance_tests/with%20space.tmp</d:href>
+    <d:propstat><d:prop><d:resourcetype/><d:getcontentlength>1</d:getcontentlength></d:prop></d:propstat>
+  </d:response>
+</d:multistatus>`
+
+func TestParseMultistatus(t *testing.T) {
+	resources, err := ParseMultistatus(strings.NewReader(testMultistatus))
+	if err != nil {
+		t.Fatalf("ParseMultistatus failed: %v", err)
+	}
+	if len(resources) != 3 {
+		t.Fatalf("expected 3 resources, got %d", len(resources))
+	}
+	if !resources[0].IsCollection || resources[1].IsCollection {
+		t.Errorf("unexpected collection flags: %+v", resources)
+	}
+	if resources[1].ContentLength != 4096 {
+		t.Errorf("expected content length 4096, got %d", resources[1].ContentLength)
+	}
+
+	names := DAVChildNames(resources, "/remote.php/dav/files/user/performance_tests")
+	want := []string{"smallfile_1_0000.tmp", "with space.tmp"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("DAVChildNames = %v, want %v", names, want)
+	}
+}
+
+func TestParseWebDAVErrorTag(t *testing.T) {
+	body := `<?xml version="1.0" encoding="utf-8"?>
+<d:error xmlns:d="DAV:" xmlns:s="http://sabredav.org/ns">
+  <s:exception>Sabre\DAV\Exception\NotFound</s:exception>
+  <s:message>File not found</s:message>
+</d:error>`
+	if tag := ParseWebDAVErrorTag(body); tag != `Sabre\DAV\Exception\NotFound` {
+		t.Errorf("ParseWebDAVErrorTag = %q", tag)
+	}
+	if tag := ParseWebDAVErrorTag("not xml"); tag != "" {
+		t.Errorf("expected no tag for a non-XML body, got %q", tag)
+	}
+}
@@ -0,0 +1,414 @@
+package webdav
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/xXRoxXeRXx/cloud-performance-monitor/internal/utils"
+)
+
+// Chunking modes of the upload
+const (
+	// ChunkingNone uploads the file with a single PUT, supported by every RFC 4918 server
+	ChunkingNone = "none"
+	// ChunkingNextcloud uses Nextcloud chunking v2: MKCOL of an upload collection, one PUT
+	// per chunk and a MOVE of the assembled .file to the destination
+	ChunkingNextcloud = "nextcloud"
+	// ChunkingOwnCloud uses the ownCloud chunked PUT (OC-Chunked, without TUS): every chunk
+	// is PUT to <file>-chunking-<transfer>-<count>-<index> and the server assembles the file
+	// when the last chunk arrives
+	ChunkingOwnCloud = "owncloud"
+)
+
+// DefaultTimeout is the timeout of a single request
+const DefaultTimeout = 300 * time.Second
+
+// ChunkingModes returns the supported chunking modes
+func ChunkingModes() []string {
+	return []string{ChunkingNone, ChunkingNextcloud, ChunkingOwnCloud}
+}
+
+// IsChunkingMode reports whether mode is a supported chunking mode; empty means ChunkingNone
+func IsChunkingMode(mode string) bool {
+	if mode == "" {
+		return true
+	}
+	for _, m := range ChunkingModes() {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// Client for generic WebDAV servers (ownCloud, Seafile, Apache mod_dav, Box, ...).
+// All paths are relative to Root, the collection of the user's files below BaseURL.
+type Client struct {
+	BaseURL  string
+	Root     string
+	Username string
+	Password string
+	Chunking string
+	// UploadsRoot is the collection the chunks of ChunkingNextcloud are uploaded to,
+	// /remote.php/dav/uploads/<username> if empty
+	UploadsRoot string
+	HTTPClient  *http.Client
+	logger      utils.ClientLogger
+}
+
+// NewClient creates a new generic WebDAV client; an empty chunking mode uploads with a
+// single PUT
+func NewClient(baseURL, root, username, password, chunking string) *Client {
+	if chunking == "" {
+		chunking = ChunkingNone
+	}
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		Root:       root,
+		Username:   username,
+		Password:   password,
+		Chunking:   chunking,
+		HTTPClient: &http.Client{Timeout: DefaultTimeout},
+		logger:     &utils.DefaultClientLogger{},
+	}
+}
+
+// davPath returns the server path of a path relative to Root
+func (c *Client) davPath(p string) string {
+	return path.Join("/", c.Root, p)
+}
+
+// uploadsRoot returns the collection Nextcloud v2 chunks are uploaded to
+func (c *Client) uploadsRoot() string {
+	if c.UploadsRoot != "" {
+		return path.Join("/", c.UploadsRoot)
+	}
+	return path.Join("/remote.php/dav/uploads/", c.Username)
+}
+
+// hrefPath returns the path the server reports in PROPFIND hrefs for a server path,
+// including the path component of BaseURL
+func (c *Client) hrefPath(serverPath string) string {
+	if u, err := url.Parse(c.BaseURL); err == nil && u.Path != "" {
+		return path.Join(u.Path, serverPath)
+	}
+	return serverPath
+}
+
+// newRequest is a helper to create authenticated WebDAV requests
+func (c *Client) newRequest(ctx context.Context, method, serverPath string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+serverPath, body)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(c.Username, c.Password)
+	return req, nil
+}
+
+// statusError returns the typed error of a response with an unexpected status
+func (c *Client) statusError(operation, phase string, resp *http.Response) error {
+	return utils.NewStatusError("webdav", operation, phase, resp, utils.ParseWebDAVErrorTag)
+}
+
+// wrapError annotates a request error with the operation it occurred in
+func (c *Client) wrapError(operation, phase string, err error) error {
+	return utils.WrapProviderError("webdav", operation, phase, err)
+}
+
+// CloseIdleConnections closes the kept-alive connections of the client, so that the next
+// request has to connect again
+func (c *Client) CloseIdleConnections() {
+	c.HTTPClient.CloseIdleConnections()
+}
+
+// EnsureDirectory ensures the test directory exists
+func (c *Client) EnsureDirectory(ctx context.Context, dirPath string) error {
+	req, err := c.newRequest(ctx, "MKCOL", c.davPath(dirPath), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return c.wrapError("mkdir", "", err)
+	}
+	defer resp.Body.Close()
+
+	// 405 is returned if the directory already exists, which is not an error for us.
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusMethodNotAllowed {
+		return c.statusError("mkdir", "", resp)
+	}
+	return nil
+}
+
+// UploadFile uploads a file using the configured chunking mode
+func (c *Client) UploadFile(ctx context.Context, filePath string, reader io.Reader, size int64, chunkSize int64) error {
+	c.logger.LogOperation(utils.INFO, "webdav", c.BaseURL, "upload", "start",
+		fmt.Sprintf("Starting upload for %s (size: %d bytes, chunking: %s, chunk size: %d bytes)", filePath, size, c.Chunking, chunkSize),
+		map[string]interface{}{"file_path": filePath, "file_size": size, "chunking": c.Chunking, "chunk_size": chunkSize})
+
+	var err error
+	switch c.Chunking {
+	case ChunkingNone:
+		err = c.uploadSingle(ctx, filePath, reader, size)
+	case ChunkingNextcloud:
+		err = c.uploadNextcloudChunks(ctx, filePath, reader, size, chunkSize)
+	case ChunkingOwnCloud:
+		err = c.uploadOwnCloudChunks(ctx, filePath, reader, size, chunkSize)
+	default:
+		return fmt.Errorf("unsupported chunking mode %q", c.Chunking)
+	}
+	if err != nil {
+		return err
+	}
+
+	c.logger.LogOperation(utils.INFO, "webdav", c.BaseURL, "upload", "completed",
+		fmt.Sprintf("Upload successful for %s", filePath),
+		map[string]interface{}{"file_path": filePath})
+	return nil
+}
+
+// uploadSingle uploads the whole file with one PUT
+func (c *Client) uploadSingle(ctx context.Context, filePath string, reader io.Reader, size int64) error {
+	req, err := c.newRequest(ctx, "PUT", c.davPath(filePath), reader)
+	if err != nil {
+		return fmt.Errorf("could not create PUT request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.ContentLength = size
+	req = utils.WithHTTPOperation(req, utils.HTTPOperationUpload)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return c.wrapError("upload", "", err)
+	}
+	defer resp.Body.Close()
+
+	if !uploadAccepted(resp) {
+		return c.statusError("upload", "", resp)
+	}
+	return nil
+}
+
+// uploadNextcloudChunks uploads the file with Nextcloud chunking v2
+func (c *Client) uploadNextcloudChunks(ctx context.Context, filePath string, reader io.Reader, size, chunkSize int64) error {
+	chunkDir := path.Join(c.uploadsRoot(), uuid.New().String())
+	destinationURL := c.BaseURL + c.davPath(filePath)
+
+	// 1. Create the upload collection for the chunks
+	req, err := c.newRequest(ctx, "MKCOL", chunkDir, nil)
+	if err != nil {
+		return fmt.Errorf("could not create MKCOL request: %w", err)
+	}
+	req.Header.Set("Destination", destinationURL)
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return c.wrapError("upload", "mkcol", err)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		defer resp.Body.Close()
+		return c.statusError("upload", "mkcol", resp)
+	}
+	resp.Body.Close()
+
+	// 2. Upload the chunks, numbered from 1 with five digits so that they sort correctly
+	err = forEachChunk(reader, chunkSize, func(index int, chunk []byte) error {
+		return c.putChunk(ctx, fmt.Sprintf("%s/%05d", chunkDir, index+1), chunk, func(req *http.Request) {
+			req.Header.Set("Destination", destinationURL)
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	// 3. Assemble the chunks by moving the virtual .file to the destination
+	req, err = c.newRequest(ctx, "MOVE", chunkDir+"/.file", nil)
+	if err != nil {
+		return fmt.Errorf("could not create MOVE request: %w", err)
+	}
+	req.Header.Set("Destination", destinationURL)
+	req.Header.Set("OC-Total-Length", fmt.Sprintf("%d", size))
+	resp, err = c.HTTPClient.Do(req)
+	if err != nil {
+		return c.wrapError("upload", "assemble", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent {
+		return c.statusError("upload", "assemble", resp)
+	}
+	return nil
+}
+
+// uploadOwnCloudChunks uploads the file with the ownCloud chunked PUT. The chunk count is
+// part of every chunk name, so it is derived from size.
+func (c *Client) uploadOwnCloudChunks(ctx context.Context, filePath string, reader io.Reader, size, chunkSize int64) error {
+	// The transfer ID must not contain dashes, which separate the parts of the chunk name
+	transferID := uuid.New().ID()
+	count := int((size + chunkSize - 1) / chunkSize)
+	if count == 0 {
+		count = 1
+	}
+	target := c.davPath(filePath)
+
+	uploaded := 0
+	err := forEachChunk(reader, chunkSize, func(index int, chunk []byte) error {
+		if index >= count {
+			return fmt.Errorf("file is larger than the announced %d bytes", size)
+		}
+		uploaded++
+		return c.putChunk(ctx, fmt.Sprintf("%s-chunking-%d-%d-%d", target, transferID, count, index), chunk, func(req *http.Request) {
+			req.Header.Set("OC-Chunked", "1")
+			req.Header.Set("OC-Total-Length", fmt.Sprintf("%d", size))
+		})
+	})
+	if err != nil {
+		return err
+	}
+	if uploaded != count {
+		return fmt.Errorf("uploaded %d of %d chunks, file is smaller than the announced %d bytes", uploaded, count, size)
+	}
+	return nil
+}
+
+// putChunk uploads a single chunk; setHeaders adds the headers of the chunking mode
+func (c *Client) putChunk(ctx context.Context, chunkPath string, chunk []byte, setHeaders func(req *http.Request)) error {
+	// Transient failures are retried by the client's transport (shared retry policy)
+	req, err := c.newRequest(ctx, "PUT", chunkPath, bytes.NewReader(chunk))
+	if err != nil {
+		return fmt.Errorf("could not create PUT request for chunk %s: %w", chunkPath, err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	setHeaders(req)
+	req.ContentLength = int64(len(chunk))
+	req = utils.WithHTTPOperation(req, utils.HTTPOperationChunk)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return c.wrapError("upload", "chunk", fmt.Errorf("PUT request for chunk %s failed: %w", chunkPath, err))
+	}
+	defer resp.Body.Close()
+
+	if !uploadAccepted(resp) {
+		return c.statusError("upload", "chunk", resp)
+	}
+	c.logger.LogOperation(utils.DEBUG, "webdav", c.BaseURL, "chunk_upload", "chunk_success",
+		fmt.Sprintf("Chunk %s uploaded (%d bytes)", chunkPath, len(chunk)),
+		map[string]interface{}{"chunk_path": chunkPath, "bytes": len(chunk)})
+	return nil
+}
+
+// forEachChunk reads reader in chunks of chunkSize bytes, only the last one may be
+// shorter, and calls fn with the zero-based index of every chunk
+func forEachChunk(reader io.Reader, chunkSize int64, fn func(index int, chunk []byte) error) error {
+	chunk := make([]byte, chunkSize)
+	for index := 0; ; index++ {
+		n, err := io.ReadFull(reader, chunk)
+		if n > 0 {
+			if err := fn(index, chunk[:n]); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read chunk %d: %w", index, err)
+		}
+	}
+}
+
+// uploadAccepted reports whether a PUT was accepted; servers answer 201 for new files
+// and 200 or 204 for replaced ones
+func uploadAccepted(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusCreated || resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNoContent
+}
+
+// DownloadFile downloads a file
+func (c *Client) DownloadFile(ctx context.Context, filePath string) (io.ReadCloser, error) {
+	req, err := c.newRequest(ctx, "GET", c.davPath(filePath), nil)
+	if err != nil {
+		return nil, err
+	}
+	req = utils.WithHTTPOperation(req, utils.HTTPOperationDownload)
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, c.wrapError("download", "", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, c.statusError("download", "", resp)
+	}
+	return resp.Body, nil
+}
+
+// propfind sends a PROPFIND with the given depth and returns the parsed resources
+func (c *Client) propfind(ctx context.Context, operation, serverPath, depth string) ([]utils.DAVResource, error) {
+	req, err := c.newRequest(ctx, "PROPFIND", serverPath, strings.NewReader(utils.PropfindBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Depth", depth)
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, c.wrapError(operation, "", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, c.statusError(operation, "", resp)
+	}
+	resources, err := utils.ParseMultistatus(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PROPFIND response: %w", err)
+	}
+	return resources, nil
+}
+
+// ListDirectory returns the names of the entries in a directory (PROPFIND with Depth: 1)
+func (c *Client) ListDirectory(ctx context.Context, dirPath string) ([]string, error) {
+	serverPath := c.davPath(dirPath)
+	resources, err := c.propfind(ctx, "list", serverPath, "1")
+	if err != nil {
+		return nil, err
+	}
+	return utils.DAVChildNames(resources, c.hrefPath(serverPath)), nil
+}
+
+// StatFile returns the size of a remote file (PROPFIND with Depth: 0)
+func (c *Client) StatFile(ctx context.Context, filePath string) (int64, error) {
+	resources, err := c.propfind(ctx, "stat", c.davPath(filePath), "0")
+	if err != nil {
+		return 0, err
+	}
+	if len(resources) == 0 {
+		return 0, fmt.Errorf("PROPFIND returned no properties for %s", filePath)
+	}
+	return resources[0].ContentLength, nil
+}
+
+// DeleteFile deletes a file or directory
+func (c *Client) DeleteFile(ctx context.Context, filePath string) error {
+	req, err := c.newRequest(ctx, "DELETE", c.davPath(filePath), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return c.wrapError("delete", "", err)
+	}
+	defer resp.Body.Close()
+
+	// mod_dav and others answer 200 instead of 204
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return c.statusError("delete", "", resp)
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package webdav
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// davServer is a minimal in-memory WebDAV server recording the requests it receives
+type davServer struct {
+	mu       sync.Mutex
+	files    map[string][]byte
+	requests []string
+}
+
+func newDAVServer() *davServer {
+	return &davServer{files: make(map[string][]byte)}
+}
+
+func (s *davServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+
+	switch r.Method {
+	case "MKCOL":
+		w.WriteHeader(http.StatusCreated)
+	case "PUT":
+		data, _ := io.ReadAll(r.Body)
+		s.files[r.URL.Path] = data
+		w.WriteHeader(http.StatusCreated)
+	case "MOVE":
+		w.WriteHeader(http.StatusCreated)
+	case "GET":
+		data, ok := s.files[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(data)
+	case "DELETE":
+		delete(s.files, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestUploadFileSinglePut(t *testing.T) {
+	dav := newDAVServer()
+	server := httptest.NewServer(dav)
+	defer server.Close()
+
+	client := NewClient(server.URL, "/seafdav", "testuser", "testpass", "")
+	content := []byte("0123456789")
+
+	if err := client.UploadFile(context.Background(), "/testdir/file.bin", bytes.NewReader(content), int64(len(content)), 4); err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+	if !bytes.Equal(dav.files["/seafdav/testdir/file.bin"], content) {
+		t.Errorf("expected the file to be uploaded with one PUT, got requests %v", dav.requests)
+	}
+
+	body, err := client.DownloadFile(context.Background(), "/testdir/file.bin")
+	if err != nil {
+		t.Fatalf("DownloadFile failed: %v", err)
+	}
+	defer body.Close()
+	if data, _ := io.ReadAll(body); !bytes.Equal(data, content) {
+		t.Errorf("unexpected content: %q", data)
+	}
+}
+
+func TestUploadFileNextcloudChunking(t *testing.T) {
+	dav := newDAVServer()
+	server := httptest.NewServer(dav)
+	defer server.Close()
+
+	client := NewClient(server.URL, "/remote.php/dav/files/testuser", "testuser", "testpass", ChunkingNextcloud)
+	content := []byte("0123456789")
+
+	if err := client.UploadFile(context.Background(), "/file.bin", bytes.NewReader(content), int64(len(content)), 4); err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+
+	var chunks []string
+	for p, data := range dav.files {
+		if !strings.HasPrefix(p, "/remote.php/dav/uploads/testuser/") {
+			t.Errorf("unexpected chunk path %s", p)
+		}
+		chunks = append(chunks, string(data))
+	}
+	if len(chunks) != 3 {
+		t.Errorf("expected 3 chunks, got %d", len(chunks))
+	}
+	if last := dav.requests[len(dav.requests)-1]; !strings.HasPrefix(last, "MOVE ") || !strings.HasSuffix(last, "/.file") {
+		t.Errorf("expected the upload to end with a MOVE of .file, got %s", last)
+	}
+}
+
+func TestUploadFileOwnCloudChunking(t *testing.T) {
+	var headers []http.Header
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header.Clone())
+		paths = append(paths, r.URL.Path)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "/remote.php/webdav", "testuser", "testpass", ChunkingOwnCloud)
+	content := []byte("0123456789")
+
+	if err := client.UploadFile(context.Background(), "/file.bin", bytes.NewReader(content), int64(len(content)), 4); err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+	if len(paths) != 3 {
+		t.Fatalf("expected 3 chunk PUTs, got %v", paths)
+	}
+	for i, p := range paths {
+		if !strings.HasPrefix(p, "/remote.php/webdav/file.bin-chunking-") || !strings.HasSuffix(p, "-3-"+strconv.Itoa(i)) {
+			t.Errorf("unexpected chunk path %s", p)
+		}
+		if headers[i].Get("OC-Chunked") != "1" || headers[i].Get("OC-Total-Length") != "10" {
+			t.Errorf("chunk %d: missing ownCloud chunking headers: %v", i, headers[i])
+		}
+	}
+}
+
+func TestUploadFileOwnCloudChunkingShortFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "", "testuser", "testpass", ChunkingOwnCloud)
+	err := client.UploadFile(context.Background(), "/file.bin", strings.NewReader("0123"), 10, 4)
+	if err == nil {
+		t.Error("expected an error when the file is shorter than announced")
+	}
+}
+
+func TestListDirectoryWithBasePath(t *testing.T) {
+	// Box serves WebDAV below a path of the server URL
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PROPFIND" || r.Header.Get("Depth") != "1" || r.URL.Path != "/dav/testdir" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = w.Write([]byte(`<?xml version="1.0"?>
+<d:multistatus xmlns:d="DAV:">
+  <d:response><d:href>/dav/testdir/</d:href></d:response>
+  <d:response><d:href>/dav/testdir/a.tmp</d:href></d:response>
+  <d:response><d:href>/dav/testdir/b%20c.tmp</d:href></d:response>
+</d:multistatus>`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/dav", "", "testuser", "testpass", "")
+
+	names, err := client.ListDirectory(context.Background(), "/testdir")
+	if err != nil {
+		t.Fatalf("ListDirectory failed: %v", err)
+	}
+	if len(names) != 2 || names[0] != "a.tmp" || names[1] != "b c.tmp" {
+		t.Errorf("Unexpected entries: %v", names)
+	}
+}
+
+func TestStatFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PROPFIND" || r.Header.Get("Depth") != "0" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = w.Write([]byte(`<?xml version="1.0"?>
+<d:multistatus xmlns:d="DAV:">
+  <d:response>
+    <d:href>/webdav/testdir/a.tmp</d:href>
+    <d:propstat><d:prop><d:getcontentlength>1024</d:getcontentlength></d:prop></d:propstat>
+  </d:response>
+</d:multistatus>`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "/webdav", "testuser", "testpass", "")
+
+	size, err := client.StatFile(context.Background(), "/testdir/a.tmp")
+	if err != nil {
+		t.Fatalf("StatFile failed: %v", err)
+	}
+	if size != 1024 {
+		t.Errorf("Expected size 1024, got %d", size)
+	}
+}
+
+func TestDeleteFileAcceptsOK(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "DELETE" {
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "/dav", "testuser", "testpass", "")
+
+	if err := client.DeleteFile(context.Background(), "testfile.txt"); err != nil {
+		t.Errorf("DeleteFile failed: %v", err)
+	}
+}